@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Account anomaly rule settings: a transaction more than anomalyMultiple times
+// its account's average over the trailing anomalyWindow is escalated to at
+// least MEDIUM, once the account has anomalyMinHistory prior transactions in
+// that window. A multiple of zero disables the rule.
+var (
+	anomalyMultiple   = 3.0
+	anomalyWindow     = 30 * 24 * time.Hour
+	anomalyMinHistory = 5
+)
+
+// anomalyCheck compares t, already converted to USD, against its account's
+// baseline. ok is true when the amount is anomalous for the account.
+func (tn *Tenant) anomalyCheck(ctx context.Context, t Transaction, now time.Time) (d Decision, ok bool, err error) {
+	if anomalyMultiple <= 0 || t.AccountID == "" {
+		return Decision{}, false, nil
+	}
+	avg, n, err := tn.store.AccountBaseline(ctx, t.AccountID, now.Add(-anomalyWindow))
+	if err != nil {
+		return Decision{}, false, err
+	}
+	if n < anomalyMinHistory || float64(t.Amount) <= anomalyMultiple*float64(avg) {
+		return Decision{}, false, nil
+	}
+	return Decision{
+		RiskLevel: "MEDIUM",
+		Reason:    fmt.Sprintf("account anomaly: $%s is more than %gx the account's $%s average", t.Amount, anomalyMultiple, avg),
+	}, true, nil
+}
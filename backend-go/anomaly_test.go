@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// seedAccount appends n $100 debits for account spread over the days before noon.
+func seedAccount(t *testing.T, tn *Tenant, account string, n int) {
+	t.Helper()
+	for i := range n {
+		at := noon.Add(-time.Duration(i+1) * 24 * time.Hour)
+		r := Record{Transaction: Transaction{AccountID: account, Merchant: "m", Amount: dollars(100), Timestamp: at}, RiskLevel: "LOW", Timestamp: at}
+		if err := tn.store.Append(t.Context(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAccountAnomaly(t *testing.T) {
+	api, tn := newTestAPI(t)
+	seedAccount(t, tn, "acct-1", anomalyMinHistory)
+
+	res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 301, "merchant": "m", "account_id": "acct-1"}`))
+	if res.RiskLevel != "MEDIUM" {
+		t.Fatalf("spike: got %s (%s), want MEDIUM", res.RiskLevel, res.Reason)
+	}
+	res = decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 120, "merchant": "m", "account_id": "acct-1"}`))
+	if res.RiskLevel != "LOW" {
+		t.Fatalf("typical amount: got %s (%s), want LOW", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestAccountAnomalyNeedsHistory(t *testing.T) {
+	api, tn := newTestAPI(t)
+	seedAccount(t, tn, "acct-1", anomalyMinHistory-1)
+	res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 900, "merchant": "m", "account_id": "acct-1"}`))
+	if res.RiskLevel != "LOW" {
+		t.Fatalf("short history: got %s (%s), want LOW", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestAccountAnomalyIgnoresOldHistory(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	old := noon.Add(-anomalyWindow - time.Hour)
+	for range anomalyMinHistory {
+		tn.store.Append(t.Context(), Record{Transaction: Transaction{AccountID: "a", Amount: dollars(10), Timestamp: old}, Timestamp: old})
+	}
+	if _, ok, err := tn.anomalyCheck(t.Context(), Transaction{AccountID: "a", Amount: dollars(900)}, noon); err != nil || ok {
+		t.Fatalf("history outside the window counted: ok=%v err=%v", ok, err)
+	}
+}
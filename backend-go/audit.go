@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Audited actions.
+const (
+	auditRulesReload    = "rules.reload"
+	auditConfigUpdate   = "config.update"
+	auditMerchantAdd    = "merchants.add"
+	auditMerchantRemove = "merchants.remove"
+)
+
+// AuditEntry records one configuration change. Actor identifies the API key
+// that made it by a fingerprint, never the key itself.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Actor     string          `json:"actor"`
+	Tenant    string          `json:"tenant"`
+	Action    string          `json:"action"`
+	Change    json.RawMessage `json:"change"`
+}
+
+// AuditLog is an append-only, in-memory record of configuration changes.
+// Entries cannot be edited or removed once written, and readers get copies.
+// It is safe for concurrent use.
+type AuditLog struct {
+	mu      sync.RWMutex
+	entries []AuditEntry
+}
+
+// auditLog records every configuration change made through the API.
+var auditLog = &AuditLog{}
+
+// keyFingerprint identifies an API key without revealing it.
+func keyFingerprint(key string) string {
+	if key == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:6])
+}
+
+// Record appends an entry for the change r made to its tenant. change is
+// encoded as it is at the time of the call.
+func (l *AuditLog) Record(r *http.Request, action string, change any) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		logFor(r.Context()).Error("audit entry not encodable", "action", action, "error", err)
+		data = []byte("null")
+	}
+	tenant := defaultTenant
+	if t := tenantFrom(r.Context()); t != nil {
+		tenant = t.ID
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, AuditEntry{
+		ID:        int64(len(l.entries)) + 1,
+		Timestamp: now().UTC(),
+		Actor:     keyFingerprint(r.Header.Get("X-API-Key")),
+		Tenant:    tenant,
+		Action:    action,
+		Change:    data,
+	})
+}
+
+// Entries returns copies of tenant's entries, oldest first.
+func (l *AuditLog) Entries(tenant string) []AuditEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := []AuditEntry{}
+	for _, e := range l.entries {
+		if e.Tenant == tenant {
+			e.Change = append(json.RawMessage(nil), e.Change...)
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// auditHandler serves GET /audit: the requesting tenant's audit entries.
+func (s *Server) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auditLog.Entries(s.tenant(r.Context()).ID))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAuditRecordsConfigChanges(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	if rec := do(api, "PATCH", "/config", `{"medium_threshold": 200}`); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(api, "POST", "/merchants/deny", `{"merchant": "Shady LLC"}`); rec.Code != http.StatusOK {
+		t.Fatalf("deny: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(api, "GET", "/audit", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /audit: status %d", rec.Code)
+	}
+	entries := decode[[]AuditEntry](t, rec)
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2: %+v", len(entries), entries)
+	}
+	for i, action := range []string{auditConfigUpdate, auditMerchantAdd} {
+		e := entries[i]
+		if e.ID != int64(i+1) || e.Action != action || e.Actor != keyFingerprint(testKey) || e.Tenant != defaultTenant || !e.Timestamp.Equal(noon) {
+			t.Errorf("entry %d: %+v", i, e)
+		}
+		if e.Actor == testKey {
+			t.Errorf("entry %d reveals the API key", i)
+		}
+	}
+	var sc settingsChange
+	if err := json.Unmarshal(entries[0].Change, &sc); err != nil || sc.Before.MediumThreshold != dollars(1000) || sc.After.MediumThreshold != dollars(200) {
+		t.Errorf("config change %s: %v", entries[0].Change, err)
+	}
+	var mc merchantListChange
+	if err := json.Unmarshal(entries[1].Change, &mc); err != nil || mc != (merchantListChange{List: "deny", Merchant: "Shady LLC"}) {
+		t.Errorf("merchant change %s: %v", entries[1].Change, err)
+	}
+}
+
+func TestAuditEntriesImmutable(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	do(api, "POST", "/merchants/allow", `{"merchant": "Payroll Co"}`)
+	got := auditLog.Entries(defaultTenant)
+	got[0].Action = "tampered"
+	got[0].Change[2] = 'X'
+	again := auditLog.Entries(defaultTenant)
+	if again[0].Action != auditMerchantAdd || string(again[0].Change) != `{"list":"allow","merchant":"Payroll Co"}` {
+		t.Fatalf("stored entry changed to %+v (%s)", again[0], again[0].Change)
+	}
+	if rec := do(api, "DELETE", "/audit", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE /audit: status %d, want 405", rec.Code)
+	}
+}
+
+func TestAuditScopedToTenant(t *testing.T) {
+	api := newTenantsAPI(t)
+	do(api, "PATCH", "/config", `{"high_threshold": 5000}`, "X-API-Key", "acme-key")
+	if entries := decode[[]AuditEntry](t, do(api, "GET", "/audit", "", "X-Tenant-ID", "globex")); len(entries) != 0 {
+		t.Fatalf("globex sees acme's entries: %+v", entries)
+	}
+	entries := decode[[]AuditEntry](t, do(api, "GET", "/audit", "", "X-API-Key", "acme-key"))
+	if len(entries) != 1 || entries[0].Actor != keyFingerprint("acme-key") || entries[0].Tenant != "acme" {
+		t.Fatalf("acme entries %+v", entries)
+	}
+}
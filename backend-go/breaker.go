@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned by CircuitBreaker.Do when the call was not
+// attempted.
+var errCircuitOpen = errors.New("circuit breaker open")
+
+// breakerState is the state of a CircuitBreaker.
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // calls pass through
+	breakerOpen                         // calls are rejected until the cooldown ends
+	breakerHalfOpen                     // one trial call is in flight
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calling a failing downstream. It opens after
+// threshold consecutive failures, rejects calls for cooldown, then lets a
+// single trial through: success closes it, failure opens it again. It is safe
+// for concurrent use.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be made now. A true result in the
+// half-open state claims the trial, so the caller must report the outcome
+// with Success or Failure.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// Success records a successful call and closes the breaker.
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+// Failure records a failed call, opening the breaker after threshold
+// consecutive failures or after a failed trial.
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = now()
+	}
+}
+
+// State returns the breaker's current state.
+func (b *CircuitBreaker) State() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Do calls fn if the breaker allows it and records the outcome. It returns
+// errCircuitOpen without calling fn otherwise.
+func (b *CircuitBreaker) Do(fn func() error) error {
+	if !b.Allow() {
+		return errCircuitOpen
+	}
+	err := fn()
+	if err != nil {
+		b.Failure()
+	} else {
+		b.Success()
+	}
+	return err
+}
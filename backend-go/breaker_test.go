@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsAndRecovers(t *testing.T) {
+	setClock(t, noon)
+	b := NewCircuitBreaker(3, time.Minute)
+	calls := 0
+	fail := func() error { calls++; return errors.New("down") }
+
+	for range 3 {
+		b.Do(fail)
+	}
+	if b.State() != breakerOpen {
+		t.Fatalf("state %s after 3 failures, want open", b.State())
+	}
+	if err := b.Do(fail); !errors.Is(err, errCircuitOpen) || calls != 3 {
+		t.Fatalf("open breaker: err %v, %d calls; want errCircuitOpen without a call", err, calls)
+	}
+
+	setClock(t, noon.Add(time.Minute))
+	if !b.Allow() || b.State() != breakerHalfOpen {
+		t.Fatalf("after the cooldown: state %s, want a half-open trial", b.State())
+	}
+	if b.Allow() {
+		t.Fatal("second call allowed while the trial is in flight")
+	}
+	b.Failure()
+	if b.State() != breakerOpen {
+		t.Fatalf("failed trial: state %s, want open", b.State())
+	}
+
+	setClock(t, noon.Add(2*time.Minute))
+	if err := b.Do(func() error { return nil }); err != nil || b.State() != breakerClosed {
+		t.Fatalf("successful trial: err %v, state %s; want closed", err, b.State())
+	}
+}
+
+func TestCircuitBreakerCountsConsecutiveFailures(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Minute)
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if b.State() != breakerClosed {
+		t.Fatal("non-consecutive failures opened the breaker")
+	}
+}
+
+func TestWebhookCircuitOpenDropsEvents(t *testing.T) {
+	setClock(t, noon)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	n := newWebhookNotifier(srv.URL)
+	n.attempts = 1
+	n.breaker = NewCircuitBreaker(2, time.Minute)
+	for range 2 {
+		if err := n.send(webhookEvent{RiskLevel: "HIGH"}); err == nil {
+			t.Fatal("delivery to a failing receiver succeeded")
+		}
+		n.breaker.Failure()
+	}
+
+	metrics := func() string {
+		rec := httptest.NewRecorder()
+		metricsHandler(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+	series := `risk_webhook_dropped_total{reason="circuit_open"}`
+	before := scrape(t, metrics(), series)
+	n.notify(webhookEvent{RiskLevel: "HIGH"})
+	if got := scrape(t, metrics(), series) - before; got != 1 {
+		t.Fatalf("%s rose by %v, want 1", series, got)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("receiver called %d times, want 2; the open breaker must not call it", got)
+	}
+}
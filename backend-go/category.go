@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// categoryThresholds maps merchant category codes (MCC) to the USD amount
+// above which a charge in that category is anomalous. Transactions with a
+// listed MCC are judged against it instead of the global thresholds.
+var categoryThresholds = map[string]Money{
+	"5812": dollars(500),  // eating places and restaurants
+	"5814": dollars(500),  // fast food
+	"5732": dollars(5000), // electronics
+}
+
+// mccNames labels well-known category codes in decision reasons.
+var mccNames = map[string]string{
+	"5812": "restaurants",
+	"5814": "fast food",
+	"5732": "electronics",
+}
+
+// loadCategoryThresholds reads a JSON object of MCC to threshold from path.
+func loadCategoryThresholds(path string) (map[string]Money, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read categories: %w", err)
+	}
+	var m map[string]Money
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse categories: %w", err)
+	}
+	for mcc, v := range m {
+		if v <= 0 {
+			return nil, fmt.Errorf("category %s: threshold must be positive", mcc)
+		}
+	}
+	return m, nil
+}
+
+// describeMCC returns "5814 (fast food)" for known codes and the bare code otherwise.
+func describeMCC(mcc string) string {
+	if name, ok := mccNames[mcc]; ok {
+		return fmt.Sprintf("%s (%s)", mcc, name)
+	}
+	return mcc
+}
+
+// applyCategoryThreshold judges t against its category's threshold. ok is
+// false when the transaction has no MCC or the MCC has no threshold.
+func applyCategoryThreshold(t Transaction) (d Decision, ok bool) {
+	mcc := strings.TrimSpace(t.MCC)
+	limit, ok := categoryThresholds[mcc]
+	if !ok {
+		return Decision{}, false
+	}
+	if t.Amount > limit {
+		return Decision{RiskLevel: "HIGH", Reason: fmt.Sprintf("category anomaly: >$%s for MCC %s", limit, describeMCC(mcc))}, true
+	}
+	return Decision{RiskLevel: "LOW", Reason: fmt.Sprintf("within normal range for MCC %s", describeMCC(mcc))}, true
+}
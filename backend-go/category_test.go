@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestCategoryThresholds(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	for _, tc := range []struct {
+		name  string
+		tx    Transaction
+		level string
+	}{
+		{"restaurant anomaly", Transaction{Merchant: "Luigi's", MCC: "5812", Amount: dollars(600)}, "HIGH"},
+		{"fast food within range", Transaction{Merchant: "Burger Barn", MCC: "5814", Amount: dollars(40)}, "LOW"},
+		{"electronics stays LOW", Transaction{Merchant: "Gadget Hut", MCC: "5732", Amount: dollars(4500)}, "LOW"},
+		{"electronics over its threshold", Transaction{Merchant: "Gadget Hut", MCC: "5732", Amount: dollars(5001)}, "HIGH"},
+		{"unknown MCC falls back", Transaction{Merchant: "m", MCC: "9999", Amount: dollars(4500)}, "MEDIUM"},
+	} {
+		d, err := tn.evaluate(t.Context(), tc.tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.RiskLevel != tc.level {
+			t.Errorf("%s: got %s (%s), want %s", tc.name, d.RiskLevel, d.Reason, tc.level)
+		}
+	}
+}
+
+func TestCategoryReason(t *testing.T) {
+	d, ok := applyCategoryThreshold(Transaction{MCC: " 5814 ", Amount: dollars(501)})
+	if !ok {
+		t.Fatal("MCC with surrounding spaces not matched")
+	}
+	if want := "category anomaly: >$500.00 for MCC 5814 (fast food)"; d.Reason != want {
+		t.Fatalf("reason %q, want %q", d.Reason, want)
+	}
+	if _, ok := applyCategoryThreshold(Transaction{Amount: dollars(501)}); ok {
+		t.Fatal("transaction without MCC matched a category")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// maxInFlight bounds how many requests are handled at once, so a traffic
+// spike cannot spawn unbounded handler goroutines. A request arriving at the
+// limit waits up to inFlightWait for a slot before getting a 503. A limit of
+// zero disables the bound.
+var (
+	maxInFlight  = 500
+	inFlightWait = 100 * time.Millisecond
+)
+
+// unlimitedPaths are served outside the limit, so probes and scrapes still
+// answer while the API is saturated.
+var unlimitedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// concurrencyLimit admits at most maxInFlight requests to next at a time.
+// The limit is read when the middleware is built. It must run inside
+// timeoutMiddleware: a handler that outlives its deadline keeps running, and
+// its slot must stay taken until it returns.
+func concurrencyLimit(next http.Handler) http.Handler {
+	if maxInFlight <= 0 {
+		return next
+	}
+	slots := make(chan struct{}, maxInFlight)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unlimitedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			wait := time.NewTimer(inFlightWait)
+			defer wait.Stop()
+			select {
+			case slots <- struct{}{}:
+			case <-wait.C:
+				w.Header().Set("Retry-After", "1")
+				writeError(w, http.StatusServiceUnavailable, "server busy")
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+		defer func() { <-slots }()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitSheds(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &maxInFlight, 2)
+	setVar(t, &inFlightWait, 10*time.Millisecond)
+	setVar(t, &requestTimeout, 50*time.Millisecond)
+	setVar(t, &dependencies, &readinessChecks{})
+	ready.Store(true)
+	t.Cleanup(func() { ready.Store(false) })
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := timeoutMiddleware(concurrencyLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-release
+			return
+		}
+		api.ServeHTTP(w, r)
+	})))
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxInFlight; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			do(h, "GET", "/slow", "")
+		}()
+		<-started
+	}
+	busy := func(when string) {
+		t.Helper()
+		rec := do(h, "POST", "/risk", `{"amount": 5, "merchant": "m"}`)
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+			t.Fatalf("%s: status %d, want 503 with Retry-After", when, rec.Code)
+		}
+		if e := decode[ErrorResponse](t, rec); e.Error != "server busy" {
+			t.Fatalf("%s: body %+v", when, e)
+		}
+	}
+	busy("slots taken")
+	for _, path := range []string{"/healthz", "/readyz", "/metrics"} {
+		if rec := do(h, "GET", path, ""); rec.Code != http.StatusOK {
+			t.Errorf("%s while saturated: status %d, want 200", path, rec.Code)
+		}
+	}
+
+	// The slow requests have timed out, but their handlers still run and
+	// keep their slots.
+	wg.Wait()
+	busy("after the timeouts")
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		rec := do(h, "POST", "/risk", `{"amount": 5, "merchant": "m"}`)
+		if rec.Code == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("slots not released: status %d", rec.Code)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Settings are the scoring parameters adjustable at runtime via /config.
+type Settings struct {
+	MediumThreshold Money `json:"medium_threshold"`
+	HighThreshold   Money `json:"high_threshold"`
+	// DefaultLevel is the level of a transaction no rule matches before the
+	// amount thresholds are applied; they can raise it but never lower it.
+	DefaultLevel string `json:"default_level"`
+	// CombinePolicy decides between several matching rules: PolicyFirstMatch
+	// or PolicyMaxSeverity.
+	CombinePolicy string `json:"combine_policy"`
+}
+
+// Rule combining policies. Under first-match the first matching rule in
+// evaluation order decides; under max-severity every rule is evaluated and
+// the most severe match decides.
+const (
+	PolicyFirstMatch  = "first-match"
+	PolicyMaxSeverity = "max-severity"
+)
+
+var validPolicies = map[string]bool{PolicyFirstMatch: true, PolicyMaxSeverity: true}
+
+// validate checks that the thresholds are positive and correctly ordered and
+// that the default level and combining policy are known.
+func (s Settings) validate() error {
+	if s.MediumThreshold <= 0 || s.HighThreshold <= 0 {
+		return &ValidationError{Field: "threshold", Message: "thresholds must be positive"}
+	}
+	if s.MediumThreshold >= s.HighThreshold {
+		return &ValidationError{Field: "threshold", Message: "medium_threshold must be less than high_threshold"}
+	}
+	if !validLevels[s.DefaultLevel] {
+		return &ValidationError{Field: "default_level", Message: "default_level must be LOW, MEDIUM or HIGH"}
+	}
+	if !validPolicies[s.CombinePolicy] {
+		return &ValidationError{Field: "combine_policy", Message: "combine_policy must be first-match or max-severity"}
+	}
+	return nil
+}
+
+// Config guards the live Settings. It is safe for concurrent use.
+type Config struct {
+	mu       sync.RWMutex
+	settings Settings
+}
+
+func NewConfig(s Settings) *Config {
+	return &Config{settings: s}
+}
+
+// Get returns a copy of the current settings.
+func (c *Config) Get() Settings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.settings
+}
+
+// Update applies fn to a copy of the settings and stores the result if it
+// validates. On error the settings are left unchanged.
+func (c *Config) Update(fn func(*Settings)) (Settings, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := c.settings
+	fn(&next)
+	if err := next.validate(); err != nil {
+		return c.settings, err
+	}
+	c.settings = next
+	return next, nil
+}
+
+// defaultSettings are the thresholds used unless overridden by flags.
+var defaultSettings = Settings{MediumThreshold: dollars(1000), HighThreshold: dollars(10000), DefaultLevel: "LOW", CombinePolicy: PolicyFirstMatch}
+
+// settingsPatch is the body of PATCH /config; omitted fields are unchanged.
+type settingsPatch struct {
+	MediumThreshold *Money  `json:"medium_threshold"`
+	HighThreshold   *Money  `json:"high_threshold"`
+	DefaultLevel    *string `json:"default_level"`
+	CombinePolicy   *string `json:"combine_policy"`
+}
+
+func (p settingsPatch) apply(s *Settings) {
+	if p.MediumThreshold != nil {
+		s.MediumThreshold = *p.MediumThreshold
+	}
+	if p.HighThreshold != nil {
+		s.HighThreshold = *p.HighThreshold
+	}
+	if p.DefaultLevel != nil {
+		s.DefaultLevel = *p.DefaultLevel
+	}
+	if p.CombinePolicy != nil {
+		s.CombinePolicy = *p.CombinePolicy
+	}
+}
+
+// settingsChange is the audit record of a PATCH /config.
+type settingsChange struct {
+	Before Settings `json:"before"`
+	After  Settings `json:"after"`
+}
+
+// configHandler serves GET /config and PATCH /config for the calling tenant.
+func (s *Server) configHandler(w http.ResponseWriter, r *http.Request) {
+	config := s.tenant(r.Context()).config
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config.Get())
+	case http.MethodPatch:
+		var p settingsPatch
+		if !decodeBody(w, r, &p) {
+			return
+		}
+		var before Settings
+		s, err := config.Update(func(s *Settings) { before = *s; p.apply(s) })
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		auditLog.Record(r, auditConfigUpdate, settingsChange{Before: before, After: s})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// applyThresholds is the fallback when no rule matches: amounts above the
+// configured thresholds are MEDIUM or HIGH, everything else gets the default
+// level. A threshold never lowers a result below the default.
+func applyThresholds(t Transaction, s Settings) Decision {
+	return applyThresholdsIn(t, s, "$")
+}
+
+// applyThresholdsIn is applyThresholds with the thresholds in reasons
+// prefixed by unit.
+func applyThresholdsIn(t Transaction, s Settings, unit string) Decision {
+	d := Decision{RiskLevel: s.DefaultLevel, Reason: noRulesReason}
+	switch {
+	case t.Amount > s.HighThreshold:
+		d = Decision{RiskLevel: "HIGH", Reason: fmt.Sprintf("amount exceeds %s%s threshold", unit, s.HighThreshold)}
+	case t.Amount > s.MediumThreshold && levelRank[s.DefaultLevel] < levelRank["MEDIUM"]:
+		d = Decision{RiskLevel: "MEDIUM", Reason: fmt.Sprintf("amount exceeds %s%s threshold", unit, s.MediumThreshold)}
+	}
+	return d
+}
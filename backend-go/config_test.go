@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPatchConfigThresholds(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	body := `{"amount": 600, "merchant": "m"}`
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "LOW" {
+		t.Fatalf("before PATCH: got %s", res.RiskLevel)
+	}
+
+	rec := do(api, "PATCH", "/config", `{"medium_threshold": 100, "high_threshold": 500}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if s := decode[Settings](t, do(api, "GET", "/config", "")); s.MediumThreshold != dollars(100) || s.HighThreshold != dollars(500) {
+		t.Fatalf("GET /config: %+v", s)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "HIGH" {
+		t.Fatalf("after PATCH: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestPatchConfigValidation(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	for _, body := range []string{
+		`{"medium_threshold": 5000, "high_threshold": 1000}`,
+		`{"medium_threshold": 1000, "high_threshold": 1000}`,
+		`{"medium_threshold": -1}`,
+		`{"high_threshold": 0}`,
+	} {
+		if rec := do(api, "PATCH", "/config", body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422", body, rec.Code)
+		}
+	}
+	if got := tn.config.Get(); got != defaultSettings {
+		t.Fatalf("rejected PATCH changed settings: %+v", got)
+	}
+}
+
+func TestDefaultLevelMedium(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	if rec := do(api, "PATCH", "/config", `{"default_level": "MEDIUM"}`); rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	for _, tc := range []struct {
+		body, want string
+	}{
+		{`{"amount": 10, "merchant": "m"}`, "MEDIUM"},
+		{`{"amount": 1500, "merchant": "m"}`, "MEDIUM"},
+		{`{"amount": 10001, "merchant": "m"}`, "HIGH"},
+	} {
+		if res := decode[ScoreResult](t, do(api, "POST", "/risk", tc.body)); res.RiskLevel != tc.want {
+			t.Errorf("%s: got %s (%s), want %s", tc.body, res.RiskLevel, res.Reason, tc.want)
+		}
+	}
+}
+
+func TestDefaultLevelValidation(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	if rec := do(api, "PATCH", "/config", `{"default_level": "SEVERE"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", rec.Code)
+	}
+	if got := tn.config.Get().DefaultLevel; got != "LOW" {
+		t.Fatalf("rejected PATCH set default level %q", got)
+	}
+}
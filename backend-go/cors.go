@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// corsOrigins is the set of browser origins allowed to call the API.
+var corsOrigins = map[string]bool{}
+
+const (
+	corsAllowMethods  = "GET, POST, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders  = "Content-Type, X-API-Key, X-Request-ID, Idempotency-Key, X-Ruleset, X-Tenant-ID, X-Signature"
+	corsExposeHeaders = "X-Request-ID, X-Idempotent-Replay, X-Duplicate, Retry-After"
+)
+
+// parseOrigins splits a comma-separated origin list into a set.
+func parseOrigins(s string) map[string]bool {
+	origins := map[string]bool{}
+	for _, o := range strings.Split(s, ",") {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			origins[o] = true
+		}
+	}
+	return origins
+}
+
+// corsMiddleware echoes allowed origins in Access-Control-Allow-Origin and
+// answers preflight requests. Disallowed origins get no CORS headers, which
+// the browser treats as a rejection.
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		allowed := corsOrigins[origin]
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
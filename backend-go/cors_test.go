@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &corsOrigins, parseOrigins("https://dash.example.com/, https://other.example.com"))
+	h := corsMiddleware(api)
+
+	rec := do(h, "OPTIONS", "/risk", "", "Origin", "https://dash.example.com", "Access-Control-Request-Method", "POST")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status %d, want 204", rec.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://dash.example.com",
+		"Access-Control-Allow-Methods": corsAllowMethods,
+		"Access-Control-Allow-Headers": corsAllowHeaders,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s: got %q, want %q", header, got, want)
+		}
+	}
+
+	rec = do(h, "OPTIONS", "/risk", "", "Origin", "https://evil.example.com", "Access-Control-Request-Method", "POST")
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Fatalf("preflight from a disallowed origin allowed methods %q", got)
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &corsOrigins, parseOrigins("https://dash.example.com"))
+	h := corsMiddleware(api)
+
+	rec := do(h, "GET", "/transactions", "", "Origin", "https://dash.example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+		t.Fatalf("allowed origin: Access-Control-Allow-Origin %q", got)
+	}
+
+	rec = do(h, "GET", "/transactions", "", "Origin", "https://evil.example.com")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("disallowed origin echoed as %q", got)
+	}
+	if rec.Header().Get("Vary") != "Origin" {
+		t.Fatal("missing Vary: Origin")
+	}
+}
+
+// corsListed reports whether name is in a comma-separated CORS header list.
+func corsListed(list, name string) bool {
+	for _, h := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCORSPreflightRequestHeaders(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &corsOrigins, parseOrigins("https://dash.example.com"))
+	h := corsMiddleware(api)
+	for _, header := range []string{"X-Tenant-ID", signatureHeader} {
+		rec := do(h, "OPTIONS", "/risk", "", "Origin", "https://dash.example.com", "Access-Control-Request-Method", "POST", "Access-Control-Request-Headers", header)
+		if got := rec.Header().Get("Access-Control-Allow-Headers"); !corsListed(got, header) {
+			t.Errorf("preflight for %s: Access-Control-Allow-Headers %q", header, got)
+		}
+	}
+}
+
+func TestCORSExposesResponseHeaders(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &corsOrigins, parseOrigins("https://dash.example.com"))
+	rec := do(corsMiddleware(api), "GET", "/transactions", "", "Origin", "https://dash.example.com")
+	for _, header := range []string{"X-Request-ID", "X-Idempotent-Replay", "X-Duplicate", "Retry-After"} {
+		if got := rec.Header().Get("Access-Control-Expose-Headers"); !corsListed(got, header) {
+			t.Errorf("%s not exposed: Access-Control-Expose-Headers %q", header, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// highRiskCountries is the set of ISO 3166-1 alpha-2 codes whose transactions
+// get extra scrutiny. The default is the FATF "call for action" list.
+var highRiskCountries = map[string]bool{"IR": true, "KP": true, "MM": true}
+
+// highRiskCountryThreshold is the USD amount above which a transaction from a
+// high-risk country is forced to HIGH. Below it the transaction is escalated
+// to at least MEDIUM.
+var highRiskCountryThreshold = dollars(2000)
+
+// countryOf returns t's normalized country code, or "" if it has none.
+func countryOf(t Transaction) string {
+	return strings.ToUpper(strings.TrimSpace(t.Country))
+}
+
+// validCountry reports whether c looks like an ISO 3166-1 alpha-2 code.
+func validCountry(c string) bool {
+	return len(c) == 2 && c[0] >= 'A' && c[0] <= 'Z' && c[1] >= 'A' && c[1] <= 'Z'
+}
+
+// parseCountries builds a country set from a comma- or newline-separated
+// list. Blank entries and lines starting with '#' are ignored.
+func parseCountries(list string) (map[string]bool, error) {
+	set := map[string]bool{}
+	sc := bufio.NewScanner(strings.NewReader(list))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, c := range strings.Split(line, ",") {
+			c = strings.ToUpper(strings.TrimSpace(c))
+			if c == "" {
+				continue
+			}
+			if !validCountry(c) {
+				return nil, fmt.Errorf("invalid country code %q", c)
+			}
+			set[c] = true
+		}
+	}
+	return set, sc.Err()
+}
+
+// loadCountries reads a country list file; see parseCountries.
+func loadCountries(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read countries: %w", err)
+	}
+	set, err := parseCountries(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse countries: %w", err)
+	}
+	return set, nil
+}
+
+// applyCountryRisk escalates d for a transaction, already converted to USD,
+// from a high-risk country: to HIGH above highRiskCountryThreshold and to at
+// least MEDIUM otherwise. Other countries pass d through unchanged.
+func applyCountryRisk(d Decision, t Transaction) Decision {
+	c := countryOf(t)
+	if !highRiskCountries[c] {
+		return d
+	}
+	if t.Amount > highRiskCountryThreshold {
+		return Decision{RiskLevel: "HIGH", Reason: fmt.Sprintf("high-risk country %s: >$%s", c, highRiskCountryThreshold)}
+	}
+	if d.RiskLevel == "LOW" {
+		return Decision{RiskLevel: "MEDIUM", Reason: fmt.Sprintf("high-risk country %s", c)}
+	}
+	return d
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHighRiskCountry(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	for _, tc := range []struct {
+		name  string
+		tx    Transaction
+		level string
+	}{
+		{"high-risk country escalated", Transaction{Merchant: "m", Amount: dollars(10), Country: "ir"}, "MEDIUM"},
+		{"high-risk country above its threshold", Transaction{Merchant: "m", Amount: dollars(2001), Country: "KP"}, "HIGH"},
+		{"neutral country passes through", Transaction{Merchant: "m", Amount: dollars(10), Country: "FR"}, "LOW"},
+		{"neutral country keeps the default thresholds", Transaction{Merchant: "m", Amount: dollars(2001), Country: "FR"}, "MEDIUM"},
+		{"no country", Transaction{Merchant: "m", Amount: dollars(10)}, "LOW"},
+	} {
+		d, _ := tn.decide(t.Context(), tc.tx)
+		if d.RiskLevel != tc.level {
+			t.Errorf("%s: got %s (%s), want %s", tc.name, d.RiskLevel, d.Reason, tc.level)
+		}
+	}
+}
+
+func TestInvalidCountryRejected(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if rec := do(api, "POST", "/risk", `{"amount": 10, "merchant": "m", "country": "USA"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", rec.Code)
+	}
+}
+
+func TestParseCountries(t *testing.T) {
+	set, err := parseCountries("# FATF\nir, kp\n\nMM\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 3 || !set["IR"] || !set["KP"] || !set["MM"] {
+		t.Fatalf("got %v", set)
+	}
+	if _, err := parseCountries("IR,Iran"); err == nil {
+		t.Fatal("invalid code accepted")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Transaction types. A transaction without a type is a debit.
+const (
+	TypeDebit  = "debit"
+	TypeCredit = "credit"
+)
+
+// largeRefundThreshold is the USD amount above which a credit is held at
+// MEDIUM for review.
+var largeRefundThreshold = dollars(5000)
+
+// typeOf returns the transaction's type, defaulting to debit.
+func typeOf(t Transaction) string {
+	if t.Type == "" {
+		return TypeDebit
+	}
+	return strings.ToLower(strings.TrimSpace(t.Type))
+}
+
+func isCredit(t Transaction) bool {
+	return typeOf(t) == TypeCredit
+}
+
+// decideCredit scores a refund or other credit. Credits are LOW unless their
+// size, ignoring sign, exceeds largeRefundThreshold. t must be in USD.
+func decideCredit(t Transaction) Decision {
+	amount := t.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	if amount > largeRefundThreshold {
+		return Decision{RiskLevel: "MEDIUM", Reason: fmt.Sprintf("large refund: credit over $%s", largeRefundThreshold)}
+	}
+	return Decision{RiskLevel: "LOW", Reason: "credit"}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCreditScoring(t *testing.T) {
+	api, tn := newTestAPI(t)
+	for _, tc := range []struct {
+		body, want string
+	}{
+		{`{"amount": -250, "merchant": "Corner Shop", "type": "credit"}`, "LOW"},
+		{`{"amount": 9000, "merchant": "Corner Shop", "type": "credit"}`, "MEDIUM"},
+		{`{"amount": -5000.01, "merchant": "Corner Shop", "type": "credit"}`, "MEDIUM"},
+		{`{"amount": 20000, "merchant": "m", "type": "credit"}`, "MEDIUM"},
+	} {
+		rec := do(api, "POST", "/risk", tc.body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tc.body, rec.Code, rec.Body)
+		}
+		if res := decode[ScoreResult](t, rec); res.RiskLevel != tc.want {
+			t.Errorf("%s: got %s (%s), want %s", tc.body, res.RiskLevel, res.Reason, tc.want)
+		}
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 4 {
+		t.Fatalf("%d credits stored, want 4", n)
+	}
+	if rec := do(api, "POST", "/risk", `{"amount": -250, "merchant": "m"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("negative debit: status %d, want 422", rec.Code)
+	}
+}
+
+func TestCreditsSkipCTRAggregation(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, body := range []string{
+		`{"amount": 6000, "merchant": "m", "account_id": "acct-1", "timestamp": "2026-03-10T09:00:00Z"}`,
+		`{"amount": 6000, "merchant": "m", "account_id": "acct-1", "type": "credit", "timestamp": "2026-03-10T10:00:00Z"}`,
+	} {
+		if rec := do(api, "POST", "/risk", body); rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	if report := decode[CTRReport](t, do(api, "GET", "/reports/ctr?date=2026-03-10", "")); len(report.Accounts) != 0 {
+		t.Fatalf("credit counted towards the CTR threshold: %+v", report.Accounts)
+	}
+}
+
+func TestCreditsSkipVelocity(t *testing.T) {
+	tn := newTestTenant(t, defaultTenant)
+	credit := Transaction{Amount: dollars(20), Merchant: "m", AccountID: "acct-1", Type: TypeCredit}
+	for i := 0; i < 10; i++ {
+		if res := processAt(t, tn, noon.Add(time.Duration(i)*time.Second), credit); res.RiskLevel != "LOW" {
+			t.Fatalf("credit %d: got %s (%s)", i+1, res.RiskLevel, res.Reason)
+		}
+	}
+	if got := tn.velocityScore("acct-1", noon.Add(10*time.Second)); got != 0 {
+		t.Fatalf("credits raised the velocity score to %v", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rates maps ISO 4217 currency codes to the USD value of one unit. Thresholds
+// are expressed in USD, so amounts are converted with these before scoring.
+var rates = map[string]float64{
+	"USD": 1,
+	"EUR": 1.08,
+	"GBP": 1.27,
+	"CAD": 0.74,
+	"JPY": 0.0067,
+}
+
+// loadRates reads a JSON object of currency code to USD rate from path.
+func loadRates(path string) (map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rates: %w", err)
+	}
+	var raw map[string]float64
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse rates: %w", err)
+	}
+	out := map[string]float64{"USD": 1}
+	for code, rate := range raw {
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("rate for %s must be positive", code)
+		}
+		out[strings.ToUpper(code)] = rate
+	}
+	return out, nil
+}
+
+// currencyOf returns the transaction's currency code, defaulting to USD.
+func currencyOf(t Transaction) string {
+	if t.Currency == "" {
+		return "USD"
+	}
+	return strings.ToUpper(strings.TrimSpace(t.Currency))
+}
+
+// inUSD returns a copy of t with its amount converted to USD, rounded half
+// up to the cent.
+func inUSD(t Transaction) Transaction {
+	cur := currencyOf(t)
+	if cur == "USD" {
+		return t
+	}
+	usd, err := convertAmount(t.Amount, rates[cur])
+	if err != nil {
+		// validateTransaction rejects such amounts; saturate rather than wrap
+		// for anything else.
+		usd = math.MaxInt64
+		if t.Amount < 0 {
+			usd = math.MinInt64
+		}
+	}
+	t.Amount, t.Currency = usd, "USD"
+	return t
+}
+
+var errConversionOverflow = errors.New("amount overflows when converted to USD")
+
+// convertAmount multiplies m by rate, rounding half up to the cent. It fails
+// rather than overflow when the result does not fit in Money.
+func convertAmount(m Money, rate float64) (Money, error) {
+	v := float64(m) * rate
+	// 2^63 is exactly representable, unlike math.MaxInt64.
+	if math.IsNaN(v) || v >= 1<<63 || v < -(1<<63) {
+		return 0, errConversionOverflow
+	}
+	return Money(RoundHalfUp(v, 0)), nil
+}
+
+// defaultCurrencyPlaces is the number of decimal places amounts are shown
+// with in currencies missing from currencyPlaces.
+const defaultCurrencyPlaces = 2
+
+// currencyPlaces overrides the display precision of currencies whose minor
+// unit isn't a hundredth.
+var currencyPlaces = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+// placesFor returns the display precision of currency.
+func placesFor(currency string) int {
+	if p, ok := currencyPlaces[currency]; ok {
+		return p
+	}
+	return defaultCurrencyPlaces
+}
+
+// subUnitReason explains a decision escalated by applySubUnitPrecision.
+const subUnitReason = "sub-unit precision"
+
+// hasSubUnitPrecision reports whether t's amount was submitted with more
+// decimal places than its currency has, e.g. $10.001. Skimming fractions of
+// the minor unit is a known fraud pattern. t must not be converted yet.
+func hasSubUnitPrecision(t Transaction) bool {
+	return t.amountPlaces > placesFor(currencyOf(t))
+}
+
+// applySubUnitPrecision escalates a LOW decision to MEDIUM when subUnit is
+// set. Higher levels pass through unchanged.
+func applySubUnitPrecision(d Decision, subUnit bool) Decision {
+	if subUnit && d.RiskLevel == "LOW" {
+		return Decision{RiskLevel: "MEDIUM", Reason: subUnitReason}
+	}
+	return d
+}
+
+// parseCurrencyPlaces parses precision overrides such as "JPY=0,BHD=3".
+func parseCurrencyPlaces(s string) (map[string]int, error) {
+	out := map[string]int{}
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		code, places, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("currency precision %q: want CODE=places", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(places))
+		if err != nil || n < 0 || n > 4 {
+			return nil, fmt.Errorf("currency precision %q: places must be 0 to 4", entry)
+		}
+		out[strings.ToUpper(strings.TrimSpace(code))] = n
+	}
+	return out, nil
+}
+
+// RoundHalfUp rounds amount to places decimal places, rounding halves away
+// from zero. It rounds the shortest decimal representation of amount, so
+// 1.005 rounds to 1.01 even though its binary value is slightly below.
+func RoundHalfUp(amount float64, places int) float64 {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return amount
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(amount, 'g', -1, 64))
+	if !ok {
+		return amount
+	}
+	v, _ := strconv.ParseFloat(r.FloatString(places), 64)
+	return v
+}
+
+// formatAmount formats m in currency's display precision, rounding halves
+// away from zero. Only the display is rounded; m keeps its full precision.
+func formatAmount(m Money, currency string) string {
+	return new(big.Rat).SetFrac64(int64(m), 100).FloatString(placesFor(currency))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCurrencyNormalization(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, tc := range []struct {
+		name, body, level string
+	}{
+		{"USD passthrough", `{"amount": 9300, "merchant": "m", "currency": "USD"}`, "MEDIUM"},
+		{"no currency is USD", `{"amount": 9300, "merchant": "m"}`, "MEDIUM"},
+		{"EUR converted across HIGH", `{"amount": 9300, "merchant": "m", "currency": "eur"}`, "HIGH"},
+	} {
+		rec := do(api, "POST", "/risk", tc.body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tc.name, rec.Code, rec.Body)
+		}
+		if res := decode[ScoreResult](t, rec); res.RiskLevel != tc.level {
+			t.Errorf("%s: got %s, want %s", tc.name, res.RiskLevel, tc.level)
+		}
+	}
+}
+
+func TestUnknownCurrencyRejected(t *testing.T) {
+	api, _ := newTestAPI(t)
+	rec := do(api, "POST", "/risk", `{"amount": 10, "merchant": "m", "currency": "XYZ"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", rec.Code)
+	}
+}
+
+func TestRoundHalfUp(t *testing.T) {
+	for _, tc := range []struct {
+		in     float64
+		places int
+		want   float64
+	}{
+		{12.344999, 2, 12.34},
+		{12.345, 2, 12.35},
+		{1.005, 2, 1.01},
+		{-1.005, 2, -1.01},
+		{2.5, 0, 3},
+		{1234.5678, 0, 1235},
+		{0.0005, 3, 0.001},
+	} {
+		if got := RoundHalfUp(tc.in, tc.places); got != tc.want {
+			t.Errorf("RoundHalfUp(%v, %d) = %v, want %v", tc.in, tc.places, got, tc.want)
+		}
+	}
+}
+
+func TestAmountDisplayPrecision(t *testing.T) {
+	for _, tc := range []struct {
+		amount   Money
+		currency string
+		want     string
+	}{
+		{1234, "USD", "12.34"},
+		{1250, "JPY", "13"},
+		{1249, "JPY", "12"},
+		{1234, "BHD", "12.340"},
+	} {
+		if got := formatAmount(tc.amount, tc.currency); got != tc.want {
+			t.Errorf("formatAmount(%d, %s) = %s, want %s", tc.amount, tc.currency, got, tc.want)
+		}
+	}
+	b, err := json.Marshal(Transaction{Amount: 150000, Currency: "JPY", Merchant: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"amount":1500,`) {
+		t.Fatalf("JPY amount not shown with 0 places: %s", b)
+	}
+}
+
+func TestParseCurrencyPlaces(t *testing.T) {
+	got, err := parseCurrencyPlaces("jpy=0, BHD=3")
+	if err != nil || got["JPY"] != 0 || got["BHD"] != 3 || len(got) != 2 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	for _, in := range []string{"JPY", "JPY=x", "USD=5"} {
+		if _, err := parseCurrencyPlaces(in); err == nil {
+			t.Errorf("%q: no error", in)
+		}
+	}
+}
+
+func TestSubUnitPrecision(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &rates, map[string]float64{"USD": 1, "JPY": 0.0067, "BHD": 2.65})
+	for _, tc := range []struct {
+		body, level, reason string
+	}{
+		{`{"amount": 10.001, "merchant": "m"}`, "MEDIUM", subUnitReason},
+		{`{"amount": "10.001", "merchant": "m"}`, "MEDIUM", subUnitReason},
+		{`{"amount": 10.00, "merchant": "m"}`, "LOW", noRulesReason},
+		{`{"amount": 10.5, "merchant": "m"}`, "LOW", noRulesReason},
+		{`{"amount": 1000.5, "merchant": "m", "currency": "JPY"}`, "MEDIUM", subUnitReason},
+		{`{"amount": 10.001, "merchant": "m", "currency": "BHD"}`, "LOW", noRulesReason},
+		{`{"amount": 20000.001, "merchant": "m"}`, "HIGH", "amount exceeds $10000.00 threshold"},
+	} {
+		res := decode[ScoreResult](t, do(api, "POST", "/risk", tc.body))
+		if res.RiskLevel != tc.level || res.Reason != tc.reason {
+			t.Errorf("%s: got %s (%s), want %s (%s)", tc.body, res.RiskLevel, res.Reason, tc.level, tc.reason)
+		}
+	}
+}
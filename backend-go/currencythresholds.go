@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Threshold modes. In convert mode every amount is converted to USD and
+// judged against the USD thresholds. In native mode an amount in a currency
+// listed in thresholdsByCurrency is judged in that currency against its own
+// MEDIUM and HIGH thresholds, so exchange-rate drift cannot move it across a
+// line; other currencies are still converted.
+const (
+	thresholdModeConvert = "convert"
+	thresholdModeNative  = "native"
+)
+
+var thresholdMode = thresholdModeConvert
+
+// thresholdsByCurrency maps upper-case currency codes to their native
+// thresholds, used in native mode in place of the global and merchant ones.
+var thresholdsByCurrency = map[string]amountThreshold{}
+
+// parseThresholdMode validates a -threshold-mode value.
+func parseThresholdMode(s string) (string, error) {
+	switch s {
+	case thresholdModeConvert, thresholdModeNative:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid threshold mode %q: want convert or native", s)
+}
+
+// loadCurrencyThresholds reads a JSON object of currency code to
+// {"medium": ..., "high": ...} in that currency from path.
+func loadCurrencyThresholds(path string) (map[string]amountThreshold, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read currency thresholds: %w", err)
+	}
+	var raw map[string]amountThreshold
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse currency thresholds: %w", err)
+	}
+	m := make(map[string]amountThreshold, len(raw))
+	for code, th := range raw {
+		if th.Medium <= 0 || th.High <= 0 {
+			return nil, fmt.Errorf("currency %s: thresholds must be positive", code)
+		}
+		if th.Medium >= th.High {
+			return nil, fmt.Errorf("currency %s: medium must be less than high", code)
+		}
+		m[strings.ToUpper(strings.TrimSpace(code))] = th
+	}
+	return m, nil
+}
+
+// applyNativeThresholds judges t, in its own currency, against that
+// currency's thresholds when native mode applies to it.
+func applyNativeThresholds(t Transaction, s Settings) (Decision, bool) {
+	if thresholdMode != thresholdModeNative {
+		return Decision{}, false
+	}
+	cur := currencyOf(t)
+	th, ok := thresholdsByCurrency[cur]
+	if !ok {
+		return Decision{}, false
+	}
+	s.MediumThreshold, s.HighThreshold = th.Medium, th.High
+	return applyThresholdsIn(t, s, cur+" "), true
+}
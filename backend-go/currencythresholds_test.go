@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNativeCurrencyThresholds(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	setVar(t, &thresholdsByCurrency, map[string]amountThreshold{"EUR": {Medium: dollars(2000), High: dollars(8000)}})
+	for _, tc := range []struct {
+		mode     string
+		amount   Money
+		currency string
+		want     string
+	}{
+		// €1,500 is $1,620 and €9,000 is $9,720.
+		{thresholdModeConvert, dollars(1500), "EUR", "MEDIUM"},
+		{thresholdModeNative, dollars(1500), "EUR", "LOW"},
+		{thresholdModeConvert, dollars(9000), "EUR", "MEDIUM"},
+		{thresholdModeNative, dollars(9000), "eur", "HIGH"},
+		{thresholdModeNative, dollars(2500), "EUR", "MEDIUM"},
+		// GBP has no native thresholds: £900 is still judged as $1,143.
+		{thresholdModeNative, dollars(900), "GBP", "MEDIUM"},
+		{thresholdModeNative, dollars(900), "", "LOW"},
+	} {
+		setVar(t, &thresholdMode, tc.mode)
+		d, err := tn.evaluate(t.Context(), Transaction{Merchant: "Corner Shop", Amount: tc.amount, Currency: tc.currency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.RiskLevel != tc.want {
+			t.Errorf("%s %s %s: got %s (%s), want %s", tc.mode, tc.amount, tc.currency, d.RiskLevel, d.Reason, tc.want)
+		}
+	}
+}
+
+func TestNativeThresholdsThroughAPI(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &thresholdMode, thresholdModeNative)
+	setVar(t, &thresholdsByCurrency, map[string]amountThreshold{"EUR": {Medium: dollars(2000), High: dollars(8000)}})
+	res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 9000, "currency": "EUR", "merchant": "Corner Shop"}`))
+	if res.RiskLevel != "HIGH" {
+		t.Fatalf("got %s (%s), want HIGH", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestParseThresholdMode(t *testing.T) {
+	for _, s := range []string{thresholdModeConvert, thresholdModeNative} {
+		if got, err := parseThresholdMode(s); got != s || err != nil {
+			t.Errorf("%s: got %q, %v", s, got, err)
+		}
+	}
+	if _, err := parseThresholdMode("both"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestLoadCurrencyThresholds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "currencies.json")
+	os.WriteFile(path, []byte(`{" eur": {"medium": 900, "high": 9000}}`), 0o600)
+	m, err := loadCurrencyThresholds(path)
+	if err != nil || m["EUR"] != (amountThreshold{Medium: dollars(900), High: dollars(9000)}) {
+		t.Fatalf("got %v, %v", m, err)
+	}
+	for _, body := range []string{`{"EUR": {"medium": 9000, "high": 900}}`, `{"EUR": {"medium": 0, "high": 900}}`} {
+		os.WriteFile(path, []byte(body), 0o600)
+		if _, err := loadCurrencyThresholds(path); err == nil {
+			t.Errorf("%s: no error", body)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// debugEnabled turns on GET /debug/info. The endpoint exposes configuration
+// and runtime details, so it stays off unless asked for.
+var debugEnabled = false
+
+// startTime is when the process started, for uptime.
+var startTime = time.Now()
+
+// DebugInfo is the response body of GET /debug/info.
+type DebugInfo struct {
+	Config     DebugConfig     `json:"config"`
+	Uptime     string          `json:"uptime"`
+	GoVersion  string          `json:"go_version"`
+	Goroutines int             `json:"goroutines"`
+	Memory     DebugMemoryInfo `json:"memory"`
+}
+
+// DebugConfig is the effective configuration of the requesting tenant and
+// the process.
+type DebugConfig struct {
+	Tenant    string          `json:"tenant"`
+	Settings  Settings        `json:"settings"`
+	RuleCount int             `json:"rule_count"`
+	Features  map[string]bool `json:"features"`
+}
+
+// DebugMemoryInfo is a subset of runtime.MemStats.
+type DebugMemoryInfo struct {
+	Alloc      uint64 `json:"alloc_bytes"`
+	TotalAlloc uint64 `json:"total_alloc_bytes"`
+	Sys        uint64 `json:"sys_bytes"`
+	HeapInUse  uint64 `json:"heap_inuse_bytes"`
+	NumGC      uint32 `json:"num_gc"`
+}
+
+// features reports which optional behaviors are switched on.
+func features() map[string]bool {
+	return map[string]bool{
+		"velocity":         velocityThreshold > 0,
+		"anomaly":          anomalyMultiple > 0,
+		"merchant_outlier": outlierDeviations > 0,
+		"daily_limit":      dailyLimit > 0 || len(accountDailyLimits) > 0,
+		"off_hours":        offHoursEnabled,
+		"dedup":            dedupEnabled,
+		"unique_ids":       uniqueIDs,
+		"strict_merchants": strictMerchants,
+		"envelope":         envelopeEnabled,
+		"simulate":         simulateEnabled,
+		"webhook":          webhook != nil,
+		"kafka":            publisher != nil,
+	}
+}
+
+// debugInfo serves GET /debug/info when debugEnabled is set.
+func (s *Server) debugInfo(w http.ResponseWriter, r *http.Request) {
+	if !debugEnabled {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	tn := s.tenant(r.Context())
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	info := DebugInfo{
+		Config: DebugConfig{
+			Tenant:    tn.ID,
+			Settings:  tn.config.Get(),
+			RuleCount: len(tn.rules.Get()),
+			Features:  features(),
+		},
+		Uptime:     time.Since(startTime).Round(time.Second).String(),
+		GoVersion:  runtime.Version(),
+		Goroutines: runtime.NumGoroutine(),
+		Memory: DebugMemoryInfo{
+			Alloc:      ms.Alloc,
+			TotalAlloc: ms.TotalAlloc,
+			Sys:        ms.Sys,
+			HeapInUse:  ms.HeapInuse,
+			NumGC:      ms.NumGC,
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+)
+
+func TestDebugInfo(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &debugEnabled, true)
+	setVar(t, &dedupEnabled, true)
+	rec := do(api, "GET", "/debug/info", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	info := decode[DebugInfo](t, rec)
+	if info.Config.Tenant != tn.ID || info.Config.RuleCount != 2 || info.Config.Settings != tn.config.Get() {
+		t.Errorf("config %+v", info.Config)
+	}
+	if !info.Config.Features["dedup"] || info.Config.Features["envelope"] {
+		t.Errorf("features %v", info.Config.Features)
+	}
+	if info.GoVersion != runtime.Version() || info.Goroutines <= 0 || info.Uptime == "" {
+		t.Errorf("runtime %+v", info)
+	}
+	if info.Memory.Sys == 0 || info.Memory.TotalAlloc < info.Memory.Alloc {
+		t.Errorf("memory %+v", info.Memory)
+	}
+}
+
+func TestDebugInfoGuarded(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if rec := do(api, "GET", "/debug/info", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("without -debug: status %d, want 404", rec.Code)
+	}
+	setVar(t, &debugEnabled, true)
+	if rec := do(api, "GET", "/debug/info", "", "X-API-Key", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without an API key: status %d, want 401", rec.Code)
+	}
+	if rec := do(api, "POST", "/debug/info", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: status %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Payload deduplication for clients that retry without an Idempotency-Key:
+// when enabled, a /risk payload identical to one scored within dedupWindow
+// gets the earlier decision back instead of being scored and recorded again.
+// It is off by default because legitimate repeat charges look identical too.
+var (
+	dedupEnabled = false
+	dedupWindow  = 10 * time.Second
+)
+
+// recentPayloads caches decisions by payloadHash.
+var recentPayloads = newIdempotencyCache()
+
+// uniqueIDs rejects a client-supplied transaction ID already used within
+// dedupWindow, independently of dedupEnabled.
+var uniqueIDs = false
+
+var errDuplicateID = errors.New("transaction id already submitted")
+
+// recentIDs holds the client-supplied transaction IDs claimed within
+// dedupWindow, scoped to the tenant.
+var recentIDs = newIdempotencyCache()
+
+// payloadHash identifies a transaction by the fields a retry repeats: ID,
+// amount, currency, type, merchant, account and timestamp, scoped to the
+// tenant. Merchants are compared case-insensitively; a missing timestamp
+// hashes as missing, so untimestamped retries still match.
+func payloadHash(tenant string, t Transaction) string {
+	var at int64
+	if !t.Timestamp.IsZero() {
+		at = t.Timestamp.UnixNano()
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%d\x00%s\x00%s\x00%s\x00%s\x00%d",
+		tenant, t.ID, t.Amount, currencyOf(t), typeOf(t), normalizeMerchant(t.Merchant), t.AccountID, at))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDedupReturnsCachedDecision(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &dedupEnabled, true)
+	setVar(t, &recentPayloads, newIdempotencyCache())
+	body := `{"amount": 20, "merchant": "Corner Shop", "account_id": "acct-1"}`
+	first := do(api, "POST", "/risk", body)
+	if first.Code != http.StatusOK || first.Header().Get("X-Duplicate") != "" {
+		t.Fatalf("first: status %d, X-Duplicate %q", first.Code, first.Header().Get("X-Duplicate"))
+	}
+	setClock(t, noon.Add(5*time.Second))
+	dup := do(api, "POST", "/risk", `{"amount": 20, "merchant": "corner shop", "account_id": "acct-1"}`)
+	if dup.Header().Get("X-Duplicate") != "true" {
+		t.Fatal("retry within the window was scored again")
+	}
+	if a, b := decode[ScoreResult](t, first), decode[ScoreResult](t, dup); a.TransactionID != b.TransactionID || a.RiskLevel != b.RiskLevel {
+		t.Fatalf("duplicate got %+v, want %+v", b, a)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 1 {
+		t.Fatalf("%d records, want 1", n)
+	}
+	if got := tn.velocityScore("acct-1", noon.Add(5*time.Second)); got > 1 {
+		t.Fatalf("duplicate counted towards velocity: score %v", got)
+	}
+}
+
+func TestDedupDistinctPayloads(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &dedupEnabled, true)
+	setVar(t, &recentPayloads, newIdempotencyCache())
+	do(api, "POST", "/risk", `{"amount": 20, "merchant": "m"}`)
+	if rec := do(api, "POST", "/risk", `{"amount": 21, "merchant": "m"}`); rec.Header().Get("X-Duplicate") != "" {
+		t.Fatal("different amount treated as a duplicate")
+	}
+	setClock(t, noon.Add(dedupWindow+time.Second))
+	if rec := do(api, "POST", "/risk", `{"amount": 20, "merchant": "m"}`); rec.Header().Get("X-Duplicate") != "" {
+		t.Fatal("repeat after the window treated as a duplicate")
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 3 {
+		t.Fatalf("%d records, want 3", n)
+	}
+}
+
+func TestDedupOffByDefault(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &recentPayloads, newIdempotencyCache())
+	body := `{"amount": 20, "merchant": "m"}`
+	do(api, "POST", "/risk", body)
+	if rec := do(api, "POST", "/risk", body); rec.Header().Get("X-Duplicate") != "" {
+		t.Fatal("duplicate detected with -dedup unset")
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 2 {
+		t.Fatalf("%d records, want 2", n)
+	}
+}
+
+func TestUniqueIDsRejectsReuse(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &uniqueIDs, true)
+	setVar(t, &recentIDs, newIdempotencyCache())
+	if rec := do(api, "POST", "/risk", `{"amount": 20, "merchant": "m", "id": "tx-1"}`); rec.Code != http.StatusOK {
+		t.Fatalf("first: status %d", rec.Code)
+	}
+	if rec := do(api, "POST", "/risk", `{"amount": 30, "merchant": "other", "id": "tx-1"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("reused id: status %d, want 409", rec.Code)
+	}
+	// Generated IDs are never claimed, and the window expires.
+	do(api, "POST", "/risk", `{"amount": 20, "merchant": "m"}`)
+	setClock(t, noon.Add(dedupWindow+time.Second))
+	if rec := do(api, "POST", "/risk", `{"amount": 20, "merchant": "m", "id": "tx-1"}`); rec.Code != http.StatusOK {
+		t.Fatalf("reuse after the window: status %d", rec.Code)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 3 {
+		t.Fatalf("%d records, want 3", n)
+	}
+}
+
+func TestUniqueIDsReleasedOnFailure(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &uniqueIDs, true)
+	setVar(t, &recentIDs, newIdempotencyCache())
+	store := &flakyStore{MemoryStore: NewMemoryStore(defaultHistorySize)}
+	store.failures.Store(1)
+	tn.store = store
+	body := `{"amount": 20, "merchant": "m", "id": "tx-1"}`
+	if rec := do(api, "POST", "/risk", body); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("store down: status %d, want 500", rec.Code)
+	}
+	if rec := do(api, "POST", "/risk", body); rec.Code != http.StatusOK {
+		t.Fatalf("retry: status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if rec := do(api, "POST", "/risk", body); rec.Code != http.StatusConflict {
+		t.Fatalf("after the retry: status %d, want 409", rec.Code)
+	}
+	if n := store.Len(); n != 1 {
+		t.Fatalf("%d records, want 1", n)
+	}
+}
+
+func TestUniqueIDsOffAllowsReuse(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &recentIDs, newIdempotencyCache())
+	for i := 0; i < 2; i++ {
+		if rec := do(api, "POST", "/risk", `{"amount": 20, "merchant": "m", "id": "tx-1"}`); rec.Code != http.StatusOK {
+			t.Fatalf("attempt %d: status %d", i, rec.Code)
+		}
+	}
+}
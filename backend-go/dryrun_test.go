@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDryRunLeavesNoTrace(t *testing.T) {
+	api, tn := newTestAPI(t)
+	srv, events, _ := webhookTarget(t, 0)
+	setVar(t, &webhook, newWebhookNotifier(srv.URL))
+	setClock(t, noon)
+	tx := Transaction{Amount: dollars(20), Merchant: "Corner Shop", AccountID: "acct-1"}
+	for i := 0; i < 5; i++ {
+		if _, err := tn.process(t.Context(), tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := tn.store.(*MemoryStore)
+	count, score := store.Len(), tn.velocityScore("acct-1", noon)
+
+	rec := do(api, "POST", "/risk?dry_run=true", `{"amount": 20, "merchant": "Corner Shop", "account_id": "acct-1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, rec); res.RiskLevel != "HIGH" || !res.DryRun {
+		t.Fatalf("got %s (dry_run %v), want a HIGH dry run", res.RiskLevel, res.DryRun)
+	}
+	rec = do(api, "POST", "/risk?dry_run=true", `{"amount": 20000, "merchant": "m", "id": "tx-dry"}`)
+	if res := decode[ScoreResult](t, rec); res.RiskLevel != "HIGH" || !res.DryRun {
+		t.Fatalf("got %s (dry_run %v), want a HIGH dry run", res.RiskLevel, res.DryRun)
+	}
+	if n := store.Len(); n != count {
+		t.Fatalf("dry runs stored %d transactions", n-count)
+	}
+	if got := tn.velocityScore("acct-1", noon); got != score {
+		t.Fatalf("velocity score moved from %v to %v", score, got)
+	}
+
+	// The first webhook event must be the real request's, not the dry run's.
+	do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m", "id": "tx-real"}`)
+	select {
+	case ev := <-events:
+		if ev.Transaction.ID != "tx-real" {
+			t.Fatalf("webhook got %q, want tx-real", ev.Transaction.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no webhook event")
+	}
+}
+
+func TestDryRunInvalidFlag(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if rec := do(api, "POST", "/risk?dry_run=maybe", `{"amount": 20, "merchant": "m"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// envelopeEnabled wraps every JSON response in an envelope with request
+// metadata: {"data": ..., "meta": ...} on success and {"error": ..., "meta":
+// ...} on failure. It is off by default so existing clients keep the bare
+// bodies.
+var envelopeEnabled = false
+
+// envelopeVersion is the envelope format reported in meta.version.
+const envelopeVersion = "1.0"
+
+// envelopeMeta is the meta object of an enveloped response.
+type envelopeMeta struct {
+	RequestID string    `json:"request_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Version   string    `json:"version"`
+}
+
+type envelope struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error json.RawMessage `json:"error,omitempty"`
+	Meta  envelopeMeta    `json:"meta"`
+}
+
+// envelopeMiddleware wraps JSON responses, problem details included, when
+// envelopeEnabled is set. The envelope is served as application/json, since
+// a wrapped problem is no longer an RFC 7807 document. Other content types
+// and empty bodies pass through unchanged. The wrapped handler is served
+// uncompressed and the envelope gzipped afterwards, so routes that compress
+// their own responses are enveloped too. It must run inside withRequestID.
+func envelopeMiddleware(next http.Handler) http.Handler {
+	if !envelopeEnabled {
+		return next
+	}
+	return gzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.Header.Del("Accept-Encoding")
+		ew := &envelopeWriter{header: w.Header().Clone()}
+		next.ServeHTTP(ew, r)
+
+		for k, v := range ew.header {
+			w.Header()[k] = v
+		}
+		if ew.status == 0 {
+			ew.status = http.StatusOK
+		}
+		body := ew.buf.Bytes()
+		mt, _, _ := mime.ParseMediaType(ew.header.Get("Content-Type"))
+		if (mt != "application/json" && mt != "application/problem+json") || !json.Valid(body) {
+			w.WriteHeader(ew.status)
+			w.Write(body)
+			return
+		}
+		env := envelope{Meta: envelopeMeta{RequestID: requestIDFrom(r.Context()), Timestamp: now().UTC(), Version: envelopeVersion}}
+		if ew.status >= http.StatusBadRequest {
+			env.Error = body
+		} else {
+			env.Data = body
+		}
+		out, err := json.Marshal(env)
+		if err != nil {
+			w.WriteHeader(ew.status)
+			w.Write(body)
+			return
+		}
+		out = append(out, '\n')
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(out)))
+		w.WriteHeader(ew.status)
+		w.Write(out)
+	}))
+}
+
+// envelopeWriter buffers a handler's response for envelopeMiddleware.
+type envelopeWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+}
+
+func (ew *envelopeWriter) Header() http.Header { return ew.header }
+
+func (ew *envelopeWriter) WriteHeader(code int) {
+	if ew.status == 0 {
+		ew.status = code
+	}
+}
+
+func (ew *envelopeWriter) Write(p []byte) (int, error) {
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+	return ew.buf.Write(p)
+}
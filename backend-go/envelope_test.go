@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// envelopeResponse is the decoded shape of an enveloped response.
+type envelopeResponse struct {
+	Data  json.RawMessage `json:"data"`
+	Error json.RawMessage `json:"error"`
+	Meta  envelopeMeta    `json:"meta"`
+}
+
+// newEnvelopeAPI returns the test API behind withRequestID and
+// envelopeMiddleware, as main mounts it.
+func newEnvelopeAPI(t *testing.T, enabled bool) http.Handler {
+	t.Helper()
+	api, _ := newTestAPI(t)
+	setVar(t, &envelopeEnabled, enabled)
+	return withRequestID(envelopeMiddleware(api))
+}
+
+func TestEnvelopeSuccessAndError(t *testing.T) {
+	setClock(t, noon)
+	api := newEnvelopeAPI(t, true)
+
+	rec := do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m"}`, "X-Request-ID", "req-1")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	env := decode[envelopeResponse](t, rec)
+	if env.Error != nil || env.Meta.RequestID != "req-1" || !env.Meta.Timestamp.Equal(noon) || env.Meta.Version != envelopeVersion {
+		t.Fatalf("envelope %+v", env)
+	}
+	var res ScoreResult
+	if err := json.Unmarshal(env.Data, &res); err != nil || res.RiskLevel != "HIGH" {
+		t.Fatalf("data %s: %v", env.Data, err)
+	}
+
+	rec = do(api, "POST", "/risk", `{"amount": `, "X-Request-ID", "req-2")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", rec.Code)
+	}
+	env = decode[envelopeResponse](t, rec)
+	var e ErrorResponse
+	if env.Data != nil || env.Meta.RequestID != "req-2" || json.Unmarshal(env.Error, &e) != nil || e.Status != http.StatusBadRequest || e.Error == "" {
+		t.Fatalf("envelope %+v", env)
+	}
+}
+
+func TestEnvelopeWrapsProblemAsJSON(t *testing.T) {
+	api := newEnvelopeAPI(t, true)
+	setVar(t, &problemJSON, true)
+	rec := do(api, "POST", "/risk", `{"amount": `)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type %q, want application/json", ct)
+	}
+	env := decode[envelopeResponse](t, rec)
+	var p Problem
+	if err := json.Unmarshal(env.Error, &p); err != nil || p.Status != http.StatusBadRequest || p.Type == "" {
+		t.Fatalf("error %s: %v", env.Error, err)
+	}
+}
+
+func TestEnvelopeDisabledIsBare(t *testing.T) {
+	api := newEnvelopeAPI(t, false)
+	var bare map[string]json.RawMessage
+	if err := json.Unmarshal(do(api, "POST", "/risk", `{"amount": 5, "merchant": "m"}`).Body.Bytes(), &bare); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bare["risk_level"]; !ok || bare["data"] != nil || bare["meta"] != nil {
+		t.Fatalf("success body %v is not a bare decision", bare)
+	}
+	if e := decode[ErrorResponse](t, do(api, "POST", "/risk", `{"amount": `)); e.Status != http.StatusBadRequest || e.Error == "" {
+		t.Fatalf("error body %+v is not a bare error", e)
+	}
+}
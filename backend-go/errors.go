@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorResponse is the JSON body written for every error response.
+type ErrorResponse struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+	// Violations lists schema violations for a rejected payload.
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// problemJSON switches error responses to RFC 7807 problem details served as
+// application/problem+json. It is off by default so existing clients keep
+// ErrorResponse bodies.
+var problemJSON = false
+
+// Problem is the RFC 7807 form of an error response. Instance is the
+// request ID, so a reported problem can be found in the logs.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+	// Violations lists schema violations for a rejected payload.
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// problemType is the type and title of a class of problem.
+type problemType struct {
+	uri, title string
+}
+
+// problemTypes maps the statuses the API answers with to problem types.
+// Other statuses use "about:blank" and the standard status text.
+var problemTypes = map[int]problemType{
+	http.StatusBadRequest:            {"/problems/malformed-request", "Malformed request"},
+	http.StatusUnauthorized:          {"/problems/unauthorized", "Missing or invalid credentials"},
+	http.StatusForbidden:             {"/problems/forbidden", "Forbidden"},
+	http.StatusNotFound:              {"/problems/not-found", "Resource not found"},
+	http.StatusMethodNotAllowed:      {"/problems/method-not-allowed", "Method not allowed"},
+	http.StatusNotAcceptable:         {"/problems/not-acceptable", "Response type not supported"},
+	http.StatusConflict:              {"/problems/conflict", "Conflicting request"},
+	http.StatusRequestEntityTooLarge: {"/problems/too-large", "Request too large"},
+	http.StatusUnsupportedMediaType:  {"/problems/unsupported-media-type", "Unsupported media type"},
+	http.StatusUnprocessableEntity:   {"/problems/validation-failed", "Validation failed"},
+	http.StatusTooManyRequests:       {"/problems/rate-limited", "Rate limit exceeded"},
+	http.StatusInternalServerError:   {"/problems/internal-error", "Internal server error"},
+	http.StatusServiceUnavailable:    {"/problems/unavailable", "Service unavailable"},
+}
+
+// writeError writes a JSON error body with the given status code.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeErrorResponse(w, ErrorResponse{Error: msg, Status: status})
+}
+
+// writeErrorResponse writes e as an ErrorResponse or, with problemJSON, as a
+// Problem.
+func writeErrorResponse(w http.ResponseWriter, e ErrorResponse) {
+	if !problemJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(e.Status)
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	pt, ok := problemTypes[e.Status]
+	if !ok {
+		pt = problemType{"about:blank", http.StatusText(e.Status)}
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:       pt.uri,
+		Title:      pt.title,
+		Status:     e.Status,
+		Detail:     e.Error,
+		Instance:   w.Header().Get("X-Request-ID"),
+		Violations: e.Violations,
+	})
+}
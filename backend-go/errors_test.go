@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorResponsesAreJSON(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, tc := range []struct {
+		name, method, body string
+		status             int
+	}{
+		{"malformed payload", "POST", `{"amount": `, http.StatusBadRequest},
+		{"wrong method", "GET", "", http.StatusMethodNotAllowed},
+	} {
+		rec := do(api, tc.method, "/risk", tc.body)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: Content-Type %q", tc.name, ct)
+		}
+		e := decode[ErrorResponse](t, rec)
+		if e.Status != tc.status || e.Error == "" {
+			t.Errorf("%s: body %+v", tc.name, e)
+		}
+	}
+}
+
+// problemFor decodes rec as a problem+json body.
+func problemFor(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type %q, want application/problem+json", ct)
+	}
+	return decode[Problem](t, rec)
+}
+
+func TestProblemJSONValidationError(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &problemJSON, true)
+	h := withRequestID(api)
+	rec := do(h, "POST", "/risk", `{"amount": "lots", "merchant": 7}`, "X-Request-ID", "req-42")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", rec.Code)
+	}
+	p := problemFor(t, rec)
+	if p.Type != "/problems/validation-failed" || p.Title != "Validation failed" || p.Status != http.StatusUnprocessableEntity || p.Detail == "" || p.Instance != "req-42" || len(p.Violations) == 0 {
+		t.Fatalf("problem %+v", p)
+	}
+
+	rec = do(h, "GET", "/risk", "", "X-Request-ID", "req-43")
+	if p := problemFor(t, rec); p.Type != "/problems/method-not-allowed" || p.Status != http.StatusMethodNotAllowed || p.Instance != "req-43" {
+		t.Fatalf("405 problem %+v", p)
+	}
+}
+
+func TestProblemJSONRateLimited(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &problemJSON, true)
+	setVar(t, &rateLimitRPS, 0.01)
+	setVar(t, &rateLimitBurst, 1)
+	h := withRequestID(api)
+	do(h, "GET", "/transactions", "")
+	rec := do(h, "GET", "/transactions", "", "X-Request-ID", "req-429")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("status %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	p := problemFor(t, rec)
+	if p.Type != "/problems/rate-limited" || p.Title != "Rate limit exceeded" || p.Status != http.StatusTooManyRequests || p.Detail != "rate limit exceeded" || p.Instance != "req-429" {
+		t.Fatalf("problem %+v", p)
+	}
+}
+
+func TestProblemJSONUnmappedStatus(t *testing.T) {
+	setVar(t, &problemJSON, true)
+	rec := httptest.NewRecorder()
+	writeError(rec, http.StatusTeapot, "short and stout")
+	if p := problemFor(t, rec); p.Type != "about:blank" || p.Title != http.StatusText(http.StatusTeapot) || p.Detail != "short and stout" {
+		t.Fatalf("problem %+v", p)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// now is the clock used for timestamps and time-windowed rules.
+var now = time.Now
+
+// degradeOnStoreError lets evaluate skip the history-based rules (account
+// anomaly and daily limit) when the store fails, rather than failing the
+// request. Such decisions are marked Degraded.
+var degradeOnStoreError = true
+
+// Decision is the outcome of evaluating a transaction: its risk level and a
+// human-readable explanation of why. Decisions returned to clients are
+// stamped with when they were made and the response schema version.
+// Degraded marks a decision made without the history-based rules because the
+// store could not be read.
+type Decision struct {
+	RiskLevel     string    `json:"risk_level" xml:"risk_level"`
+	Reason        string    `json:"reason" xml:"reason"`
+	EvaluatedAt   time.Time `json:"evaluated_at" xml:"evaluated_at"`
+	SchemaVersion string    `json:"schema_version" xml:"schema_version"`
+	Degraded      bool      `json:"degraded,omitempty" xml:"degraded,omitempty"`
+}
+
+// decisionSchemaVersion versions the shape of decision responses. Bump it
+// whenever a field is added, removed or changes meaning.
+const decisionSchemaVersion = "1.1"
+
+// stamped returns d marked as evaluated at at.
+func (d Decision) stamped(at time.Time) Decision {
+	d.EvaluatedAt, d.SchemaVersion = at.UTC().Truncate(time.Second), decisionSchemaVersion
+	return d
+}
+
+// noRulesReason explains a default LOW decision.
+const noRulesReason = "no rules triggered"
+
+// decide makes the history-independent part of a decision. A sanctions
+// watchlist hit forces HIGH and an allow- or deny-listed merchant forces LOW
+// or HIGH; these are final. Otherwise the matching rules decide under the
+// configured combining policy, falling back to the transaction's category
+// threshold and then the amount thresholds: the currency's own in native
+// threshold mode, else the merchant's own when it has them, else the
+// configured ones. A LOW amount given with more precision than its currency
+// allows becomes MEDIUM. Transactions from high-risk countries are escalated
+// to at least MEDIUM, or to HIGH above a lower amount threshold, and the
+// result is escalated one level when the transaction falls outside business
+// hours. Other amounts are compared in USD.
+func (tn *Tenant) decide(ctx context.Context, t Transaction) (d Decision, final bool) {
+	if reason := watchlistReason(t); reason != "" {
+		return Decision{RiskLevel: "HIGH", Reason: reason}, true
+	}
+	if isCredit(t) {
+		return decideCredit(inUSD(t)), true
+	}
+	if d, ok := tn.applyMerchantLists(t); ok {
+		return d, true
+	}
+	settings := tn.config.Get()
+	d, ok := tn.rules.Get().EvaluatePolicy(t, settings.CombinePolicy)
+	logFor(ctx).Debug("rule evaluation", "matched", ok, "risk_level", d.RiskLevel, "reason", d.Reason)
+	subUnit := hasSubUnitPrecision(t)
+	native := t
+	t = inUSD(t)
+	if !ok {
+		d, ok = applyCategoryThreshold(t)
+	}
+	if !ok {
+		d, ok = applyNativeThresholds(native, settings)
+	}
+	if !ok {
+		d = applyThresholds(t, settings.forMerchant(t.Merchant))
+	}
+	d = applySubUnitPrecision(d, subUnit)
+	d = applyCountryRisk(d, t)
+	at := t.Timestamp
+	if at.IsZero() {
+		at = now()
+	}
+	return applyOffHours(d, at), false
+}
+
+// historyReasons prefix the reasons of the rules evaluate applies on top of
+// decide. They depend on the history as it was when the transaction arrived.
+var historyReasons = []string{"account anomaly:", "merchant outlier:", "velocity:", dailyLimitReason}
+
+// historyBased reports whether reason was given by one of evaluate's
+// history-based rules.
+func historyBased(reason string) bool {
+	for _, p := range historyReasons {
+		if strings.HasPrefix(reason, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate decides the risk level for a transaction against the tenant's
+// rules and history: decide's result, with a LOW result escalated to MEDIUM
+// when the amount is anomalous for the account or an outlier for the
+// merchant, and any result escalated to HIGH when the account trips the
+// velocity rule. The daily limit applies to final decisions too, so an
+// allow-listed merchant cannot carry an account past it. The result is never
+// below the merchant's floor. With degradeOnStoreError, a store failure skips
+// the rules that need it instead of failing.
+func (tn *Tenant) evaluate(ctx context.Context, t Transaction) (Decision, error) {
+	d, final := tn.decide(ctx, t)
+	t = inUSD(t)
+	degraded := false
+	if !final && d.RiskLevel == "LOW" {
+		ad, anomalous, err := tn.anomalyCheck(ctx, t, now())
+		if err != nil {
+			if !degradeOnStoreError {
+				return Decision{}, err
+			}
+			logFor(ctx).Warn("history unavailable; skipping account anomaly rule", "error", err)
+			degraded = true
+		}
+		if anomalous {
+			d = ad
+		} else if od, outlier := tn.outlierCheck(t); outlier {
+			d = od
+		}
+	}
+	if !final && tn.velocityCheck(ctx, t.AccountID, now()) {
+		d = Decision{
+			RiskLevel: "HIGH",
+			Reason:    fmt.Sprintf("velocity: account %s scored above %g with a %s half-life", t.AccountID, velocityThreshold, velocityHalfLife),
+		}
+	}
+	over, err := tn.dailyLimitCheck(ctx, t)
+	if err != nil {
+		if !degradeOnStoreError {
+			return Decision{}, err
+		}
+		logFor(ctx).Warn("history unavailable; skipping daily limit", "error", err)
+		degraded = true
+	}
+	// A final HIGH keeps its own reason, such as a watchlist hit.
+	if over && !(final && d.RiskLevel == "HIGH") {
+		d = Decision{RiskLevel: "HIGH", Reason: dailyLimitReason}
+	}
+	d = applyMerchantFloor(d, t.Merchant)
+	d.Degraded = degraded
+	return d, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecisionReasons(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	for _, tc := range []struct {
+		tx     Transaction
+		reason string
+	}{
+		{Transaction{Merchant: "Starbucks", Amount: dollars(501)}, "coffee-shop anomaly: >$500 at Starbucks"},
+		{Transaction{Merchant: "Apple Store", Amount: dollars(4000)}, "tech-store exception: <$5000 at Apple Store"},
+		{Transaction{Merchant: "m", Amount: dollars(10001)}, "amount exceeds $10000.00 threshold"},
+		{Transaction{Merchant: "m", Amount: dollars(1001)}, "amount exceeds $1000.00 threshold"},
+		{Transaction{Merchant: "m", Amount: dollars(10)}, noRulesReason},
+	} {
+		d, err := tn.evaluate(t.Context(), tc.tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Reason != tc.reason {
+			t.Errorf("%s $%s: reason %q, want %q", tc.tx.Merchant, tc.tx.Amount, d.Reason, tc.reason)
+		}
+	}
+}
+
+func TestDecisionTimestampAndVersion(t *testing.T) {
+	api, _ := newTestAPI(t)
+	check := func(name string, fields map[string]any) {
+		t.Helper()
+		s, _ := fields["evaluated_at"].(string)
+		at, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Errorf("%s: evaluated_at %q: %v", name, s, err)
+		} else if !at.Equal(noon) || at.Location() != time.UTC {
+			t.Errorf("%s: evaluated_at %s, want %s in UTC", name, at, noon)
+		}
+		if v := fields["schema_version"]; v != decisionSchemaVersion {
+			t.Errorf("%s: schema_version %v, want %s", name, v, decisionSchemaVersion)
+		}
+	}
+	check("/risk", decode[map[string]any](t, do(api, "POST", "/risk", `{"amount": 10, "merchant": "m"}`)))
+	items := decode[[]map[string]any](t, do(api, "POST", "/risk/batch", `[{"amount": 10, "merchant": "m"}, {"amount": 20000, "merchant": "m"}]`))
+	if len(items) != 2 {
+		t.Fatalf("got %d batch results", len(items))
+	}
+	for i, item := range items {
+		check(fmt.Sprintf("batch item %d", i), item)
+	}
+}
+
+// historyDownStore records transactions but fails every history lookup, like
+// a database whose read replica is down.
+type historyDownStore struct {
+	*MemoryStore
+}
+
+var errHistoryDown = errors.New("history unavailable")
+
+func (historyDownStore) AccountTotal(context.Context, string, time.Time) (Money, error) {
+	return 0, errHistoryDown
+}
+
+func (historyDownStore) AccountBaseline(context.Context, string, time.Time) (Money, int, error) {
+	return 0, 0, errHistoryDown
+}
+
+func TestStoreErrorDegradesDecision(t *testing.T) {
+	api, tn := newTestAPI(t)
+	tn.store = historyDownStore{NewMemoryStore(defaultHistorySize)}
+	setVar(t, &dailyLimit, dollars(100))
+	logs := captureLogs(t, false)
+
+	for _, tc := range []struct {
+		body, want string
+	}{
+		{`{"amount": 50, "merchant": "Corner Shop", "account_id": "acct-1"}`, "LOW"},
+		{`{"amount": 2500, "merchant": "Corner Shop", "account_id": "acct-1"}`, "MEDIUM"},
+		{`{"amount": 600, "merchant": "Starbucks", "account_id": "acct-1"}`, "HIGH"},
+	} {
+		rec := do(api, "POST", "/risk", tc.body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tc.body, rec.Code, rec.Body)
+		}
+		res := decode[ScoreResult](t, rec)
+		if res.RiskLevel != tc.want || !res.Degraded || res.Reason == dailyLimitReason {
+			t.Errorf("%s: got %s (%s), degraded %v; want a degraded %s", tc.body, res.RiskLevel, res.Reason, res.Degraded, tc.want)
+		}
+	}
+	if !strings.Contains(logs.String(), "skipping daily limit") || !strings.Contains(logs.String(), "skipping account anomaly rule") {
+		t.Fatalf("no degradation warnings logged:\n%s", logs)
+	}
+}
+
+func TestStoreErrorFailsWhenNotDegrading(t *testing.T) {
+	api, tn := newTestAPI(t)
+	tn.store = historyDownStore{NewMemoryStore(defaultHistorySize)}
+	setVar(t, &degradeOnStoreError, false)
+	if rec := do(api, "POST", "/risk", `{"amount": 50, "merchant": "m", "account_id": "acct-1"}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", rec.Code)
+	}
+}
+
+func TestHealthyStoreNotDegraded(t *testing.T) {
+	api, _ := newTestAPI(t)
+	var body map[string]any
+	if err := json.Unmarshal(do(api, "POST", "/risk", `{"amount": 50, "merchant": "m", "account_id": "acct-1"}`).Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["degraded"]; ok {
+		t.Fatalf("healthy decision marked degraded: %v", body)
+	}
+}
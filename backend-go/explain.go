@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ExplainResult is the response body of POST /explain.
+type ExplainResult struct {
+	// Rules lists every rule of the tenant's set in evaluation order. The
+	// matching ones decided under the combining policy, unless a watchlist
+	// hit, merchant list or credit handling took precedence.
+	Rules []RuleTrace `json:"rules"`
+	// RuleMatched reports whether any rule matched.
+	RuleMatched bool `json:"rule_matched"`
+	// Decision is the final result, as POST /risk?dry_run=true would return
+	// it.
+	Decision ScoreResult `json:"decision"`
+}
+
+// explain serves POST /explain: the transaction is scored like a dry run, and
+// the response shows how each rule judged it.
+func (s *Server) explain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var t Transaction
+	if !decodeValidated(w, r, transactionSchema, &t) {
+		return
+	}
+	if err := validateTransaction(t); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if t.Timestamp.IsZero() {
+		t.Timestamp = now().UTC()
+	}
+
+	res, err := explainWith(r.Context(), s.tenant(r.Context()), t)
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// explainWith scores t for tn without recording it and traces tn's rules.
+func explainWith(ctx context.Context, tn *Tenant, t Transaction) (ExplainResult, error) {
+	trace, _, matched := tn.rules.Get().Trace(t, tn.config.Get().CombinePolicy)
+	res, err := tn.score(ctx, t)
+	if err != nil {
+		return ExplainResult{}, err
+	}
+	res.DryRun = true
+	return ExplainResult{Rules: trace, RuleMatched: matched, Decision: res}, nil
+}
+
+// whatifRequest is the body of POST /risk/whatif.
+type whatifRequest struct {
+	Transaction Transaction     `json:"transaction"`
+	Rules       json.RawMessage `json:"rules"`
+}
+
+var whatifSchema = &Schema{
+	Type: []string{"object"},
+	Properties: map[string]*Schema{
+		"transaction": transactionSchema,
+		"rules":       {Type: []string{"array"}},
+	},
+	Required:             []string{"transaction", "rules"},
+	AdditionalProperties: &noAdditional,
+}
+
+// whatif serves POST /risk/whatif: like /explain, but the transaction is
+// judged by the rule set in the request instead of the tenant's. The
+// tenant's history, thresholds and live rules are used as they are and left
+// untouched.
+func (s *Server) whatif(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req whatifRequest
+	if !decodeValidated(w, r, whatifSchema, &req) {
+		return
+	}
+	t := req.Transaction
+	if err := validateTransaction(t); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	rs, err := parseRules(req.Rules)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if t.Timestamp.IsZero() {
+		t.Timestamp = now().UTC()
+	}
+
+	hypothetical := *s.tenant(r.Context())
+	hypothetical.rules = &ActiveRules{}
+	hypothetical.rules.Set(rs)
+	res, err := explainWith(r.Context(), &hypothetical, t)
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestExplainTracesEveryRule(t *testing.T) {
+	api, tn := newTestAPI(t)
+	rs, err := parseRules([]byte(`[
+		{"merchant": "Starbucks", "operator": ">", "amount": 500, "risk_level": "HIGH", "priority": 1},
+		{"merchant": "Apple Store", "operator": "<", "amount": 5000, "risk_level": "LOW", "priority": 2},
+		{"mcc": "7995", "operator": ">", "amount": 0, "risk_level": "MEDIUM", "priority": 3, "reason": "gambling"},
+		{"operator": ">", "amount": 100000, "risk_level": "HIGH", "priority": 4}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tn.rules.Set(rs)
+
+	rec := do(api, "POST", "/explain", `{"amount": 50, "merchant": "Lucky Casino", "mcc": "7995"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	res := decode[ExplainResult](t, rec)
+	if len(res.Rules) != 4 {
+		t.Fatalf("trace has %d rules, want 4", len(res.Rules))
+	}
+	for i, want := range []bool{false, false, true, false} {
+		tr := res.Rules[i]
+		if tr.Matched != want || tr.Reason == "" {
+			t.Errorf("rule %d: matched %v (%q), want %v with a reason", i+1, tr.Matched, tr.Reason, want)
+		}
+		if tr.Rule.Priority != i+1 {
+			t.Errorf("rule %d out of order: priority %d", i+1, tr.Rule.Priority)
+		}
+	}
+	if !res.RuleMatched || res.Decision.RiskLevel != "MEDIUM" || res.Decision.Reason != "gambling" || !res.Decision.DryRun {
+		t.Fatalf("decision %+v, rule_matched %v", res.Decision, res.RuleMatched)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("explain recorded %d transactions", n)
+	}
+}
+
+func TestExplainNoMatch(t *testing.T) {
+	api, _ := newTestAPI(t)
+	res := decode[ExplainResult](t, do(api, "POST", "/explain", `{"amount": 50, "merchant": "Corner Shop"}`))
+	if res.RuleMatched || len(res.Rules) != 2 || res.Decision.Reason != noRulesReason {
+		t.Fatalf("got %+v", res)
+	}
+	for i, tr := range res.Rules {
+		if tr.Matched {
+			t.Errorf("rule %d matched", i+1)
+		}
+	}
+}
+
+func TestWhatIfChangesOutcome(t *testing.T) {
+	api, tn := newTestAPI(t)
+	tx := `{"amount": 300, "merchant": "Starbucks"}`
+	live := decode[ExplainResult](t, do(api, "POST", "/explain", tx))
+	if live.Decision.RiskLevel != "LOW" {
+		t.Fatalf("live rules: got %s (%s)", live.Decision.RiskLevel, live.Decision.Reason)
+	}
+
+	rec := do(api, "POST", "/risk/whatif", `{"transaction": `+tx+`, "rules": [
+		{"merchant": "Starbucks", "operator": ">", "amount": 250, "risk_level": "HIGH", "reason": "proposed coffee limit"}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	res := decode[ExplainResult](t, rec)
+	if res.Decision.RiskLevel != "HIGH" || res.Decision.Reason != "proposed coffee limit" {
+		t.Fatalf("what-if: got %s (%s)", res.Decision.RiskLevel, res.Decision.Reason)
+	}
+	if len(res.Rules) != 1 || !res.Rules[0].Matched {
+		t.Fatalf("what-if trace %+v", res.Rules)
+	}
+
+	if n := len(tn.rules.Get()); n != 2 {
+		t.Fatalf("live rule set changed to %d rules", n)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("what-if recorded %d transactions", n)
+	}
+	if after := decode[ExplainResult](t, do(api, "POST", "/explain", tx)); after.Decision.RiskLevel != "LOW" {
+		t.Fatalf("live rules after what-if: got %s", after.Decision.RiskLevel)
+	}
+}
+
+func TestWhatIfInvalidRules(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, rules := range []string{
+		`[{"operator": "~", "amount": 1, "risk_level": "LOW"}]`,
+		`[{"operator": ">", "amount": 1, "risk_level": "SEVERE"}]`,
+	} {
+		rec := do(api, "POST", "/risk/whatif", `{"transaction": {"amount": 1, "merchant": "m"}, "rules": `+rules+`}`)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422", rules, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A rule's When expression is a boolean condition over transaction fields,
+// for example:
+//
+//	merchant == "Starbucks" && amount > 500 || amount > 10000
+//
+// Comparisons are field op literal. amount takes a number and supports
+// ==, !=, <, <=, > and >=, comparing in USD; merchant, currency and mcc take a
+// quoted string and support == and !=, comparing the same way the fixed rule
+// fields do. && binds tighter than ||, and parentheses group.
+
+// cond is a compiled When expression.
+type cond interface {
+	eval(t Transaction) bool
+}
+
+type orCond struct{ l, r cond }
+
+func (c orCond) eval(t Transaction) bool { return c.l.eval(t) || c.r.eval(t) }
+
+type andCond struct{ l, r cond }
+
+func (c andCond) eval(t Transaction) bool { return c.l.eval(t) && c.r.eval(t) }
+
+// amountCond compares the transaction's USD amount against a literal.
+type amountCond struct {
+	cmp   func(a, b Money) bool
+	value Money
+}
+
+func (c amountCond) eval(t Transaction) bool { return c.cmp(inUSD(t).Amount, c.value) }
+
+// stringCond compares a normalized string field against a literal normalized
+// the same way.
+type stringCond struct {
+	field func(Transaction) string
+	value string
+	equal bool
+}
+
+func (c stringCond) eval(t Transaction) bool { return (c.field(t) == c.value) == c.equal }
+
+// stringField describes a string-valued field usable in expressions.
+type stringField struct {
+	get       func(Transaction) string
+	normalize func(string) string
+}
+
+var stringFields = map[string]stringField{
+	"merchant": {
+		get:       func(t Transaction) string { return normalizeMerchant(t.Merchant) },
+		normalize: normalizeMerchant,
+	},
+	"currency": {
+		get:       currencyOf,
+		normalize: func(s string) string { return strings.ToUpper(strings.TrimSpace(s)) },
+	},
+	"mcc": {
+		get:       func(t Transaction) string { return strings.TrimSpace(t.MCC) },
+		normalize: strings.TrimSpace,
+	},
+}
+
+// parseCond compiles a When expression.
+func parseCond(src string) (cond, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{toks: toks}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", tok, tok.pos)
+	}
+	return c, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// twoCharOps must be tried before the single-character comparisons.
+var twoCharOps = []string{"&&", "||", "==", "!=", ">=", "<="}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			toks = append(toks, token{tokString, s, i})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '.' || c == '-':
+			j := i + 1
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokNumber, src[i:j], i})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range twoCharOps {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" && (c == '<' || c == '>') {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			toks = append(toks, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
+
+type condParser struct {
+	toks []token
+	i    int
+}
+
+func (p *condParser) peek() token { return p.toks[p.i] }
+
+func (p *condParser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *condParser) parseOr() (cond, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orCond{l, r}
+	}
+	return l, nil
+}
+
+func (p *condParser) parseAnd() (cond, error) {
+	l, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		r, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		l = andCond{l, r}
+	}
+	return l, nil
+}
+
+func (p *condParser) parsePrimary() (cond, error) {
+	tok := p.next()
+	if tok.kind == tokLParen {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, fmt.Errorf("expected \")\" at offset %d, got %s", end.pos, end)
+		}
+		return c, nil
+	}
+	if tok.kind != tokIdent {
+		return nil, fmt.Errorf("expected a field name at offset %d, got %s", tok.pos, tok)
+	}
+	op := p.next()
+	if op.kind != tokOp || op.text == "&&" || op.text == "||" {
+		return nil, fmt.Errorf("expected a comparison after %s at offset %d, got %s", tok, op.pos, op)
+	}
+	lit := p.next()
+
+	if tok.text == "amount" {
+		if lit.kind != tokNumber {
+			return nil, fmt.Errorf("amount must be compared with a number at offset %d, got %s", lit.pos, lit)
+		}
+		v, err := parseMoney(lit.text)
+		if err != nil {
+			return nil, fmt.Errorf("offset %d: %w", lit.pos, err)
+		}
+		cmp := operators[op.text]
+		if op.text == "!=" {
+			cmp = func(a, b Money) bool { return a != b }
+		}
+		return amountCond{cmp: cmp, value: v}, nil
+	}
+	f, ok := stringFields[tok.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s at offset %d", tok, tok.pos)
+	}
+	if op.text != "==" && op.text != "!=" {
+		return nil, fmt.Errorf("%s supports only == and != (offset %d)", tok.text, op.pos)
+	}
+	if lit.kind != tokString {
+		return nil, fmt.Errorf("%s must be compared with a quoted string at offset %d, got %s", tok.text, lit.pos, lit)
+	}
+	return stringCond{field: f.get, value: f.normalize(lit.text), equal: op.text == "=="}, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCondCompound(t *testing.T) {
+	c, err := parseCond(`merchant == "Starbucks" && amount > 500 || amount > 10000`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		tx   Transaction
+		want bool
+	}{
+		{Transaction{Merchant: "Starbucks", Amount: dollars(501)}, true},
+		{Transaction{Merchant: "starbucks ", Amount: dollars(501)}, true},
+		{Transaction{Merchant: "Starbucks", Amount: dollars(500)}, false},
+		{Transaction{Merchant: "Corner Shop", Amount: dollars(600)}, false},
+		{Transaction{Merchant: "Corner Shop", Amount: dollars(10001)}, true},
+	} {
+		if got := c.eval(tc.tx); got != tc.want {
+			t.Errorf("%s $%s: got %v, want %v", tc.tx.Merchant, tc.tx.Amount, got, tc.want)
+		}
+	}
+}
+
+func TestCondParentheses(t *testing.T) {
+	c, err := parseCond(`(currency == "EUR" || currency == "GBP") && mcc != "5732" && amount >= 100`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.eval(Transaction{Currency: "GBP", MCC: "5812", Amount: dollars(1000)}) {
+		t.Error("GBP restaurant charge should match")
+	}
+	if c.eval(Transaction{Currency: "EUR", MCC: "5732", Amount: dollars(1000)}) {
+		t.Error("electronics excluded by mcc != 5732")
+	}
+	if c.eval(Transaction{Currency: "USD", Amount: dollars(1000)}) {
+		t.Error("USD should not match")
+	}
+}
+
+func TestCondMalformed(t *testing.T) {
+	for _, src := range []string{
+		`amount >`,
+		`amount > "500"`,
+		`merchant > "x"`,
+		`country == "US"`,
+		`(amount > 1`,
+		`amount > 1 &&`,
+		`merchant == "unterminated`,
+	} {
+		if _, err := parseCond(src); err == nil {
+			t.Errorf("%q: parsed without error", src)
+		}
+	}
+	_, err := parseRules([]byte(`[{"when": "amount >> 5", "risk_level": "HIGH"}]`))
+	if err == nil || !strings.Contains(err.Error(), "rule 0: when") {
+		t.Fatalf("malformed rule at load time: %v", err)
+	}
+}
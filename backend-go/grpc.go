@@ -0,0 +1,185 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=module=github.com/AshleyKlibowitz/financial-compliance-risk-analysis-pipeline/backend-go --go-grpc_out=. --go-grpc_opt=module=github.com/AshleyKlibowitz/financial-compliance-risk-analysis-pipeline/backend-go proto/risk.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	riskv1 "github.com/AshleyKlibowitz/financial-compliance-risk-analysis-pipeline/backend-go/proto/riskv1"
+)
+
+// riskService implements RiskService on top of Tenant.process, so gRPC and
+// HTTP callers share evaluation, storage, metrics and notifications.
+type riskService struct {
+	riskv1.UnimplementedRiskServiceServer
+	api *Server
+}
+
+// newGRPCServer returns a gRPC server exposing RiskService for api. Callers
+// authenticate with x-api-key metadata and may name their tenant with
+// x-tenant-id, as the HTTP API does with headers.
+func newGRPCServer(api *Server, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(api.grpcUnaryAuth), grpc.StreamInterceptor(api.grpcStreamAuth))
+	srv := grpc.NewServer(opts...)
+	riskv1.RegisterRiskServiceServer(srv, &riskService{api: api})
+	return srv
+}
+
+// grpcTenant authenticates the call's metadata and returns ctx carrying the
+// resolved tenant.
+func (s *Server) grpcTenant(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	key := first("x-api-key")
+	if key == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing API key")
+	}
+	if !validAPIKey(key) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	t, err := s.resolveTenant(key, first("x-tenant-id"))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return context.WithValue(ctx, tenantKey, t), nil
+}
+
+func (s *Server) grpcUnaryAuth(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.grpcTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+func (s *Server) grpcStreamAuth(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.grpcTenant(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+}
+
+// tenantStream overrides a stream's context with one carrying the tenant.
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context { return s.ctx }
+
+// transactionFromProto converts and validates a ScoreRequest.
+func transactionFromProto(req *riskv1.ScoreRequest) (Transaction, error) {
+	amount, err := parseMoney(req.GetAmount())
+	if err != nil {
+		return Transaction{}, &ValidationError{Field: "amount", Message: err.Error()}
+	}
+	t := Transaction{
+		ID:           req.GetId(),
+		Amount:       amount,
+		Merchant:     req.GetMerchant(),
+		Currency:     req.GetCurrency(),
+		MCC:          req.GetMcc(),
+		Counterparty: req.GetCounterparty(),
+		AccountID:    req.GetAccountId(),
+		Country:      req.GetCountry(),
+		Type:         req.GetType(),
+	}
+	if err := validateTransaction(t); err != nil {
+		return Transaction{}, err
+	}
+	return t, nil
+}
+
+// score processes one request for the tenant in ctx.
+func (rs *riskService) score(ctx context.Context, req *riskv1.ScoreRequest) (*riskv1.ScoreResponse, error) {
+	t, err := transactionFromProto(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	res, err := rs.api.tenant(ctx).process(ctx, t)
+	if err != nil {
+		return nil, grpcProcessError(ctx, err)
+	}
+	return &riskv1.ScoreResponse{
+		RiskLevel:     res.RiskLevel,
+		Reason:        res.Reason,
+		RiskScore:     int32(res.RiskScore),
+		TransactionId: res.TransactionID,
+	}, nil
+}
+
+// grpcProcessError maps a process failure to a status the way
+// writeProcessError maps it to an HTTP response.
+func grpcProcessError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, errDuplicateID):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return status.FromContextError(err).Err()
+	}
+	logFor(ctx).Error("store operation failed", "error", err)
+	return status.Error(codes.Unavailable, "storage unavailable")
+}
+
+// Score evaluates a single transaction, like POST /v1/risk.
+func (rs *riskService) Score(ctx context.Context, req *riskv1.ScoreRequest) (*riskv1.ScoreResponse, error) {
+	return rs.score(ctx, req)
+}
+
+// ScoreBatch answers each streamed transaction in order. An invalid
+// transaction ends the stream with InvalidArgument naming its position;
+// those before it have already been recorded.
+func (rs *riskService) ScoreBatch(stream grpc.BidiStreamingServer[riskv1.ScoreRequest, riskv1.ScoreResponse]) error {
+	ctx := stream.Context()
+	for i := 0; ; i++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res, err := rs.score(ctx, req)
+		if err != nil {
+			if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+				return status.Error(codes.InvalidArgument, fmt.Sprintf("transaction %d: %s", i, st.Message()))
+			}
+			return err
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// stopGRPC lets in-flight calls finish for up to shutdownTimeout, then
+// closes whatever is left.
+func stopGRPC(srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		srv.Stop()
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	riskv1 "github.com/AshleyKlibowitz/financial-compliance-risk-analysis-pipeline/backend-go/proto/riskv1"
+)
+
+// newTestGRPC serves RiskService for tn over an in-memory listener and
+// returns a connected client.
+func newTestGRPC(t *testing.T, tn *Tenant) riskv1.RiskServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(NewServer(NewTenants(tn)))
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return riskv1.NewRiskServiceClient(conn)
+}
+
+func withKey(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "x-api-key", testKey)
+}
+
+var grpcCases = []struct {
+	amount, merchant, json string
+}{
+	{"50", "Corner Shop", `{"amount": 50, "merchant": "Corner Shop"}`},
+	{"2500", "Corner Shop", `{"amount": 2500, "merchant": "Corner Shop"}`},
+	{"600", "Starbucks", `{"amount": 600, "merchant": "Starbucks"}`},
+	{"20000.50", "Corner Shop", `{"amount": "20000.50", "merchant": "Corner Shop"}`},
+}
+
+func TestGRPCScoreMatchesHTTP(t *testing.T) {
+	api, _ := newTestAPI(t)
+	client := newTestGRPC(t, newTestTenant(t, defaultTenant))
+	for _, tc := range grpcCases {
+		want := decode[ScoreResult](t, do(api, "POST", "/v1/risk", tc.json))
+		got, err := client.Score(withKey(t.Context()), &riskv1.ScoreRequest{Amount: tc.amount, Merchant: tc.merchant})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.RiskLevel != want.RiskLevel || got.Reason != want.Reason || int(got.RiskScore) != want.RiskScore {
+			t.Errorf("$%s at %s: gRPC %s/%q/%d, HTTP %s/%q/%d", tc.amount, tc.merchant,
+				got.RiskLevel, got.Reason, got.RiskScore, want.RiskLevel, want.Reason, want.RiskScore)
+		}
+	}
+}
+
+func TestGRPCScoreBatchStream(t *testing.T) {
+	setClock(t, noon)
+	setVar(t, &apiKeys, []apiKey{{key: []byte(testKey)}})
+	tn := newTestTenant(t, defaultTenant)
+	client := newTestGRPC(t, tn)
+	stream, err := client.ScoreBatch(withKey(t.Context()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range grpcCases {
+		if err := stream.Send(&riskv1.ScoreRequest{Amount: tc.amount, Merchant: tc.merchant}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream.CloseSend()
+	want := []string{"LOW", "MEDIUM", "HIGH", "HIGH"}
+	for i := 0; ; i++ {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Fatalf("got %d responses, want %d", i, len(want))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.RiskLevel != want[i] {
+			t.Errorf("response %d: got %s, want %s", i, res.RiskLevel, want[i])
+		}
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != len(want) {
+		t.Fatalf("%d transactions recorded, want %d", n, len(want))
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	setClock(t, noon)
+	setVar(t, &apiKeys, []apiKey{{key: []byte(testKey)}})
+	client := newTestGRPC(t, newTestTenant(t, defaultTenant))
+	req := &riskv1.ScoreRequest{Amount: "10", Merchant: "m"}
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		req  *riskv1.ScoreRequest
+		code codes.Code
+	}{
+		{"missing key", t.Context(), req, codes.Unauthenticated},
+		{"wrong key", metadata.AppendToOutgoingContext(t.Context(), "x-api-key", "nope"), req, codes.Unauthenticated},
+		{"unknown tenant", metadata.AppendToOutgoingContext(withKey(t.Context()), "x-tenant-id", "acme"), req, codes.Unauthenticated},
+		{"bad amount", withKey(t.Context()), &riskv1.ScoreRequest{Amount: "ten", Merchant: "m"}, codes.InvalidArgument},
+		{"unknown currency", withKey(t.Context()), &riskv1.ScoreRequest{Amount: "10", Merchant: "m", Currency: "XYZ"}, codes.InvalidArgument},
+	} {
+		_, err := client.Score(tc.ctx, tc.req)
+		if got := status.Code(err); got != tc.code {
+			t.Errorf("%s: code %s, want %s (%v)", tc.name, got, tc.code, err)
+		}
+	}
+
+	stream, err := client.ScoreBatch(withKey(t.Context()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(req)
+	stream.Send(&riskv1.ScoreRequest{Amount: "-5", Merchant: "m"})
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("first response: %v", err)
+	}
+	_, err = stream.Recv()
+	if st := status.Convert(err); st.Code() != codes.InvalidArgument || !strings.HasPrefix(st.Message(), "transaction 1:") {
+		t.Fatalf("invalid stream item: %v", err)
+	}
+}
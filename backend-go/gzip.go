@@ -0,0 +1,79 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize is the smallest response body, in bytes, worth compressing.
+var gzipMinSize = 1024
+
+// gzipMiddleware compresses responses for clients that accept gzip. Bodies
+// are buffered until they reach gzipMinSize; smaller ones go out as-is.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(enc), "gzip") && strings.TrimSpace(params) != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the status and body until it knows whether
+// the body is large enough to compress.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status int
+	buf    []byte
+	gz     *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < gzipMinSize {
+		return len(p), nil
+	}
+
+	h := w.ResponseWriter.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	if _, err := w.gz.Write(w.buf); err != nil {
+		return 0, err
+	}
+	w.buf = nil
+	return len(p), nil
+}
+
+// close flushes the compressed stream, or writes the small body uncompressed.
+func (w *gzipResponseWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.buf)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBatchGzipLargeBody(t *testing.T) {
+	api, _ := newTestAPI(t)
+	items := make([]string, 50)
+	for i := range items {
+		items[i] = `{"amount": 10, "merchant": "Corner Shop"}`
+	}
+	rec := do(api, "POST", "/risk/batch", "["+strings.Join(items, ",")+"]", "Accept-Encoding", "gzip")
+	if enc := rec.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Content-Encoding %q, want gzip", enc)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []BatchResult
+	if err := json.NewDecoder(zr).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("got %d results, want %d", len(results), len(items))
+	}
+}
+
+func TestBatchGzipSmallBodyUncompressed(t *testing.T) {
+	api, _ := newTestAPI(t)
+	rec := do(api, "POST", "/risk/batch", `[{"amount": 10, "merchant": "m"}]`, "Accept-Encoding", "gzip")
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("small body sent with Content-Encoding %q", enc)
+	}
+	if got := decode[[]BatchResult](t, rec); len(got) != 1 {
+		t.Fatalf("got %d results", len(got))
+	}
+}
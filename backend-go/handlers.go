@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBodyBytes caps the size of request bodies accepted by the JSON handlers.
+var maxBodyBytes int64 = 1 << 20
+
+// bodyLimit returns the request body cap for the route at path: CSV uploads
+// get maxUploadBytes, job submissions maxJobBytes and everything else
+// maxBodyBytes. Middleware that reads the body before the handler must use it
+// so it never rejects a body the route would accept.
+func bodyLimit(path string) int64 {
+	switch strings.TrimPrefix(path, apiVersion) {
+	case "/risk/upload":
+		return maxUploadBytes
+	case "/jobs":
+		return maxJobBytes
+	}
+	return maxBodyBytes
+}
+
+// decodeBody decodes the JSON request body into v, reading at most
+// bodyLimit(r.URL.Path) and rejecting fields v doesn't have with 422. On
+// failure it writes the error response and returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, bodyLimit(r.URL.Path))
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if r.Context().Err() != nil {
+			writeError(w, http.StatusServiceUnavailable, "request timed out")
+			return false
+		}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return false
+		}
+		if errors.Is(err, errInvalidMoney) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return false
+		}
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			writeError(w, http.StatusUnprocessableEntity, "unknown field "+field)
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return false
+	}
+	return true
+}
+
+// score evaluates and scores a validated transaction without recording it.
+func (tn *Tenant) score(ctx context.Context, t Transaction) (ScoreResult, error) {
+	if t.ID == "" {
+		t.ID = newUUID()
+	}
+	d, err := tn.evaluate(ctx, t)
+	if err != nil {
+		return ScoreResult{}, err
+	}
+	res := scoreTransaction(t, d)
+	res.TransactionID = t.ID
+	res.Decision = res.Decision.stamped(now())
+	return res, nil
+}
+
+// process scores a validated transaction, records the decision, publishes it
+// to Kafka and notifies the webhook of HIGH-risk results. With -unique-ids, a
+// client-supplied ID seen within dedupWindow fails with errDuplicateID; the
+// ID is released again if the transaction isn't recorded. If ctx is done
+// before the decision is recorded, as when the request timed out and was
+// answered with 503, nothing is recorded and ctx's error is returned, so a
+// client retrying the request doesn't create a duplicate decision. Once the
+// decision is recorded it is returned, but publishing and notifying are
+// skipped if ctx is done by then.
+func (tn *Tenant) process(ctx context.Context, t Transaction) (ScoreResult, error) {
+	claimed := ""
+	if t.ID == "" {
+		t.ID = newUUID()
+	} else if uniqueIDs {
+		claimed = tn.ID + "\x00" + t.ID
+		if !recentIDs.claim(claimed, now(), dedupWindow) {
+			return ScoreResult{}, fmt.Errorf("%w: %q", errDuplicateID, t.ID)
+		}
+	}
+	if t.Timestamp.IsZero() {
+		t.Timestamp = now().UTC()
+	}
+	ctx, undo := tn.reserveVelocity(ctx, t)
+	// fail rolls back the velocity reservation and the ID claim of a
+	// transaction that was not recorded, so the client can retry it.
+	fail := func(err error) (ScoreResult, error) {
+		undo()
+		if claimed != "" {
+			recentIDs.unclaim(claimed)
+		}
+		return ScoreResult{}, err
+	}
+	res, err := tn.score(ctx, t)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fail(err)
+	}
+	ts := now().UTC()
+	rec := Record{Transaction: t, RiskLevel: res.RiskLevel, Reason: res.Reason, Timestamp: ts}
+	if rec.RiskLevel == "HIGH" {
+		rec.Status = StatusPending
+	}
+	if err := tn.store.Append(ctx, rec); err != nil {
+		return fail(err)
+	}
+	tn.recordMerchantAmount(t)
+	recordDecision(res.RiskLevel)
+	logDecision(ctx, t, res)
+	if ctx.Err() != nil {
+		return res, nil
+	}
+	if publisher != nil {
+		publisher.publish(decisionMessage{Tenant: tn.ID, Transaction: t, RiskLevel: res.RiskLevel, Reason: res.Reason, RiskScore: res.RiskScore, Timestamp: ts})
+	}
+	if webhook != nil && res.RiskLevel == "HIGH" {
+		webhook.notify(webhookEvent{Transaction: t, RiskLevel: res.RiskLevel, Reason: res.Reason, Timestamp: ts})
+	}
+	return res, nil
+}
+
+// logSampleRate is the fraction of recorded decisions logged in full at info
+// level. HIGH decisions are always logged; the rest of the sample logs at
+// debug.
+var logSampleRate = 0.0
+
+// parseSampleRate validates a -log-sample-rate value.
+func parseSampleRate(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(v >= 0 && v <= 1) {
+		return 0, fmt.Errorf("invalid sample rate %q: want a number from 0 to 1", s)
+	}
+	return v, nil
+}
+
+// logDecision logs a recorded decision with the transaction redacted, at info
+// level for HIGH decisions and a logSampleRate sample of the others.
+func logDecision(ctx context.Context, t Transaction, res ScoreResult) {
+	level := slog.LevelDebug
+	if res.RiskLevel == "HIGH" || rand.Float64() < logSampleRate {
+		level = slog.LevelInfo
+	}
+	logFor(ctx).Log(ctx, level, "scored transaction", "transaction", t, "risk_level", res.RiskLevel, "reason", res.Reason, "risk_score", res.RiskScore)
+}
+
+// processError describes a failed process call: 409 for a reused
+// transaction ID, 503 for a request that timed out and, after logging the
+// storage failure, a generic 500 otherwise.
+func processError(r *http.Request, err error) ErrorResponse {
+	switch {
+	case errors.Is(err, errDuplicateID):
+		return ErrorResponse{Error: err.Error(), Status: http.StatusConflict}
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		return ErrorResponse{Error: "request timed out", Status: http.StatusServiceUnavailable}
+	}
+	logFor(r.Context()).Error("store operation failed", "error", err)
+	return ErrorResponse{Error: "storage unavailable", Status: http.StatusInternalServerError}
+}
+
+// writeProcessError answers a failed process call with processError.
+func writeProcessError(w http.ResponseWriter, r *http.Request, err error) {
+	writeErrorResponse(w, processError(r, err))
+}
+
+// writeStoreError logs a storage failure and answers with a generic 500.
+func writeStoreError(w http.ResponseWriter, r *http.Request, err error) {
+	logFor(r.Context()).Error("store operation failed", "error", err)
+	writeError(w, http.StatusInternalServerError, "storage unavailable")
+}
+
+func (s *Server) checkRisk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	contentType, ok := negotiate(r.Header.Get("Accept"))
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, "supported response types are application/json and application/xml")
+		return
+	}
+
+	var t Transaction
+	if !decodeValidated(w, r, transactionSchema, &t) {
+		return
+	}
+	if err := validateTransaction(t); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	// A dry run is scored against the current history but leaves no trace:
+	// nothing is stored, counted, cached or sent to the webhook.
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+		if dryRun {
+			if t.Timestamp.IsZero() {
+				t.Timestamp = now().UTC()
+			}
+			res, err := s.tenant(r.Context()).score(r.Context(), t)
+			if err != nil {
+				writeStoreError(w, r, err)
+				return
+			}
+			res.DryRun = true
+			writeNegotiated(w, contentType, res)
+			return
+		}
+	}
+
+	tn := s.tenant(r.Context())
+	idemKey := idempotencyKey(r.Header.Get("Idempotency-Key"), tn.ID, r.Header.Get("X-API-Key"))
+	if idemKey != "" {
+		res, state := idempotency.reserve(idemKey, requestHash(t), now(), idempotencyTTL)
+		switch state {
+		case idemReplay:
+			w.Header().Set("X-Idempotent-Replay", "true")
+			writeNegotiated(w, contentType, res)
+			return
+		case idemInFlight:
+			writeError(w, http.StatusConflict, "a request with this Idempotency-Key is still being processed")
+			return
+		case idemMismatch:
+			writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request body")
+			return
+		}
+	}
+	var dupKey string
+	if idemKey == "" && dedupEnabled {
+		dupKey = payloadHash(tn.ID, t)
+		if res, ok := recentPayloads.get(dupKey, now()); ok {
+			w.Header().Set("X-Duplicate", "true")
+			writeNegotiated(w, contentType, res)
+			return
+		}
+	}
+
+	res, err := tn.process(r.Context(), t)
+	if err != nil {
+		if idemKey != "" {
+			idempotency.release(idemKey)
+		}
+		writeProcessError(w, r, err)
+		return
+	}
+	if idemKey != "" {
+		idempotency.finish(idemKey, res, now(), idempotencyTTL)
+	}
+	if dupKey != "" {
+		recentPayloads.put(dupKey, res, now(), dedupWindow)
+	}
+	writeNegotiated(w, contentType, res)
+}
+
+// BatchResult is one entry of a /risk/batch response. Error is set, and the
+// decision left out, for a transaction that could not be recorded.
+type BatchResult struct {
+	Merchant string `json:"merchant"`
+	ScoreResult
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes a failed entry as just its merchant and error.
+func (b BatchResult) MarshalJSON() ([]byte, error) {
+	if b.Error != nil {
+		return json.Marshal(struct {
+			Merchant string         `json:"merchant"`
+			Error    *ErrorResponse `json:"error"`
+		}{b.Merchant, b.Error})
+	}
+	type plain BatchResult
+	return json.Marshal(plain(b))
+}
+
+// checkBatch scores an array of transactions and returns the results in the
+// same order. The whole batch is validated before anything is recorded, so an
+// invalid transaction rejects it with 422. After that each transaction is
+// recorded on its own: if any fails, the response is 207 Multi-Status and
+// the failed entries carry an error in place of a decision. Every entry
+// without one was recorded, so a client retries only the failed ones.
+func (s *Server) checkBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var batch []Transaction
+	if !decodeValidated(w, r, batchSchema, &batch) {
+		return
+	}
+	if len(batch) > maxBatchSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch of %d transactions exceeds the maximum of %d", len(batch), maxBatchSize))
+		return
+	}
+
+	for i, t := range batch {
+		if err := validateTransaction(t); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("transaction %d: %s", i, err))
+			return
+		}
+	}
+
+	tn := s.tenant(r.Context())
+	results := make([]BatchResult, len(batch))
+	status := http.StatusOK
+	for i, t := range batch {
+		res, err := tn.process(r.Context(), t)
+		if err != nil {
+			e := processError(r, err)
+			results[i] = BatchResult{Merchant: t.Merchant, Error: &e}
+			status = http.StatusMultiStatus
+			continue
+		}
+		results[i] = BatchResult{Merchant: t.Merchant, ScoreResult: res}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
+
+// defaultHistoryLimit is the number of records /transactions returns by
+// default, and maxHistoryLimit the most one page may ask for.
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// HistoryPage is the response body of GET /transactions.
+type HistoryPage struct {
+	Transactions []Record `json:"transactions"`
+	NextCursor   string   `json:"next_cursor,omitempty"`
+}
+
+// encodeCursor and decodeCursor convert a record's position to the opaque
+// cursor handed to clients.
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(c string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return id, nil
+}
+
+// listTransactions returns scored transactions, newest first, one page at a
+// time. ?from= and ?to= (RFC 3339) bound the transaction timestamp to
+// [from, to); ?status= keeps HIGH decisions in one review state; ?limit= sets
+// the page size (default defaultHistoryLimit, at most maxHistoryLimit); and
+// ?cursor= continues from the next_cursor of a previous page.
+func (s *Server) listTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	q := HistoryQuery{Limit: defaultHistoryLimit}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if n > maxHistoryLimit {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("limit must be at most %d", maxHistoryLimit))
+			return
+		}
+		q.Limit = n
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &q.From}, {"to", &q.To}} {
+		if v := query.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*p.dst = t
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.From.After(q.To) {
+		writeError(w, http.StatusUnprocessableEntity, "from must not be after to")
+		return
+	}
+	if v := query.Get("cursor"); v != "" {
+		id, err := decodeCursor(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		q.Before = id
+	}
+	if v := query.Get("status"); v != "" {
+		if !validStatuses[v] {
+			writeError(w, http.StatusBadRequest, "status must be pending, cleared or confirmed")
+			return
+		}
+		q.Status = v
+	}
+
+	// Ask for one extra record to learn whether another page follows.
+	limit := q.Limit
+	q.Limit++
+	recs, err := s.tenant(r.Context()).store.History(r.Context(), q)
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	page := HistoryPage{Transactions: recs}
+	if len(recs) > limit {
+		page.Transactions = recs[:limit]
+		page.NextCursor = encodeCursor(recs[limit-1].ID)
+	}
+	if page.Transactions == nil {
+		page.Transactions = []Record{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBatchEmpty(t *testing.T) {
+	api, _ := newTestAPI(t)
+	rec := do(api, "POST", "/risk/batch", `[]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := decode[[]BatchResult](t, rec); len(got) != 0 {
+		t.Fatalf("got %d results for an empty batch", len(got))
+	}
+}
+
+func TestBatchMixed(t *testing.T) {
+	api, _ := newTestAPI(t)
+	rec := do(api, "POST", "/risk/batch", `[
+		{"amount": 50, "merchant": "Corner Shop"},
+		{"amount": 2500, "merchant": "Corner Shop"},
+		{"amount": 600, "merchant": "Starbucks"}
+	]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	got := decode[[]BatchResult](t, rec)
+	want := []string{"LOW", "MEDIUM", "HIGH"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i, res := range got {
+		if res.RiskLevel != want[i] {
+			t.Errorf("result %d: got %s, want %s", i, res.RiskLevel, want[i])
+		}
+	}
+	if got[2].Merchant != "Starbucks" {
+		t.Errorf("results out of order: %+v", got)
+	}
+}
+
+func TestBatchInvalidItemRecordsNothing(t *testing.T) {
+	api, tn := newTestAPI(t)
+	rec := do(api, "POST", "/risk/batch", `[{"amount": 10, "merchant": "m"}, {"amount": -1, "merchant": "m"}]`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422: %s", rec.Code, rec.Body)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("%d transactions recorded from a rejected batch", n)
+	}
+}
+
+func TestBatchPartialFailure(t *testing.T) {
+	api, tn := newTestAPI(t)
+	store := &flakyStore{MemoryStore: NewMemoryStore(defaultHistorySize)}
+	store.failures.Store(1)
+	tn.store = store
+	rec := do(api, "POST", "/risk/batch", `[
+		{"amount": 50, "merchant": "Corner Shop"},
+		{"amount": 2500, "merchant": "Corner Shop"}
+	]`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status %d, want 207: %s", rec.Code, rec.Body)
+	}
+	items := decode[[]map[string]any](t, rec)
+	if len(items) != 2 {
+		t.Fatalf("got %d results, want 2", len(items))
+	}
+	if _, ok := items[0]["risk_level"]; ok || items[0]["error"] == nil {
+		t.Errorf("failed entry %v", items[0])
+	}
+	if items[1]["risk_level"] != "MEDIUM" || items[1]["error"] != nil {
+		t.Errorf("recorded entry %v", items[1])
+	}
+	if n := store.Len(); n != 1 {
+		t.Fatalf("%d transactions recorded, want 1", n)
+	}
+	if got := decode[[]BatchResult](t, rec); got[0].Error.Status != http.StatusInternalServerError {
+		t.Errorf("failed entry status %d, want 500", got[0].Error.Status)
+	}
+}
+
+func TestBatchOverLimit(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &maxBatchSize, 2)
+	items := make([]string, 3)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"amount": %d, "merchant": "m"}`, i+1)
+	}
+	rec := do(api, "POST", "/risk/batch", "["+strings.Join(items, ",")+"]")
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413", rec.Code)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("%d transactions recorded from a rejected batch", n)
+	}
+}
+
+func TestBodySizeLimit(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &maxBodyBytes, 64)
+	body := func(size int) string {
+		b := `{"amount": 1, "merchant": "m"`
+		return b + strings.Repeat(" ", size-len(b)-1) + "}"
+	}
+	if rec := do(api, "POST", "/risk", body(64)); rec.Code != http.StatusOK {
+		t.Fatalf("body at the limit: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(api, "POST", "/risk", body(65)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("body over the limit: status %d, want 413", rec.Code)
+	}
+}
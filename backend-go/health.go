@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ready is set once startup (rule loading, store setup) has completed and
+// cleared again when the server begins shutting down.
+var ready atomic.Bool
+
+// healthz reports that the process is up and serving HTTP.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Readiness check settings. Each dependency check gets readinessTimeout, and
+// a report is reused for readinessCacheTTL so frequent probes don't hammer
+// the dependencies.
+var (
+	readinessTimeout  = 2 * time.Second
+	readinessCacheTTL = 5 * time.Second
+)
+
+// pinger is a dependency /readyz can check, such as a database or a Kafka
+// producer that supports it.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ReadinessReport is the body of GET /readyz once startup has completed.
+// Dependencies maps each checked dependency to "ok" or "unavailable"; the
+// errors behind them are logged, not served, since /readyz is
+// unauthenticated.
+type ReadinessReport struct {
+	Status       string            `json:"status"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// readinessChecks runs the registered dependency checks for /readyz and
+// caches the result. It is safe for concurrent use.
+type readinessChecks struct {
+	mu      sync.Mutex
+	deps    map[string]pinger
+	report  ReadinessReport
+	healthy bool
+	expires time.Time
+}
+
+// dependencies is checked by readyz. main registers the stores and the Kafka
+// producer.
+var dependencies = &readinessChecks{deps: map[string]pinger{}}
+
+// register adds a dependency to check under name.
+func (c *readinessChecks) register(name string, p pinger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deps[name] = p
+	c.expires = time.Time{}
+}
+
+// check returns the cached report, or pings every dependency concurrently and
+// caches a fresh one. Concurrent callers wait for the same round of checks,
+// so the pings run on a context of their own rather than the first caller's,
+// whose cancellation would otherwise fail every dependency for all of them.
+func (c *readinessChecks) check() (ReadinessReport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now().Before(c.expires) {
+		return c.report, c.healthy
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readinessTimeout)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]string, len(c.deps))
+	)
+	healthy := true
+	for name, p := range c.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := "ok"
+			if err := p.Ping(ctx); err != nil {
+				logger.Warn("readiness check failed", "dependency", name, "error", err)
+				status = "unavailable"
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = status
+			if status != "ok" {
+				healthy = false
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.report = ReadinessReport{Status: "ready"}
+	if !healthy {
+		c.report.Status = "unavailable"
+	}
+	if len(results) > 0 {
+		c.report.Dependencies = results
+	}
+	c.healthy, c.expires = healthy, now().Add(readinessCacheTTL)
+	return c.report, healthy
+}
+
+// readyz reports whether the server is ready to take traffic: startup has
+// completed and every registered dependency answers. When one does not, it
+// answers 503 with each dependency's status.
+func readyz(w http.ResponseWriter, r *http.Request) {
+	if !ready.Load() {
+		writeError(w, http.StatusServiceUnavailable, "not ready")
+		return
+	}
+	report, healthy := dependencies.check()
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
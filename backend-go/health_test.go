@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadyzFlipsWhenReady(t *testing.T) {
+	t.Cleanup(func() { ready.Store(false) })
+	get := func() int {
+		rec := httptest.NewRecorder()
+		readyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+		return rec.Code
+	}
+	ready.Store(false)
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Fatalf("before ready: status %d, want 503", code)
+	}
+	ready.Store(true)
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("after ready: status %d, want 200", code)
+	}
+}
+
+func TestHealthzNeedsNoAPIKey(t *testing.T) {
+	api, _ := newTestAPI(t)
+	r := httptest.NewRequest("GET", "/healthz", nil)
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+}
+
+// fakeDependency is a pinger whose health the test switches.
+type fakeDependency struct {
+	err   atomic.Pointer[error]
+	pings atomic.Int32
+	delay time.Duration
+}
+
+func (d *fakeDependency) Ping(ctx context.Context) error {
+	d.pings.Add(1)
+	if d.delay > 0 {
+		select {
+		case <-time.After(d.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := d.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+func (d *fakeDependency) fail(err error) { d.err.Store(&err) }
+
+// readyzWith serves GET /readyz against deps, marked ready, with the clock at
+// noon.
+func readyzWith(t *testing.T, deps map[string]pinger) func(r *http.Request) (*httptest.ResponseRecorder, ReadinessReport) {
+	t.Helper()
+	setClock(t, noon)
+	setVar(t, &dependencies, &readinessChecks{deps: deps})
+	ready.Store(true)
+	t.Cleanup(func() { ready.Store(false) })
+	return func(r *http.Request) (*httptest.ResponseRecorder, ReadinessReport) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		readyz(rec, r)
+		return rec, decode[ReadinessReport](t, rec)
+	}
+}
+
+func TestReadyzDependencyHealth(t *testing.T) {
+	db, kafka := &fakeDependency{}, &fakeDependency{}
+	get := readyzWith(t, map[string]pinger{"db:default": db, "kafka": kafka})
+	logs := captureLogs(t, false)
+	req := func() *http.Request { return httptest.NewRequest("GET", "/readyz", nil) }
+
+	rec, report := get(req())
+	if rec.Code != http.StatusOK || report.Status != "ready" || report.Dependencies["db:default"] != "ok" || report.Dependencies["kafka"] != "ok" {
+		t.Fatalf("healthy: status %d, report %+v", rec.Code, report)
+	}
+
+	kafka.fail(errors.New("failed to dial: dial tcp 10.0.0.7:9092: connect: connection refused"))
+	// The healthy report is cached for readinessCacheTTL.
+	if rec, _ := get(req()); rec.Code != http.StatusOK || kafka.pings.Load() != 1 {
+		t.Fatalf("within the cache TTL: status %d after %d pings", rec.Code, kafka.pings.Load())
+	}
+	setClock(t, noon.Add(readinessCacheTTL))
+	rec, report = get(req())
+	if rec.Code != http.StatusServiceUnavailable || report.Status != "unavailable" || report.Dependencies["kafka"] != "unavailable" || report.Dependencies["db:default"] != "ok" {
+		t.Fatalf("unhealthy: status %d, report %+v", rec.Code, report)
+	}
+	if strings.Contains(rec.Body.String(), "10.0.0.7") || strings.Contains(rec.Body.String(), "refused") {
+		t.Fatalf("/readyz leaks the dependency error: %s", rec.Body)
+	}
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Fatalf("dependency error not logged:\n%s", logs)
+	}
+}
+
+func TestReadyzIgnoresCallerCancellation(t *testing.T) {
+	get := readyzWith(t, map[string]pinger{"db:default": &fakeDependency{delay: 10 * time.Millisecond}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if rec, report := get(httptest.NewRequest("GET", "/readyz", nil).WithContext(ctx)); rec.Code != http.StatusOK {
+		t.Fatalf("cancelled caller: status %d, report %+v", rec.Code, report)
+	}
+}
+
+func TestReadyzTimesOutSlowDependency(t *testing.T) {
+	setVar(t, &readinessTimeout, 20*time.Millisecond)
+	get := readyzWith(t, map[string]pinger{"kafka": &fakeDependency{delay: time.Minute}})
+	start := time.Now()
+	rec, report := get(httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable || report.Dependencies["kafka"] != "unavailable" {
+		t.Fatalf("status %d, report %+v", rec.Code, report)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("check took %s", d)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHistoryTimeRange(t *testing.T) {
+	api, tn := newTestAPI(t)
+	for i := 0; i < 5; i++ {
+		at := noon.Add(time.Duration(i) * time.Hour)
+		processAt(t, tn, at, Transaction{Amount: dollars(10), Merchant: "m", Timestamp: at})
+	}
+	from, to := noon.Add(time.Hour).Format(time.RFC3339), noon.Add(3*time.Hour).Format(time.RFC3339)
+	rec := do(api, "GET", "/transactions?from="+url.QueryEscape(from)+"&to="+url.QueryEscape(to), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	page := decode[HistoryPage](t, rec)
+	if len(page.Transactions) != 2 || page.NextCursor != "" {
+		t.Fatalf("got %d transactions (cursor %q), want 2 and no cursor", len(page.Transactions), page.NextCursor)
+	}
+	for _, r := range page.Transactions {
+		if at := r.Transaction.Timestamp; at.Before(noon.Add(time.Hour)) || !at.Before(noon.Add(3*time.Hour)) {
+			t.Errorf("transaction at %s outside [from, to)", at)
+		}
+	}
+
+	rec = do(api, "GET", "/transactions?from="+url.QueryEscape(to)+"&to="+url.QueryEscape(from), "")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("from after to: status %d, want 422", rec.Code)
+	}
+}
+
+func TestHistoryLimitBounds(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for target, want := range map[string]int{
+		fmt.Sprintf("/transactions?limit=%d", maxHistoryLimit):   http.StatusOK,
+		fmt.Sprintf("/transactions?limit=%d", maxHistoryLimit+1): http.StatusUnprocessableEntity,
+		fmt.Sprintf("/transactions?limit=%d", math.MaxInt):       http.StatusUnprocessableEntity,
+		"/transactions?limit=0":                                  http.StatusBadRequest,
+	} {
+		if rec := do(api, "GET", target, ""); rec.Code != want {
+			t.Errorf("%s: status %d, want %d", target, rec.Code, want)
+		}
+	}
+}
+
+func TestHistoryCursorPaging(t *testing.T) {
+	api, tn := newTestAPI(t)
+	for i := 0; i < 5; i++ {
+		processAt(t, tn, noon.Add(time.Duration(i)*time.Minute), Transaction{Amount: dollars(10), Merchant: "m"})
+	}
+	seen := map[int64]bool{}
+	var pages int
+	for target := "/transactions?limit=2"; ; {
+		rec := do(api, "GET", target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", target, rec.Code, rec.Body)
+		}
+		page := decode[HistoryPage](t, rec)
+		pages++
+		for _, r := range page.Transactions {
+			if seen[r.ID] {
+				t.Fatalf("record %d returned twice", r.ID)
+			}
+			seen[r.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		target = "/transactions?limit=2&cursor=" + page.NextCursor
+	}
+	if pages != 3 || len(seen) != 5 {
+		t.Fatalf("got %d records over %d pages, want 5 over 3", len(seen), pages)
+	}
+
+	if rec := do(api, "GET", "/transactions?cursor=bogus", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad cursor: status %d, want 400", rec.Code)
+	}
+}
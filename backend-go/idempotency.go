@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// idempotencyTTL is how long a decision is replayed for a repeated
+// Idempotency-Key.
+var idempotencyTTL = 24 * time.Hour
+
+// idempotency caches /risk decisions by Idempotency-Key.
+var idempotency = newIdempotencyCache()
+
+type idempotentEntry struct {
+	result  ScoreResult
+	expires time.Time
+	// hash identifies the request body a key was reserved for; pending is
+	// set until its decision is recorded.
+	hash    string
+	pending bool
+}
+
+// idemState is the outcome of reserving an Idempotency-Key.
+type idemState int
+
+const (
+	idemReserved idemState = iota // key is new; the caller must finish or release it
+	idemReplay                    // a decision is cached for the same body
+	idemInFlight                  // the same body is still being processed
+	idemMismatch                  // the key was used with a different body
+)
+
+// idempotencyCache maps keys to the decision first returned for them. It
+// backs both Idempotency-Key replay and payload deduplication. It is safe for
+// concurrent use; expired entries are swept lazily.
+type idempotencyCache struct {
+	mu        sync.Mutex
+	entries   map[string]idempotentEntry
+	lastSweep time.Time
+}
+
+func newIdempotencyCache() *idempotencyCache {
+	return &idempotencyCache{entries: map[string]idempotentEntry{}}
+}
+
+// get returns the cached decision for key if it hasn't expired.
+func (c *idempotencyCache) get(key string, now time.Time) (ScoreResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.pending || !now.Before(e.expires) {
+		return ScoreResult{}, false
+	}
+	return e.result, true
+}
+
+// reserve claims key for a request whose body hashes to hash. Concurrent
+// requests with the same key see idemInFlight until the first one calls
+// finish or release, so only one of them is processed.
+func (c *idempotencyCache) reserve(key, hash string, now time.Time, ttl time.Duration) (ScoreResult, idemState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		switch {
+		case e.hash != hash:
+			return ScoreResult{}, idemMismatch
+		case e.pending:
+			return ScoreResult{}, idemInFlight
+		default:
+			return e.result, idemReplay
+		}
+	}
+	c.entries[key] = idempotentEntry{expires: now.Add(ttl), hash: hash, pending: true}
+	return ScoreResult{}, idemReserved
+}
+
+// finish records the decision for a key taken with reserve, valid for ttl
+// from now.
+func (c *idempotencyCache) finish(key string, res ScoreResult, now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[key]
+	c.entries[key] = idempotentEntry{result: res, expires: now.Add(ttl), hash: e.hash}
+}
+
+// release drops a reservation whose request failed so the client can retry.
+func (c *idempotencyCache) release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key].pending {
+		delete(c.entries, key)
+	}
+}
+
+// put records the decision for key, valid for ttl from now.
+func (c *idempotencyCache) put(key string, res ScoreResult, now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	c.entries[key] = idempotentEntry{result: res, expires: now.Add(ttl)}
+}
+
+// claim records key for ttl from now unless it is already held, and reports
+// whether it did.
+func (c *idempotencyCache) claim(key string, now time.Time, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		return false
+	}
+	c.entries[key] = idempotentEntry{expires: now.Add(ttl)}
+	return true
+}
+
+// unclaim drops a key taken with claim whose transaction was not recorded,
+// so a retry of it isn't rejected as a duplicate.
+func (c *idempotencyCache) unclaim(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// sweep drops expired entries at most once a minute. c.mu must be held.
+func (c *idempotencyCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) > time.Minute {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+// idempotencyKey scopes the client's Idempotency-Key to its tenant and API key
+// so two clients can't collide. It returns "" when the request has no key.
+func idempotencyKey(header, tenant, apiKey string) string {
+	if header == "" {
+		return ""
+	}
+	return tenant + "\x00" + apiKey + "\x00" + header
+}
+
+// requestHash fingerprints every field of t so a reused Idempotency-Key can
+// be told apart from a retry of the same request. The amount is hashed in
+// cents, with the decimal places it was submitted with, rather than as
+// rendered, which rounds to the currency's display precision.
+func requestHash(t Transaction) string {
+	var at int64
+	if !t.Timestamp.IsZero() {
+		at = t.Timestamp.UnixNano()
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%q %d %d %q %q %q %q %q %q %q %d",
+		t.ID, int64(t.Amount), t.amountPlaces, t.Currency, t.Merchant, t.MCC, t.Counterparty, t.AccountID, t.Country, t.Type, at))
+	return hex.EncodeToString(sum[:])
+}
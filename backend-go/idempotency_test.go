@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestIdempotencyKeyReplay(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &idempotency, newIdempotencyCache())
+	body := `{"amount": 2500, "merchant": "m"}`
+	first := do(api, "POST", "/risk", body, "Idempotency-Key", "k1")
+	second := do(api, "POST", "/risk", body, "Idempotency-Key", "k1")
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("statuses %d, %d", first.Code, second.Code)
+	}
+	if second.Header().Get("X-Idempotent-Replay") != "true" {
+		t.Fatal("second request was not a replay")
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 1 {
+		t.Fatalf("%d transactions recorded, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyDifferentBody(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &idempotency, newIdempotencyCache())
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m"}`, "Idempotency-Key", "k1")
+	rec := do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m"}`, "Idempotency-Key", "k1")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", rec.Code)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 1 {
+		t.Fatalf("%d transactions recorded, want 1", n)
+	}
+}
+
+func TestIdempotencyKeyAmountBelowDisplayPrecision(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &idempotency, newIdempotencyCache())
+	for i, pair := range [][2]string{
+		{`{"amount": 100, "currency": "JPY", "merchant": "m"}`, `{"amount": 100.4, "currency": "JPY", "merchant": "m"}`},
+		{`{"amount": 10, "merchant": "m"}`, `{"amount": 10.001, "merchant": "m"}`},
+	} {
+		key := fmt.Sprintf("k%d", i)
+		if rec := do(api, "POST", "/risk", pair[0], "Idempotency-Key", key); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", pair[0], rec.Code)
+		}
+		if rec := do(api, "POST", "/risk", pair[1], "Idempotency-Key", key); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s after %s: status %d, want 422", pair[1], pair[0], rec.Code)
+		}
+	}
+}
+
+func TestIdempotencyKeyConcurrent(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &idempotency, newIdempotencyCache())
+	const n = 20
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = do(api, "POST", "/risk", `{"amount": 2500, "merchant": "m"}`, "Idempotency-Key", "k1").Code
+		}()
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK && code != http.StatusConflict {
+			t.Errorf("request %d: status %d", i, code)
+		}
+	}
+	if got := tn.store.(*MemoryStore).Len(); got != 1 {
+		t.Fatalf("%d transactions recorded for one key, want 1", got)
+	}
+}
+
+func TestIdempotencyReservation(t *testing.T) {
+	c := newIdempotencyCache()
+	if _, state := c.reserve("k", "h1", noon, idempotencyTTL); state != idemReserved {
+		t.Fatalf("first reserve: state %d", state)
+	}
+	if _, state := c.reserve("k", "h1", noon, idempotencyTTL); state != idemInFlight {
+		t.Fatalf("concurrent reserve: state %d, want in flight", state)
+	}
+	c.release("k")
+	if _, state := c.reserve("k", "h1", noon, idempotencyTTL); state != idemReserved {
+		t.Fatalf("reserve after release: state %d", state)
+	}
+	c.finish("k", ScoreResult{Decision: Decision{RiskLevel: "HIGH"}}, noon, idempotencyTTL)
+	if res, state := c.reserve("k", "h1", noon, idempotencyTTL); state != idemReplay || res.RiskLevel != "HIGH" {
+		t.Fatalf("got %+v, state %d", res, state)
+	}
+	if _, state := c.reserve("k", "h2", noon, idempotencyTTL); state != idemMismatch {
+		t.Fatalf("different body: state %d, want mismatch", state)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Background job settings. Jobs run on jobWorkers goroutines; at most
+// jobQueueSize submitted jobs wait for a worker, and a finished job can be
+// fetched for jobTTL. A submission holds at most maxJobSize transactions in
+// a body of at most maxJobBytes, which leaves about 600 bytes for each.
+var (
+	jobWorkers         = 2
+	jobQueueSize       = 100
+	maxJobSize         = 100000
+	maxJobBytes  int64 = 64 << 20
+	jobTTL             = time.Hour
+)
+
+// Job states.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is the response body of POST /jobs and GET /jobs/{id}. Results are
+// present once the job is done, in the order the transactions were
+// submitted.
+type Job struct {
+	ID         string        `json:"job_id"`
+	Status     string        `json:"status"`
+	Total      int           `json:"total"`
+	Processed  int           `json:"processed"`
+	Results    []BatchResult `json:"results,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// job is a submitted batch and its progress.
+type job struct {
+	Job
+	tenant *Tenant
+	batch  []Transaction
+}
+
+// jobRunner processes submitted jobs on a bounded pool of workers. Job state
+// is kept in memory only, so jobs do not survive a restart. It is safe for
+// concurrent use.
+type jobRunner struct {
+	mu    sync.Mutex
+	jobs  map[string]*job
+	queue chan *job
+}
+
+// jobs is started on first use so that it picks up the flag values.
+var (
+	jobsOnce sync.Once
+	jobs     *jobRunner
+)
+
+func jobQueue() *jobRunner {
+	jobsOnce.Do(func() { jobs = newJobRunner(jobWorkers, jobQueueSize) })
+	return jobs
+}
+
+func newJobRunner(workers, queueSize int) *jobRunner {
+	jr := &jobRunner{jobs: make(map[string]*job), queue: make(chan *job, queueSize)}
+	for i := 0; i < workers; i++ {
+		go jr.work()
+	}
+	return jr
+}
+
+// submit queues batch for tn and returns the new job, or false when the
+// queue is full.
+func (jr *jobRunner) submit(tn *Tenant, batch []Transaction) (Job, bool) {
+	j := &job{
+		Job:    Job{ID: newUUID(), Status: JobQueued, Total: len(batch), CreatedAt: now().UTC()},
+		tenant: tn,
+		batch:  batch,
+	}
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	jr.prune(now())
+	select {
+	case jr.queue <- j:
+	default:
+		return Job{}, false
+	}
+	jr.jobs[j.ID] = j
+	return j.Job, true
+}
+
+// get returns a snapshot of tn's job id.
+func (jr *jobRunner) get(tn *Tenant, id string) (Job, bool) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	j, ok := jr.jobs[id]
+	if !ok || j.tenant.ID != tn.ID {
+		return Job{}, false
+	}
+	return j.Job, true
+}
+
+// prune forgets jobs that finished more than jobTTL ago. jr.mu must be held.
+func (jr *jobRunner) prune(at time.Time) {
+	for id, j := range jr.jobs {
+		if j.FinishedAt != nil && at.Sub(*j.FinishedAt) > jobTTL {
+			delete(jr.jobs, id)
+		}
+	}
+}
+
+func (jr *jobRunner) work() {
+	for j := range jr.queue {
+		jr.run(j)
+	}
+}
+
+// run processes every transaction of j, stopping at the first error.
+func (jr *jobRunner) run(j *job) {
+	jr.update(j, func(j *job) { j.Status = JobRunning })
+	ctx := context.Background()
+	results := make([]BatchResult, 0, len(j.batch))
+	for _, t := range j.batch {
+		res, err := j.tenant.process(ctx, t)
+		if err != nil {
+			logger.Error("job failed", "job_id", j.ID, "tenant", j.tenant.ID, "error", err)
+			msg := "storage error"
+			if errors.Is(err, errDuplicateID) {
+				msg = err.Error()
+			}
+			jr.update(j, func(j *job) { j.finish(JobFailed); j.Error = msg })
+			return
+		}
+		results = append(results, BatchResult{Merchant: t.Merchant, ScoreResult: res})
+		jr.update(j, func(j *job) { j.Processed++ })
+	}
+	jr.update(j, func(j *job) { j.finish(JobDone); j.Results = results })
+	logger.Info("job done", "job_id", j.ID, "tenant", j.tenant.ID, "transactions", len(results))
+}
+
+func (jr *jobRunner) update(j *job, fn func(*job)) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	fn(j)
+}
+
+func (j *job) finish(status string) {
+	at := now().UTC()
+	j.Status, j.FinishedAt, j.batch = status, &at, nil
+}
+
+// jobsHandler serves POST /jobs, which queues a batch like /risk/batch's for
+// background processing and answers 202 with the job, and GET /jobs/{id},
+// which reports its progress and, once done, its results. It is mounted on
+// /jobs and the /jobs/ subtree and parses the path itself.
+func (s *Server) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	_, id, _ := strings.Cut(r.URL.Path, "/jobs")
+	id = strings.TrimPrefix(id, "/")
+	if id == "" {
+		s.submitJob(w, r)
+		return
+	}
+	if strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	j, ok := jobQueue().get(s.tenant(r.Context()), id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(j)
+}
+
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var batch []Transaction
+	if !decodeValidated(w, r, batchSchema, &batch) {
+		return
+	}
+	if len(batch) > maxJobSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("job of %d transactions exceeds the maximum of %d", len(batch), maxJobSize))
+		return
+	}
+	for i, t := range batch {
+		if err := validateTransaction(t); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("transaction %d: %s", i, err))
+			return
+		}
+	}
+
+	j, ok := jobQueue().submit(s.tenant(r.Context()), batch)
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "job queue is full; retry later")
+		return
+	}
+	logFor(r.Context()).Info("job queued", "job_id", j.ID, "transactions", j.Total)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", apiVersion+"/jobs/"+j.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(j)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jobBody returns a JSON batch of n small transactions.
+func jobBody(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"amount": %d, "merchant": "m"}`, 1+i%20000)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+// waitJob polls GET /jobs/{id} until the job has finished.
+func waitJob(t *testing.T, api http.Handler, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := do(api, "GET", "/jobs/"+id, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /jobs/%s: status %d: %s", id, rec.Code, rec.Body)
+		}
+		j := decode[Job](t, rec)
+		if j.Status == JobDone || j.Status == JobFailed {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s after 5s", j.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobSubmitAndPoll(t *testing.T) {
+	api, tn := newTestAPI(t)
+	rec := do(api, "POST", "/jobs", `[
+		{"amount": 50, "merchant": "Corner Shop"},
+		{"amount": 2500, "merchant": "Corner Shop"},
+		{"amount": 600, "merchant": "Starbucks"}
+	]`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	submitted := decode[Job](t, rec)
+	if submitted.ID == "" || submitted.Total != 3 || rec.Header().Get("Location") != "/v1/jobs/"+submitted.ID {
+		t.Fatalf("job %+v, Location %q", submitted, rec.Header().Get("Location"))
+	}
+
+	j := waitJob(t, api, submitted.ID)
+	if j.Status != JobDone || j.Processed != 3 || len(j.Results) != 3 {
+		t.Fatalf("job %+v", j)
+	}
+	for i, want := range []string{"LOW", "MEDIUM", "HIGH"} {
+		if j.Results[i].RiskLevel != want {
+			t.Errorf("result %d: got %s, want %s", i, j.Results[i].RiskLevel, want)
+		}
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 3 {
+		t.Fatalf("%d transactions recorded, want 3", n)
+	}
+}
+
+func TestJobNotFound(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if rec := do(api, "GET", "/jobs/nope", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("status %d, want 404", rec.Code)
+	}
+}
+
+func TestJobSizeLimits(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &maxJobSize, 200)
+	// A full job is larger than maxBodyBytes; /jobs must still accept it.
+	setVar(t, &maxBodyBytes, 1024)
+	if len(jobBody(200)) <= 1024 {
+		t.Fatal("test job does not exceed maxBodyBytes")
+	}
+
+	rec := do(api, "POST", "/jobs", jobBody(200))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("job at maxJobSize: status %d: %s", rec.Code, rec.Body)
+	}
+	if j := waitJob(t, api, decode[Job](t, rec).ID); j.Status != JobDone || len(j.Results) != 200 {
+		t.Fatalf("job %s with %d results", j.Status, len(j.Results))
+	}
+
+	rec = do(api, "POST", "/jobs", jobBody(201))
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "exceeds the maximum of 200") {
+		t.Fatalf("job over maxJobSize: status %d: %s", rec.Code, rec.Body)
+	}
+
+	setVar(t, &maxJobBytes, int64(len(jobBody(200))-1))
+	rec = do(api, "POST", "/jobs", jobBody(200))
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "request body too large") {
+		t.Fatalf("body over maxJobBytes: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestBodyLimitForJobs(t *testing.T) {
+	for _, path := range []string{"/jobs", "/v1/jobs"} {
+		if got := bodyLimit(path); got != maxJobBytes {
+			t.Errorf("bodyLimit(%s) = %d, want maxJobBytes", path, got)
+		}
+	}
+	if got := bodyLimit("/risk/batch"); got != maxBodyBytes {
+		t.Errorf("bodyLimit(/risk/batch) = %d, want maxBodyBytes", got)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Producer sends keyed messages to a Kafka topic. The publisher calls it from
+// a single goroutine. A Producer that also has a Ping(ctx) error method is
+// checked by /readyz.
+type Producer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+	Close() error
+}
+
+// openKafkaProducer parses a comma-separated broker list and opens a Producer
+// for it.
+func openKafkaProducer(brokerList string) (Producer, error) {
+	var brokers []string
+	for _, b := range strings.Split(brokerList, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, errors.New("no Kafka brokers given")
+	}
+	return newKafkaWriter(brokers), nil
+}
+
+// kafkaBatchTimeout is how long the Kafka writer waits to fill a batch before
+// sending it.
+const kafkaBatchTimeout = 50 * time.Millisecond
+
+// kafkaWriter is the Producer for -kafka-brokers, built on
+// github.com/segmentio/kafka-go. It writes asynchronously: messages are
+// batched per partition, partitioned by key hash, and a batch that fails
+// delivery is counted as dropped when it completes.
+type kafkaWriter struct {
+	brokers []string
+	w       *kafka.Writer
+}
+
+func newKafkaWriter(brokers []string) *kafkaWriter {
+	return &kafkaWriter{brokers: brokers, w: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: kafkaBatchTimeout,
+		Async:        true,
+		Completion: func(msgs []kafka.Message, err error) {
+			if err == nil {
+				return
+			}
+			for range msgs {
+				kafkaDropped.inc("produce_error")
+			}
+			logger.Warn("kafka produce failed", "messages", len(msgs), "error", err)
+		},
+	}}
+}
+
+func (k *kafkaWriter) Produce(ctx context.Context, topic string, key, value []byte) error {
+	return k.w.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: value})
+}
+
+// Ping succeeds when any broker accepts a connection.
+func (k *kafkaWriter) Ping(ctx context.Context) error {
+	var err error
+	for _, b := range k.brokers {
+		var conn *kafka.Conn
+		if conn, err = kafka.DialContext(ctx, "tcp", b); err == nil {
+			return conn.Close()
+		}
+	}
+	return err
+}
+
+// Close flushes pending batches and closes the writer.
+func (k *kafkaWriter) Close() error {
+	return k.w.Close()
+}
+
+// publisher streams every recorded decision to Kafka when -kafka-brokers is
+// set.
+var publisher *decisionPublisher
+
+// kafkaProduceTimeout bounds a single Produce call.
+const kafkaProduceTimeout = 10 * time.Second
+
+// decisionMessage is the JSON value published for a recorded decision. It is
+// keyed by account.
+type decisionMessage struct {
+	Tenant      string      `json:"tenant"`
+	Transaction Transaction `json:"transaction"`
+	RiskLevel   string      `json:"risk_level"`
+	Reason      string      `json:"reason"`
+	RiskScore   int         `json:"risk_score"`
+	Timestamp   time.Time   `json:"timestamp"`
+}
+
+// decisionPublisher hands decisions to a Producer in the background through a
+// bounded buffer, so a slow broker never blocks request handling. When the
+// buffer is full, or the publisher has been closed, the decision is dropped
+// and counted.
+type decisionPublisher struct {
+	producer Producer
+	topic    string
+	queue    chan decisionMessage
+	done     chan struct{}
+
+	// mu guards closed; publish holds it shared so Close cannot close queue
+	// under a send.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newDecisionPublisher(p Producer, topic string, buffer int) *decisionPublisher {
+	dp := &decisionPublisher{
+		producer: p,
+		topic:    topic,
+		queue:    make(chan decisionMessage, buffer),
+		done:     make(chan struct{}),
+	}
+	go dp.run()
+	return dp
+}
+
+// publish queues msg without blocking.
+func (p *decisionPublisher) publish(msg decisionMessage) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		kafkaDropped.inc("closed")
+		logger.Warn("kafka publisher closed; dropping decision", "topic", p.topic, "risk_level", msg.RiskLevel)
+		return
+	}
+	select {
+	case p.queue <- msg:
+	default:
+		kafkaDropped.inc("buffer_full")
+		logger.Warn("kafka buffer full; dropping decision", "topic", p.topic, "risk_level", msg.RiskLevel)
+	}
+}
+
+func (p *decisionPublisher) run() {
+	defer close(p.done)
+	for msg := range p.queue {
+		value, err := json.Marshal(msg)
+		if err != nil {
+			logger.Error("kafka encode failed", "error", err)
+			continue
+		}
+		var key []byte
+		if msg.Transaction.AccountID != "" {
+			key = []byte(msg.Transaction.AccountID)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), kafkaProduceTimeout)
+		err = p.producer.Produce(ctx, p.topic, key, value)
+		cancel()
+		if err != nil {
+			kafkaDropped.inc("produce_error")
+			logger.Warn("kafka produce failed", "topic", p.topic, "error", err)
+		}
+	}
+}
+
+// Close stops accepting decisions, waits for the queued ones to be produced
+// and closes the producer. Decisions published afterwards are dropped.
+func (p *decisionPublisher) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+	<-p.done
+	return p.producer.Close()
+}
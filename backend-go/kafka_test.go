@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// mockProducer records produced messages. While block is open, Produce waits
+// on it.
+type mockProducer struct {
+	mu       sync.Mutex
+	messages []producedMessage
+	block    chan struct{}
+	closed   bool
+}
+
+type producedMessage struct {
+	topic      string
+	key, value []byte
+}
+
+func (m *mockProducer) Produce(_ context.Context, topic string, key, value []byte) error {
+	if m.block != nil {
+		<-m.block
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, producedMessage{topic, key, value})
+	return nil
+}
+
+func (m *mockProducer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func TestPublisherPublishesDecisions(t *testing.T) {
+	api, _ := newTestAPI(t)
+	mp := &mockProducer{}
+	p := newDecisionPublisher(mp, "risk-decisions", 10)
+	setVar(t, &publisher, p)
+
+	do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m", "account_id": "acct-1", "id": "tx-1"}`)
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m", "id": "tx-2"}`)
+	do(api, "POST", "/risk?dry_run=true", `{"amount": 10, "merchant": "m", "id": "tx-3"}`)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !mp.closed {
+		t.Fatal("producer not closed")
+	}
+	if len(mp.messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(mp.messages))
+	}
+	for i, want := range []struct {
+		key, id, level string
+	}{{"acct-1", "tx-1", "HIGH"}, {"", "tx-2", "LOW"}} {
+		m := mp.messages[i]
+		var msg decisionMessage
+		if err := json.Unmarshal(m.value, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if m.topic != "risk-decisions" || string(m.key) != want.key || msg.Transaction.ID != want.id || msg.RiskLevel != want.level || msg.Tenant != defaultTenant {
+			t.Errorf("message %d: topic %q key %q %+v, want key %q, %s %s", i, m.topic, m.key, msg, want.key, want.id, want.level)
+		}
+	}
+}
+
+func TestPublisherDropsWhenBufferFull(t *testing.T) {
+	mp := &mockProducer{block: make(chan struct{})}
+	p := newDecisionPublisher(mp, "t", 1)
+	before := kafkaDropped.value("buffer_full")
+	// The blocked producer holds at most one message and the buffer one more,
+	// so at least three are dropped.
+	for i := 0; i < 5; i++ {
+		p.publish(decisionMessage{RiskLevel: "LOW"})
+	}
+	close(mp.block)
+	p.Close()
+	sent := len(mp.messages)
+	if dropped := kafkaDropped.value("buffer_full") - before; sent+int(dropped) != 5 || dropped < 3 {
+		t.Fatalf("%d sent and %v dropped, want at least 3 of 5 dropped", sent, dropped)
+	}
+}
+
+func TestPublisherPublishAfterClose(t *testing.T) {
+	mp := &mockProducer{}
+	p := newDecisionPublisher(mp, "t", 1)
+	p.Close()
+	before := kafkaDropped.value("closed")
+	p.publish(decisionMessage{RiskLevel: "LOW"})
+	if got := kafkaDropped.value("closed") - before; got != 1 {
+		t.Fatalf("closed drops %v, want 1", got)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestOpenKafkaProducerNoBrokers(t *testing.T) {
+	if _, err := openKafkaProducer(" , "); err == nil {
+		t.Fatal("no error for an empty broker list")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// strictMerchants rejects transactions from merchants not in knownMerchants
+// instead of scoring them.
+var strictMerchants = false
+
+// knownMerchants is the set of approved merchants, keyed by normalized name
+// and loaded from the -known-merchants file.
+var knownMerchants = map[string]bool{}
+
+// loadKnownMerchants reads one merchant name per line from path. Blank lines
+// and lines starting with '#' are ignored.
+func loadKnownMerchants(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read known merchants: %w", err)
+	}
+	defer f.Close()
+
+	m := map[string]bool{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m[normalizeMerchant(line)] = true
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read known merchants: %w", err)
+	}
+	return m, nil
+}
+
+// knownMerchant reports whether a transaction from merchant is accepted:
+// always, unless strict mode is on.
+func knownMerchant(merchant string) bool {
+	return !strictMerchants || knownMerchants[normalizeMerchant(merchant)]
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStrictMerchants(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &knownMerchants, map[string]bool{"corner shop": true})
+	setVar(t, &strictMerchants, true)
+
+	rec := do(api, "POST", "/risk", `{"amount": 2500, "merchant": "Corner Shop"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("approved merchant: status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, rec); res.RiskLevel != "MEDIUM" {
+		t.Fatalf("approved merchant: got %s", res.RiskLevel)
+	}
+
+	body := `{"amount": 10, "merchant": "Unknown Traders"}`
+	rec = do(api, "POST", "/risk", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown merchant in strict mode: status %d, want 422", rec.Code)
+	}
+	if e := decode[ErrorResponse](t, rec); e.Error != "unknown merchant" {
+		t.Fatalf("error %q, want \"unknown merchant\"", e.Error)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 1 {
+		t.Fatalf("%d records, want only the approved merchant's", n)
+	}
+
+	strictMerchants = false
+	rec = do(api, "POST", "/risk", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unknown merchant, strict mode off: status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, rec); res.RiskLevel != "LOW" {
+		t.Fatalf("unknown merchant, strict mode off: got %s", res.RiskLevel)
+	}
+}
+
+func TestLoadKnownMerchants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "merchants.txt")
+	if err := os.WriteFile(path, []byte("# approved\n Corner Shop \n\nAPPLE STORE\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadKnownMerchants(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || !m["corner shop"] || !m["apple store"] {
+		t.Fatalf("got %v", m)
+	}
+}
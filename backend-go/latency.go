@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Latency buckets grow geometrically from latencyMin by latencyGrowth, so a
+// reported percentile is within about 2% of the true value anywhere from
+// latencyMin to latencyMax. Observations outside that range are clamped.
+const (
+	latencyMin    = 10 * time.Microsecond
+	latencyMax    = 100 * time.Second
+	latencyGrowth = 1.04
+)
+
+var latencyBucketCount = int(math.Ceil(math.Log(float64(latencyMax)/float64(latencyMin))/math.Log(latencyGrowth))) + 1
+
+// latencies feeds GET /stats/latency; instrument records every scoring
+// request.
+var latencies = newLatencyRecorder()
+
+// latencyRecorder is a bucketed latency histogram for percentile estimates.
+// It is safe for concurrent use.
+type latencyRecorder struct {
+	mu     sync.Mutex
+	counts []uint64
+	total  uint64
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{counts: make([]uint64, latencyBucketCount)}
+}
+
+// bucketFor returns the index of the bucket whose upper bound is the
+// smallest one not below d.
+func bucketFor(d time.Duration) int {
+	if d <= latencyMin {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(latencyMin)) / math.Log(latencyGrowth)))
+	if i >= latencyBucketCount {
+		return latencyBucketCount - 1
+	}
+	return i
+}
+
+// bucketValue is the geometric midpoint of bucket i, used as its estimate.
+func bucketValue(i int) time.Duration {
+	if i == 0 {
+		return latencyMin
+	}
+	return time.Duration(float64(latencyMin) * math.Pow(latencyGrowth, float64(i)-0.5))
+}
+
+func (l *latencyRecorder) observe(d time.Duration) {
+	i := bucketFor(d)
+	l.mu.Lock()
+	l.counts[i]++
+	l.total++
+	l.mu.Unlock()
+}
+
+// Reset discards every observation.
+func (l *latencyRecorder) Reset() {
+	l.mu.Lock()
+	clear(l.counts)
+	l.total = 0
+	l.mu.Unlock()
+}
+
+// LatencySummary is the response body of GET /stats/latency.
+type LatencySummary struct {
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	Count uint64  `json:"count"`
+}
+
+// Summary returns the current percentiles. They are zero before any
+// observation.
+func (l *latencyRecorder) Summary() LatencySummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LatencySummary{
+		P50Ms: l.quantile(0.50),
+		P95Ms: l.quantile(0.95),
+		P99Ms: l.quantile(0.99),
+		Count: l.total,
+	}
+}
+
+// quantile returns the q-th quantile in milliseconds. l.mu must be held.
+func (l *latencyRecorder) quantile(q float64) float64 {
+	if l.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(l.total)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range l.counts {
+		if seen += c; seen >= rank {
+			return float64(bucketValue(i)) / float64(time.Millisecond)
+		}
+	}
+	return float64(bucketValue(len(l.counts)-1)) / float64(time.Millisecond)
+}
+
+// latencyHandler serves GET /stats/latency, the scoring latency percentiles
+// since start-up or the last reset, and DELETE /stats/latency, which resets
+// them.
+func latencyHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(latencies.Summary())
+	case http.MethodDelete:
+		latencies.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
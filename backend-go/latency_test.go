@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyPercentiles(t *testing.T) {
+	l := newLatencyRecorder()
+	// 1ms to 1000ms in 1ms steps: the true percentiles are 500, 950 and 990.
+	for i := 1; i <= 1000; i++ {
+		l.observe(time.Duration(i) * time.Millisecond)
+	}
+	s := l.Summary()
+	if s.Count != 1000 {
+		t.Fatalf("count %d, want 1000", s.Count)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{{"p50", s.P50Ms, 500}, {"p95", s.P95Ms, 950}, {"p99", s.P99Ms, 990}} {
+		if math.Abs(tc.got-tc.want)/tc.want > 0.03 {
+			t.Errorf("%s = %.2fms, want %.0fms ±3%%", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLatencyConcurrentAndReset(t *testing.T) {
+	l := newLatencyRecorder()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				l.observe(2 * time.Millisecond)
+				l.Summary()
+			}
+		}()
+	}
+	wg.Wait()
+	if s := l.Summary(); s.Count != 4000 || math.Abs(s.P99Ms-2)/2 > 0.03 {
+		t.Fatalf("summary %+v, want 4000 observations of 2ms", s)
+	}
+	l.Reset()
+	if s := l.Summary(); s != (LatencySummary{}) {
+		t.Fatalf("after reset: %+v", s)
+	}
+}
+
+func TestLatencyHandler(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &latencies, newLatencyRecorder())
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m"}`)
+	if s := decode[LatencySummary](t, do(api, "GET", "/stats/latency", "")); s.Count != 1 {
+		t.Fatalf("count %d after one scoring request, want 1", s.Count)
+	}
+	if rec := do(api, "DELETE", "/stats/latency", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: status %d", rec.Code)
+	}
+	if s := decode[LatencySummary](t, do(api, "GET", "/stats/latency", "")); s.Count != 0 {
+		t.Fatalf("count %d after reset", s.Count)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Daily spending limits. Once an account's debits for the reporting day,
+// including the one being scored, exceed its limit, the transaction is
+// blocked as HIGH. accountDailyLimits overrides dailyLimit per account; a
+// limit of zero disables the check.
+var (
+	dailyLimit         Money
+	accountDailyLimits = map[string]Money{}
+)
+
+// dailyLimitReason explains a blocked transaction.
+const dailyLimitReason = "daily limit exceeded"
+
+// loadAccountLimits reads a JSON object of account ID to USD daily limit
+// from path. A zero limit exempts the account from the global one.
+func loadAccountLimits(path string) (map[string]Money, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read account limits: %w", err)
+	}
+	var m map[string]Money
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse account limits: %w", err)
+	}
+	for account, v := range m {
+		if v < 0 {
+			return nil, fmt.Errorf("account %s: limit must not be negative", account)
+		}
+	}
+	return m, nil
+}
+
+// limitFor returns account's daily limit, or zero if it has none.
+func limitFor(account string) Money {
+	if l, ok := accountDailyLimits[account]; ok {
+		return l
+	}
+	return dailyLimit
+}
+
+// dailyLimitCheck reports whether t, a debit already converted to USD,
+// takes its account's total for the day of t.Timestamp over the account's
+// limit. The account ID is matched as stored, and a transaction without a
+// timestamp counts against today.
+func (tn *Tenant) dailyLimitCheck(ctx context.Context, t Transaction) (bool, error) {
+	limit := limitFor(t.AccountID)
+	if limit <= 0 || t.AccountID == "" || isCredit(t) {
+		return false, nil
+	}
+	at := t.Timestamp
+	if at.IsZero() {
+		at = now()
+	}
+	total, err := tn.store.AccountTotal(ctx, t.AccountID, at)
+	if err != nil {
+		return false, err
+	}
+	return total+t.Amount > limit, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDailyLimitBlocksCrossingDebit(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &dailyLimit, dollars(5000))
+	for _, tc := range []struct {
+		amount int
+		want   string
+	}{
+		{400, "LOW"},
+		{1500, "MEDIUM"},
+		{3000, "MEDIUM"},
+		{200, "HIGH"},
+		{10, "HIGH"},
+	} {
+		body := fmt.Sprintf(`{"amount": %d, "merchant": "Corner Shop", "account_id": "acct-1"}`, tc.amount)
+		res := decode[ScoreResult](t, do(api, "POST", "/risk", body))
+		if res.RiskLevel != tc.want {
+			t.Fatalf("$%d: got %s (%s), want %s", tc.amount, res.RiskLevel, res.Reason, tc.want)
+		}
+		if tc.want == "HIGH" && res.Reason != dailyLimitReason {
+			t.Fatalf("$%d: reason %q", tc.amount, res.Reason)
+		}
+	}
+	// Credits and other accounts are unaffected.
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 10, "merchant": "Corner Shop", "account_id": "acct-1", "type": "credit"}`)); res.Reason == dailyLimitReason {
+		t.Fatal("credit blocked by the daily limit")
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 10, "merchant": "Corner Shop", "account_id": "acct-2"}`)); res.RiskLevel != "LOW" {
+		t.Fatalf("other account: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestDailyLimitUsesTransactionDay(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &dailyLimit, dollars(1000))
+	for _, tc := range []struct {
+		account, timestamp string
+		want               string
+	}{
+		{"acct-1", "2026-03-09T09:00:00Z", "LOW"},
+		{"acct-1", "2026-03-09T10:00:00Z", "LOW"},
+		// A backfilled debit counts against the day it happened.
+		{"acct-1", "2026-03-09T11:00:00Z", "HIGH"},
+		// Today's spend starts from zero.
+		{"acct-1", "", "LOW"},
+		// IDs are matched as stored, without trimming.
+		{" acct-2", "", "LOW"},
+		{" acct-2", "", "LOW"},
+		{"acct-2", "", "LOW"},
+	} {
+		body := fmt.Sprintf(`{"amount": 400, "merchant": "Corner Shop", "account_id": %q}`, tc.account)
+		if tc.timestamp != "" {
+			body = fmt.Sprintf(`{"amount": 400, "merchant": "Corner Shop", "account_id": %q, "timestamp": %q}`, tc.account, tc.timestamp)
+		}
+		res := decode[ScoreResult](t, do(api, "POST", "/risk", body))
+		if res.RiskLevel != tc.want {
+			t.Fatalf("%q at %q: got %s (%s), want %s", tc.account, tc.timestamp, res.RiskLevel, res.Reason, tc.want)
+		}
+	}
+}
+
+func TestDailyLimitPerAccountOverride(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &dailyLimit, dollars(100))
+	setVar(t, &accountDailyLimits, map[string]Money{"vip": 0, "tight": dollars(20)})
+	for _, tc := range []struct {
+		account string
+		want    string
+	}{
+		{"vip", "LOW"},
+		{"vip", "LOW"},
+		{"tight", "HIGH"},
+		{"other", "LOW"},
+	} {
+		res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 60, "merchant": "Corner Shop", "account_id": "`+tc.account+`"}`))
+		if res.RiskLevel != tc.want {
+			t.Errorf("%s: got %s (%s), want %s", tc.account, res.RiskLevel, res.Reason, tc.want)
+		}
+	}
+}
+
+func TestDailyLimitAppliesToFinalDecisions(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	setVar(t, &dailyLimit, dollars(1000))
+	setVar(t, &watchlist, loadTestWatchlist(t))
+	if rec := do(api, "POST", "/merchants/allow", `{"merchant": "Payroll Co"}`); rec.Code != http.StatusOK {
+		t.Fatalf("allow: status %d: %s", rec.Code, rec.Body)
+	}
+	body := `{"amount": 600, "merchant": "Payroll Co", "account_id": "acct-1"}`
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "LOW" {
+		t.Fatalf("under the limit: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "HIGH" || res.Reason != dailyLimitReason {
+		t.Fatalf("allow-listed merchant over the limit: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 600, "merchant": "m", "counterparty": "Ivan Petrov", "account_id": "acct-1"}`))
+	if res.RiskLevel != "HIGH" || res.Reason == dailyLimitReason {
+		t.Fatalf("watchlist hit over the limit: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestLoadAccountLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	os.WriteFile(path, []byte(`{"acct-1": 2500, "acct-2": 0}`), 0o600)
+	m, err := loadAccountLimits(path)
+	if err != nil || m["acct-1"] != dollars(2500) || m["acct-2"] != 0 {
+		t.Fatalf("got %v, %v", m, err)
+	}
+	os.WriteFile(path, []byte(`{"acct-1": -1}`), 0o600)
+	if _, err := loadAccountLimits(path); err == nil {
+		t.Fatal("negative limit accepted")
+	}
+}
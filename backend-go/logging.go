@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// logger is the service-wide structured logger. main replaces it according
+// to -log-level and -log-format.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// newLogger builds a logger writing to w. level is one of debug, info, warn
+// or error; format is text or json.
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: want debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: want text or json", format)
+	}
+}
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	tenantKey
+	velocityKey
+)
+
+// maxRequestIDLen bounds inbound X-Request-ID values we are willing to echo.
+const maxRequestIDLen = 128
+
+// newUUID returns a random RFC 4122 version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// requestIDFrom returns the request ID stored in ctx, or "" if there is none.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// logFor returns a logger that tags every line with the request's ID.
+func logFor(ctx context.Context) *slog.Logger {
+	if t := tenantFrom(ctx); t != nil {
+		return logger.With("request_id", requestIDFrom(ctx), "tenant", t.ID)
+	}
+	return logger.With("request_id", requestIDFrom(ctx))
+}
+
+// withRequestID assigns each request an ID (reusing an inbound X-Request-ID),
+// stores it in the request context, echoes it in the response and writes an
+// access log line when the request completes.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > maxRequestIDLen {
+			id = newUUID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		logger.LogAttrs(ctx, slog.LevelInfo, "request",
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
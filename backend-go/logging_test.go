@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestIDGeneratedAndPreserved(t *testing.T) {
+	var seen string
+	h := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFrom(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	id := rec.Header().Get("X-Request-ID")
+	if !uuidPattern.MatchString(id) || seen != id {
+		t.Fatalf("generated ID %q, handler saw %q", id, seen)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", "upstream-42")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if got := rec.Header().Get("X-Request-ID"); got != "upstream-42" || seen != "upstream-42" {
+		t.Fatalf("inbound ID not preserved: header %q, context %q", got, seen)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", strings.Repeat("x", maxRequestIDLen+1))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if got := rec.Header().Get("X-Request-ID"); !uuidPattern.MatchString(got) {
+		t.Fatalf("oversized inbound ID echoed: %q", got)
+	}
+}
+
+func TestLogLevelWarnSuppressesInfoAndDebug(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := newLogger(&buf, "warn", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setVar(t, &logger, l)
+	api, _ := newTestAPI(t)
+	setVar(t, &logSampleRate, 1.0)
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m"}`)
+	logger.Warn("disk nearly full")
+
+	out := buf.String()
+	for _, msg := range []string{"rule evaluation", "scored transaction"} {
+		if strings.Contains(out, msg) {
+			t.Errorf("%q logged at level warn:\n%s", msg, out)
+		}
+	}
+	if !strings.Contains(out, `"level":"WARN","msg":"disk nearly full"`) {
+		t.Fatalf("warning missing or not JSON:\n%s", out)
+	}
+}
+
+func TestLogLevelDebugTracesRules(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := newLogger(&buf, "DEBUG", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setVar(t, &logger, l)
+	api, _ := newTestAPI(t)
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m"}`)
+	if !strings.Contains(buf.String(), "level=DEBUG msg=\"rule evaluation\"") {
+		t.Fatalf("rule trace missing at debug:\n%s", buf.String())
+	}
+}
+
+func TestNewLoggerRejectsUnknownSettings(t *testing.T) {
+	if _, err := newLogger(io.Discard, "verbose", "text"); err == nil {
+		t.Error("unknown level accepted")
+	}
+	if _, err := newLogger(io.Discard, "info", "xml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestDecisionLogSampling(t *testing.T) {
+	const low, high = 20, 3
+	for _, tc := range []struct {
+		rate float64
+		want int
+	}{
+		{0, high},
+		{1, low + high},
+	} {
+		api, _ := newTestAPI(t)
+		buf := captureLogs(t, false)
+		setVar(t, &logSampleRate, tc.rate)
+		for i := 0; i < low; i++ {
+			do(api, "POST", "/risk", `{"amount": 10, "merchant": "m"}`)
+		}
+		for i := 0; i < high; i++ {
+			do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m"}`)
+		}
+		out := buf.String()
+		if got := strings.Count(out, `level=INFO msg="scored transaction"`); got != tc.want {
+			t.Errorf("rate %v: %d decisions logged at info, want %d", tc.rate, got, tc.want)
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if tc.rate == 0 && strings.Contains(line, `level=INFO msg="scored transaction"`) && !strings.Contains(line, "risk_level=HIGH") {
+				t.Errorf("rate 0: a non-HIGH decision was logged at info: %s", line)
+			}
+		}
+		if tc.rate == 0 && strings.Count(out, `level=DEBUG msg="scored transaction"`) != low {
+			t.Errorf("rate 0: unsampled decisions not kept at debug:\n%s", out)
+		}
+	}
+}
+
+func TestParseSampleRate(t *testing.T) {
+	for _, s := range []string{"0", "0.25", "1"} {
+		if _, err := parseSampleRate(s); err != nil {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+	for _, s := range []string{"-0.1", "1.5", "half", "NaN"} {
+		if _, err := parseSampleRate(s); err == nil {
+			t.Errorf("%s accepted", s)
+		}
+	}
+}
@@ -3,62 +3,442 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"net"
 	"net/http"
-	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// maxBatchSize caps the number of transactions accepted by /risk/batch.
+var maxBatchSize = 1000
+
 type RiskRequest struct {
 	Amount float64 `json:"amount"`
 }
-type Transaction struct {
-	Amount   float64 `json:"amount"`
-	Merchant string  `json:"merchant"`
+
+const defaultAddr = ":8080"
+
+// resolveAddr returns the address to listen on. Precedence is the -addr flag,
+// then the ADDR environment variable, then defaultAddr.
+func resolveAddr(flagAddr string) string {
+	if flagAddr != "" {
+		return flagAddr
+	}
+	if env := os.Getenv("ADDR"); env != "" {
+		return env
+	}
+	return defaultAddr
 }
 
-func checkRisk(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
+// shutdownTimeout bounds how long in-flight requests get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
+// serve runs srv on ln until ctx is cancelled, then shuts it down gracefully.
+// When certFile and keyFile are set the server speaks TLS.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, certFile, keyFile string) error {
+	errc := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			errc <- srv.ServeTLS(ln, certFile, keyFile)
+			return
+		}
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
 	}
 
-	var t Transaction
-	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	logger.Info("shutting down")
+	ready.Store(false)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
+}
 
-	riskLevel := "LOW"
+func main() {
+	addrFlag := flag.String("addr", "", "listen address (overrides $ADDR; default "+defaultAddr+")")
+	rulesPath := flag.String("rules", "rules.json", "path to the JSON rule set (replaced by base64-encoded JSON in $RULES_JSON when set)")
+	tenantList := flag.String("tenants", "", "comma-separated tenant IDs; tenants bound by API_KEYS are added automatically")
+	tenantRulesDir := flag.String("tenant-rules-dir", "", "directory of per-tenant rule sets named <tenant>.json (tenants without one use -rules)")
+	rulesetDir := flag.String("ruleset-dir", "", "directory of alternate rule sets named <name>.json, selected per request with the X-Ruleset header")
+	ratesPath := flag.String("rates", "", "optional JSON file of currency code to USD rate")
+	flag.Func("currency-precision", "display decimal places per currency as CODE=places,... (default JPY=0,KRW=0,BHD=3,KWD=3,OMR=3; others 2)", func(s string) error {
+		p, err := parseCurrencyPlaces(s)
+		for code, n := range p {
+			currencyPlaces[code] = n
+		}
+		return err
+	})
+	flag.Func("threshold-mode", "convert (judge every amount in USD) or native (judge currencies in -currency-thresholds in their own units; default convert)", func(s string) (err error) {
+		thresholdMode, err = parseThresholdMode(s)
+		return err
+	})
+	currencyThresholdsPath := flag.String("currency-thresholds", "", "JSON file of currency code to {\"medium\", \"high\"} native thresholds; requires -threshold-mode native")
+	categoriesPath := flag.String("categories", "", "optional JSON file of MCC to USD anomaly threshold")
+	merchantThresholdsPath := flag.String("merchant-thresholds", "", "optional JSON file of merchant to {\"medium\", \"high\"} USD thresholds")
+	merchantFloorPath := flag.String("merchant-floor", "", "optional JSON file of merchant to the minimum risk level its transactions get")
+	flag.Var(&dailyLimit, "daily-limit", "USD amount an account may debit per reporting day before its transactions are HIGH (0 disables)")
+	accountLimitsPath := flag.String("account-limits", "", "optional JSON file of account ID to USD daily limit, overriding -daily-limit")
+	countriesList := flag.String("high-risk-countries", "", "comma-separated ISO country codes to treat as high risk (default IR,KP,MM)")
+	countriesPath := flag.String("high-risk-countries-file", "", "optional file of high-risk ISO country codes, one per line")
+	flag.Func("amount-locale", "how digit grouping in amount strings is read: none, us (10,000.50) or eu (10.000,50) (default none)", func(s string) (err error) {
+		amountLocale, err = parseAmountLocale(s)
+		return err
+	})
+	flag.Var(&maxAmount, "max-amount", "largest USD amount accepted for scoring; larger ones are rejected with 422")
+	flag.Var(&largeRefundThreshold, "large-refund-threshold", "USD amount above which a credit is MEDIUM risk")
+	flag.Var(&highRiskCountryThreshold, "high-risk-country-threshold", "USD amount above which a high-risk-country transaction is HIGH")
+	watchlistPath := flag.String("watchlist", "", "optional newline-delimited sanctions watchlist")
+	knownMerchantsPath := flag.String("known-merchants", "", "newline-delimited list of approved merchants for -strict-merchants")
+	flag.BoolVar(&strictMerchants, "strict-merchants", strictMerchants, "reject transactions from merchants not in -known-merchants with 422")
+	corsList := flag.String("cors-origins", "", "comma-separated browser origins allowed by CORS")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; serves HTTPS when set with -tls-key")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	grpcAddr := flag.String("grpc-addr", "", "listen address for the gRPC RiskService (empty disables it); uses -tls-cert and -tls-key when set")
+	webhookURL := flag.String("webhook-url", "", "optional URL notified of HIGH-risk decisions")
+	flag.IntVar(&webhookBreakerFailures, "webhook-breaker-failures", webhookBreakerFailures, "consecutive webhook failures that open the circuit breaker")
+	flag.DurationVar(&webhookBreakerCooldown, "webhook-breaker-cooldown", webhookBreakerCooldown, "how long the webhook circuit stays open before a trial delivery")
+	kafkaBrokers := flag.String("kafka-brokers", "", "comma-separated Kafka brokers; every recorded decision is published when set")
+	kafkaTopic := flag.String("kafka-topic", "risk-decisions", "Kafka topic for published decisions")
+	kafkaBuffer := flag.Int("kafka-buffer", 1000, "decisions buffered for Kafka before new ones are dropped")
+	businessTZ := flag.String("business-tz", "", "IANA timezone for business hours; required by -off-hours")
+	flag.Func("business-hours", "business hours as HH:MM-HH:MM; transactions outside them are escalated (default 06:00-22:00)", func(s string) (err error) {
+		businessStart, businessEnd, err = parseBusinessHours(s)
+		return err
+	})
+	flag.BoolVar(&offHoursEnabled, "off-hours", offHoursEnabled, "escalate transactions outside business hours in -business-tz")
+	reportTZ := flag.String("report-tz", "UTC", "IANA timezone that defines the reporting day")
+	settings := defaultSettings
+	flag.Var(&settings.MediumThreshold, "medium-threshold", "USD amount above which transactions are MEDIUM risk")
+	flag.Var(&settings.HighThreshold, "high-threshold", "USD amount above which transactions are HIGH risk")
+	flag.StringVar(&settings.DefaultLevel, "default-level", settings.DefaultLevel, "risk level for transactions no rule matches: LOW, MEDIUM or HIGH")
+	flag.StringVar(&settings.CombinePolicy, "combine-policy", settings.CombinePolicy, "how several matching rules combine: first-match or max-severity")
+	dbPath := flag.String("db", "", "SQLite database path for durable history (empty keeps history in memory)")
+	historySize := flag.Int("history-size", defaultHistorySize, "number of scored transactions kept in memory")
+	flag.DurationVar(&velocityHalfLife, "velocity-half-life", velocityHalfLife, "half-life of the per-account velocity score")
+	flag.Float64Var(&velocityThreshold, "velocity-threshold", velocityThreshold, "velocity score above which an account's transactions are HIGH risk (0 disables)")
+	flag.Float64Var(&anomalyMultiple, "anomaly-multiple", anomalyMultiple, "escalate amounts above this multiple of the account's average (0 disables)")
+	flag.DurationVar(&anomalyWindow, "anomaly-window", anomalyWindow, "trailing window for the account average")
+	flag.IntVar(&anomalyMinHistory, "anomaly-min-history", anomalyMinHistory, "prior transactions an account needs before the anomaly rule applies")
+	flag.Float64Var(&outlierDeviations, "outlier-deviations", outlierDeviations, "escalate amounts more than this many standard deviations above the merchant's mean (0 disables)")
+	flag.IntVar(&outlierMinSamples, "outlier-min-samples", outlierMinSamples, "recorded transactions a merchant needs before the outlier rule applies")
+	flag.IntVar(&outlierWindow, "outlier-window", outlierWindow, "approximate number of recent transactions a merchant's outlier statistics reflect")
+	flag.IntVar(&outlierMaxMerchants, "outlier-max-merchants", outlierMaxMerchants, "merchants tracked per tenant for the outlier rule; the least recently seen are forgotten beyond it (0 for no limit)")
+	flag.BoolVar(&degradeOnStoreError, "degrade-on-store-error", degradeOnStoreError, "score without the history-based rules when the store fails, marking the decision degraded, instead of answering 500")
+	flag.DurationVar(&idempotencyTTL, "idempotency-ttl", idempotencyTTL, "how long Idempotency-Key decisions are replayed")
+	flag.BoolVar(&dedupEnabled, "dedup", dedupEnabled, "return the earlier decision for a /risk payload repeated without an Idempotency-Key")
+	flag.DurationVar(&dedupWindow, "dedup-window", dedupWindow, "how long a payload counts as a duplicate when -dedup is set")
+	flag.BoolVar(&uniqueIDs, "unique-ids", uniqueIDs, "reject a transaction id reused within -dedup-window with 409")
+	flag.Float64Var(&rateLimitRPS, "rate-limit", rateLimitRPS, "requests per second allowed per client (0 disables)")
+	flag.IntVar(&rateLimitBurst, "rate-burst", rateLimitBurst, "burst size for the per-client rate limit")
+	flag.BoolVar(&problemJSON, "problem-json", problemJSON, "write errors as RFC 7807 application/problem+json instead of {\"error\", \"status\"}")
+	flag.BoolVar(&envelopeEnabled, "envelope", envelopeEnabled, "wrap JSON responses as {\"data\"|\"error\": ..., \"meta\": {request_id, timestamp, version}}")
+	flag.IntVar(&gzipMinSize, "gzip-min-size", gzipMinSize, "minimum response size in bytes to gzip")
+	flag.DurationVar(&requestTimeout, "request-timeout", requestTimeout, "maximum time to handle a request")
+	flag.IntVar(&maxInFlight, "max-in-flight", maxInFlight, "maximum requests handled at once (0 disables)")
+	flag.DurationVar(&inFlightWait, "in-flight-wait", inFlightWait, "how long a request waits for a free slot before a 503")
+	flag.DurationVar(&serverReadHeaderTimeout, "read-header-timeout", serverReadHeaderTimeout, "maximum time to read request headers")
+	flag.DurationVar(&serverReadTimeout, "read-timeout", serverReadTimeout, "maximum time to read a whole request, body included")
+	flag.DurationVar(&serverWriteTimeout, "write-timeout", serverWriteTimeout, "maximum time from the end of the request headers to the end of the response")
+	flag.DurationVar(&serverIdleTimeout, "idle-timeout", serverIdleTimeout, "how long an idle keep-alive connection stays open")
+	flag.Int64Var(&maxBodyBytes, "max-body-bytes", maxBodyBytes, "maximum request body size in bytes")
+	flag.Int64Var(&maxUploadBytes, "max-upload-bytes", maxUploadBytes, "maximum CSV upload size in bytes for /risk/upload")
+	flag.IntVar(&maxBatchSize, "max-batch", maxBatchSize, "maximum transactions per /risk/batch request")
+	flag.IntVar(&maxJobSize, "max-job", maxJobSize, "maximum transactions per /jobs submission")
+	flag.Int64Var(&maxJobBytes, "max-job-bytes", maxJobBytes, "maximum /jobs request body size in bytes")
+	flag.IntVar(&jobWorkers, "job-workers", jobWorkers, "goroutines processing /jobs submissions")
+	flag.IntVar(&jobQueueSize, "job-queue", jobQueueSize, "submitted jobs that may wait for a worker")
+	flag.DurationVar(&jobTTL, "job-ttl", jobTTL, "how long a finished job's results can be fetched")
+	flag.Func("metrics-backends", "comma-separated metrics backends to update: prometheus (/metrics) and simple (/metrics/simple) (default both)", func(s string) (err error) {
+		metricsBackends, err = parseMetricsBackends(s)
+		return err
+	})
+	flag.BoolVar(&debugEnabled, "debug", debugEnabled, "enable GET /debug/info, which shows the effective configuration and runtime statistics")
+	flag.BoolVar(&simulateEnabled, "simulate", simulateEnabled, "enable POST /simulate, which records synthetic transactions (development only)")
+	logLevel := flag.String("log-level", "info", "minimum log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log output format: text or json")
+	flag.Func("log-sample-rate", "fraction (0 to 1) of non-HIGH decisions logged at info level; HIGH decisions always are (default 0)", func(s string) (err error) {
+		logSampleRate, err = parseSampleRate(s)
+		return err
+	})
+	flag.Parse()
 
-	// --- NEW LOGIC START ---
+	l, err := newLogger(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		fatal("configure logging", err)
+	}
+	logger = l
 
-	// 1. The "Coffee Shop Anomaly" Rule
-	// If you spend over $500 at a coffee shop, that is suspicious.
-	if t.Merchant == "Starbucks" && t.Amount > 500 {
-		riskLevel = "HIGH"
+	if err := settings.validate(); err != nil {
+		fatal("invalid settings", err)
+	}
+	if rateLimitRPS > 0 && rateLimitBurst < 1 {
+		fatal("rate limit", errors.New("-rate-burst must be at least 1"))
+	}
 
-	// 2. The "Tech Store Exception" Rule
-	// Spending $2,000 at Apple is normal, so we keep it LOW (overriding the default $1000 rule).
-	} else if t.Merchant == "Apple Store" && t.Amount < 5000 {
-		riskLevel = "LOW"
+	if *ratesPath != "" {
+		r, err := loadRates(*ratesPath)
+		if err != nil {
+			fatal("load rates", err)
+		}
+		rates = r
+	}
+	if *categoriesPath != "" {
+		c, err := loadCategoryThresholds(*categoriesPath)
+		if err != nil {
+			fatal("load categories", err)
+		}
+		categoryThresholds = c
+	}
+	if *merchantThresholdsPath != "" {
+		m, err := loadMerchantThresholds(*merchantThresholdsPath)
+		if err != nil {
+			fatal("load merchant thresholds", err)
+		}
+		merchantThresholds = m
+	}
+	if (*currencyThresholdsPath != "") != (thresholdMode == thresholdModeNative) {
+		fatal("currency thresholds", errors.New("-currency-thresholds and -threshold-mode native must be set together"))
+	}
+	if *currencyThresholdsPath != "" {
+		c, err := loadCurrencyThresholds(*currencyThresholdsPath)
+		if err != nil {
+			fatal("load currency thresholds", err)
+		}
+		thresholdsByCurrency = c
+	}
+	if *merchantFloorPath != "" {
+		f, err := loadMerchantFloor(*merchantFloorPath)
+		if err != nil {
+			fatal("load merchant floors", err)
+		}
+		merchantFloor = f
+	}
+	if *accountLimitsPath != "" {
+		l, err := loadAccountLimits(*accountLimitsPath)
+		if err != nil {
+			fatal("load account limits", err)
+		}
+		accountDailyLimits = l
+	}
+	if *countriesList != "" && *countriesPath != "" {
+		fatal("high-risk countries", errors.New("-high-risk-countries and -high-risk-countries-file are mutually exclusive"))
+	}
+	if *countriesList != "" {
+		c, err := parseCountries(*countriesList)
+		if err != nil {
+			fatal("parse high-risk countries", err)
+		}
+		highRiskCountries = c
+	}
+	if *countriesPath != "" {
+		c, err := loadCountries(*countriesPath)
+		if err != nil {
+			fatal("load high-risk countries", err)
+		}
+		highRiskCountries = c
+	}
+	if *watchlistPath != "" {
+		wl, err := loadWatchlist(*watchlistPath)
+		if err != nil {
+			fatal("load watchlist", err)
+		}
+		watchlist = wl
+		logger.Info("loaded watchlist", "entries", len(watchlist), "path", *watchlistPath)
+	}
+	if *knownMerchantsPath != "" {
+		m, err := loadKnownMerchants(*knownMerchantsPath)
+		if err != nil {
+			fatal("load known merchants", err)
+		}
+		knownMerchants = m
+		logger.Info("loaded known merchants", "entries", len(knownMerchants), "path", *knownMerchantsPath)
+	}
+	if strictMerchants && *knownMerchantsPath == "" {
+		fatal("strict merchants", errors.New("-strict-merchants requires -known-merchants"))
+	}
+	loc, err := time.LoadLocation(*reportTZ)
+	if err != nil {
+		fatal("load report timezone", err)
+	}
+	reportLocation = loc
+	if offHoursEnabled && *businessTZ == "" {
+		fatal("off-hours", errors.New("-off-hours requires -business-tz"))
+	}
+	if *businessTZ != "" {
+		if businessLocation, err = time.LoadLocation(*businessTZ); err != nil {
+			fatal("load business timezone", err)
+		}
+	}
+	if apiKeys, err = parseAPIKeys(os.Getenv("API_KEYS")); err != nil {
+		fatal("parse API_KEYS", err)
+	}
+	if len(apiKeys) == 0 {
+		logger.Warn("API_KEYS is empty; all authenticated endpoints will return 401")
+	}
+	tenantIDs, err := parseTenantIDs(*tenantList)
+	if err != nil {
+		fatal("parse tenants", err)
+	}
+	for _, k := range apiKeys {
+		if k.tenant != "" {
+			tenantIDs = append(tenantIDs, k.tenant)
+		}
+	}
 
-	// 3. The Standard Default Rules
-	} else if t.Amount > 10000 {
-		riskLevel = "HIGH"
-	} else if t.Amount > 1000 {
-		riskLevel = "MEDIUM"
+	if *rulesetDir != "" {
+		sets, err := loadRuleSets(*rulesetDir)
+		if err != nil {
+			fatal("load rule sets", err)
+		}
+		alternateRuleSets = sets
+		logger.Info("loaded alternate rule sets", "count", len(sets), "path", *rulesetDir)
+		for name, rs := range sets {
+			logRuleWarnings(logger.With("ruleset", name), defaultTenant, rs.Get())
+		}
 	}
-	// --- NEW LOGIC END ---
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"risk_level": riskLevel})
-}
+	var closers []func() error
+	defer func() {
+		for _, c := range closers {
+			c()
+		}
+	}()
+	// RULES_JSON, when set, replaces the -rules file for tenants without
+	// their own rules in -tenant-rules-dir.
+	envRules, err := rulesFromEnv()
+	if err != nil {
+		fatal("load rules", err)
+	}
+	// newTenant opens a tenant's store and loads its rules and thresholds.
+	newTenant := func(id string) *Tenant {
+		var store Store
+		if *dbPath != "" {
+			path := tenantDBPath(*dbPath, id)
+			db, err := OpenSQLiteStore(path)
+			if err != nil {
+				fatal("open database", err)
+			}
+			closers = append(closers, db.Close)
+			dependencies.register("db:"+id, db)
+			store = db
+			logger.Info("using SQLite store", "tenant", id, "path", path)
+		} else {
+			store = NewMemoryStore(*historySize)
+		}
+		rs := &ActiveRules{}
+		loaded, source, err := loadTenantRules(rs, tenantRulesPath(*tenantRulesDir, id, ""), envRules, *rulesPath)
+		if err != nil {
+			fatal("load rules", err)
+		}
+		logger.Info("loaded rules", "tenant", id, "count", len(loaded), "source", source)
+		logRuleWarnings(logger, id, loaded)
+		tn := NewTenant(id, store, rs, NewConfig(settings))
+		n, err := tn.rebuildMerchantStats(context.Background())
+		if err != nil {
+			fatal("rebuild merchant statistics", err)
+		}
+		logger.Info("rebuilt merchant statistics", "tenant", id, "debits", n, "merchants", tn.merchantStats.len())
+		return tn
+	}
+	tenants := NewTenants(newTenant(defaultTenant))
+	for _, id := range tenantIDs {
+		if _, ok := tenants.Get(id); !ok {
+			tenants.Add(newTenant(id))
+		}
+	}
+	api := NewServer(tenants)
+	if *webhookURL != "" {
+		webhook = newWebhookNotifier(*webhookURL)
+	}
+	if *kafkaBrokers != "" {
+		p, err := openKafkaProducer(*kafkaBrokers)
+		if err != nil {
+			fatal("open kafka producer", err)
+		}
+		publisher = newDecisionPublisher(p, *kafkaTopic, *kafkaBuffer)
+		defer publisher.Close()
+		if pp, ok := p.(pinger); ok {
+			dependencies.register("kafka", pp)
+		}
+		logger.Info("publishing decisions to kafka", "brokers", *kafkaBrokers, "topic", *kafkaTopic)
+	}
 
-func main() {
-	http.HandleFunc("/risk", checkRisk)
+	corsOrigins = parseOrigins(*corsList)
+	signingSecret = []byte(os.Getenv("SIGNING_SECRET"))
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, api)
+
+	addr := resolveAddr(*addrFlag)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fatal("listen", err)
+	}
+	srv := newHTTPServer(withRequestID(envelopeMiddleware(recoverMiddleware(corsMiddleware(timeoutMiddleware(concurrencyLimit(mux)))))))
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fatal("tls", errors.New("-tls-cert and -tls-key must be set together"))
+	}
+	mode := "http"
+	if *tlsCert != "" {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		mode = "https"
+	}
+
+	if *grpcAddr != "" {
+		gln, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fatal("grpc listen", err)
+		}
+		var opts []grpc.ServerOption
+		if *tlsCert != "" {
+			creds, err := credentials.NewServerTLSFromFile(*tlsCert, *tlsKey)
+			if err != nil {
+				fatal("grpc tls", err)
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+		gs := newGRPCServer(api, opts...)
+		go func() {
+			if err := gs.Serve(gln); err != nil {
+				logger.Error("grpc server failed", "error", err)
+			}
+		}()
+		defer stopGRPC(gs)
+		logger.Info("starting grpc server", "addr", *grpcAddr, "mode", mode)
+	}
 
-	log.Println("Starting server on :8080...")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ready.Store(true)
+	logger.Info("starting server", "addr", addr, "mode", mode)
+	if err := serve(ctx, srv, ln, *tlsCert, *tlsKey); err != nil {
+		fatal("serve", err)
 	}
+	logger.Info("server stopped")
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
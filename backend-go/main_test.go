@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey is the API key accepted by the mux from newTestAPI.
+const testKey = "test-key"
+
+// noon is a business-hours instant tests pin the clock to.
+var noon = time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// setVar sets *p to v until the test ends.
+func setVar[T any](t *testing.T, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// setClock stops the clock at at until the test ends.
+func setClock(t *testing.T, at time.Time) {
+	t.Helper()
+	setVar(t, &now, func() time.Time { return at })
+}
+
+// newTestTenant returns a tenant with in-memory history, the sample rules and
+// the default settings.
+func newTestTenant(t *testing.T, id string) *Tenant {
+	t.Helper()
+	rs := &ActiveRules{}
+	if _, err := rs.Load("rules.json"); err != nil {
+		t.Fatal(err)
+	}
+	return NewTenant(id, NewMemoryStore(defaultHistorySize), rs, NewConfig(defaultSettings))
+}
+
+// newTestAPI returns every route mounted for one default tenant, accepting
+// testKey, with the clock stopped at noon.
+func newTestAPI(t *testing.T) (http.Handler, *Tenant) {
+	t.Helper()
+	setClock(t, noon)
+	setVar(t, &apiKeys, []apiKey{{key: []byte(testKey)}})
+	setVar(t, &limiters, newLimiterSet())
+	tn := newTestTenant(t, defaultTenant)
+	mux := http.NewServeMux()
+	registerRoutes(mux, NewServer(NewTenants(tn)))
+	return mux, tn
+}
+
+// do serves a request authenticated with testKey and returns the response.
+// header lists extra header names and values in pairs.
+func do(h http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("X-API-Key", testKey)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+// decode unmarshals a JSON response body.
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestResolveAddrPrecedence(t *testing.T) {
+	t.Setenv("ADDR", "")
+	if got := resolveAddr(""); got != defaultAddr {
+		t.Errorf("default: got %q, want %q", got, defaultAddr)
+	}
+	t.Setenv("ADDR", ":9090")
+	if got := resolveAddr(""); got != ":9090" {
+		t.Errorf("env: got %q, want :9090", got)
+	}
+	if got := resolveAddr("127.0.0.1:7070"); got != "127.0.0.1:7070" {
+		t.Errorf("flag over env: got %q, want 127.0.0.1:7070", got)
+	}
+}
+
+func TestServerListensOnResolvedAddr(t *testing.T) {
+	t.Setenv("ADDR", "127.0.0.1:0")
+	ln, err := net.Listen("tcp", resolveAddr(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(healthz))
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	host, _, _ := net.SplitHostPort(ln.Addr().String())
+	if host != "127.0.0.1" {
+		t.Fatalf("listening on %s, want 127.0.0.1", ln.Addr())
+	}
+	resp, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, &http.Server{Handler: http.HandlerFunc(healthz)}, ln, "", "") }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("serve returned %v", err)
+	}
+	if c, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		c.Close()
+		t.Fatal("new connections still accepted after shutdown")
+	}
+}
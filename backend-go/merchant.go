@@ -0,0 +1,48 @@
+package main
+
+import "strings"
+
+// normalizeMerchant trims surrounding whitespace and lower-cases a merchant
+// name so rule matching is case-insensitive. It uses Unicode's
+// locale-independent case mapping: "İ" (dotted capital I) becomes "i", while
+// Turkish dotless "ı" is left as-is and does not match "i". The original
+// string is never modified; callers compare normalized copies.
+func normalizeMerchant(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// sameMerchant reports whether two merchant names match after normalization.
+func sameMerchant(a, b string) bool {
+	return normalizeMerchant(a) == normalizeMerchant(b)
+}
+
+// editDistance returns the Levenshtein distance between a and b: the fewest
+// single-rune insertions, deletions and substitutions turning one into the
+// other.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// nearMerchant reports whether two merchant names differ after normalization
+// but are within maxDistance edits of each other.
+func nearMerchant(a, b string, maxDistance int) bool {
+	na, nb := normalizeMerchant(a), normalizeMerchant(b)
+	return na != nb && editDistance(na, nb) <= maxDistance
+}
@@ -0,0 +1,102 @@
+package main
+
+import "testing"
+
+func TestSameMerchant(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"Starbucks", "starbucks", true},
+		{"Starbucks", "  STARBUCKS\t", true},
+		{"Apple Store", "apple store ", true},
+		{"ÉCLAIR", "éclair", true},
+		// Turkish-I: dotted capital İ folds to plain i, while dotless ı is a
+		// different letter and must not match i.
+		{"İSTANBUL", "istanbul", true},
+		{"ıstanbul", "istanbul", false},
+		{"Starbucks", "Starbucks Reserve", false},
+	} {
+		if got := sameMerchant(tc.a, tc.b); got != tc.want {
+			t.Errorf("sameMerchant(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizedMerchantMatchesRules(t *testing.T) {
+	rs, err := loadRules("rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := rs.Evaluate(Transaction{Merchant: "  sTaRbUcKs ", Amount: dollars(600)}); !ok || d.RiskLevel != "HIGH" {
+		t.Fatalf("mixed-case Starbucks: got %+v, %v", d, ok)
+	}
+	tx := Transaction{Merchant: "Starbucks Reserve", Amount: dollars(600)}
+	if _, ok := rs.Evaluate(tx); ok {
+		t.Fatal("a different merchant matched the Starbucks rule")
+	}
+	if d := applyThresholds(tx, defaultSettings); d.RiskLevel != "LOW" {
+		t.Fatalf("default thresholds: got %s", d.RiskLevel)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"starbucks", "starbucks", 0},
+		{"starbucks", "starbuks", 1},
+		{"starbucks", "starbukcs", 2},
+		{"starbucks", "walmart", 8},
+		{"café", "cafe", 1},
+		{"", "abc", 3},
+	} {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestFuzzyMerchantRule(t *testing.T) {
+	rs, err := parseRules([]byte(`[
+		{"merchant": "Starbucks", "fuzzy": true, "operator": ">", "amount": 500, "risk_level": "HIGH", "reason": "coffee"},
+		{"merchant": "Apple Store", "operator": ">", "amount": 500, "risk_level": "HIGH", "reason": "apple"},
+		{"merchant": "Amazon", "fuzzy": true, "max_distance": 2, "operator": ">", "amount": 500, "risk_level": "HIGH", "reason": "amazon"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		merchant, reason string
+	}{
+		{"Starbucks", "coffee"},
+		{"Starbuks", `coffee (merchant "Starbuks" resembles "Starbucks")`},
+		{" STARBUKS ", `coffee (merchant "STARBUKS" resembles "Starbucks")`},
+		{"Walmart", ""},
+		{"Starbukcs", ""},
+		// Apple Store did not opt in.
+		{"Aple Store", ""},
+		{"Amazn", `amazon (merchant "Amazn" resembles "Amazon")`},
+		{"Amzn", `amazon (merchant "Amzn" resembles "Amazon")`},
+		{"Amz", ""},
+	} {
+		d, ok := rs.Evaluate(Transaction{Merchant: tc.merchant, Amount: dollars(600)})
+		if ok != (tc.reason != "") || d.Reason != tc.reason {
+			t.Errorf("%q: matched %v with %q, want %q", tc.merchant, ok, d.Reason, tc.reason)
+		}
+	}
+}
+
+func TestFuzzyRuleValidation(t *testing.T) {
+	for _, body := range []string{
+		`[{"fuzzy": true, "operator": ">", "amount": 1, "risk_level": "LOW"}]`,
+		`[{"merchant": "m", "max_distance": 2, "operator": ">", "amount": 1, "risk_level": "LOW"}]`,
+		`[{"merchant": "m", "fuzzy": true, "max_distance": -1, "operator": ">", "amount": 1, "risk_level": "LOW"}]`,
+	} {
+		if _, err := parseRules([]byte(body)); err == nil {
+			t.Errorf("%s: accepted", body)
+		}
+	}
+}
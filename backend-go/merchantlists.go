@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Merchant list names, as used in /merchants/{list}.
+const (
+	listAllow = "allow"
+	listDeny  = "deny"
+)
+
+// errListConflict is returned when a merchant is added to one list while it
+// is on the other.
+var errListConflict = errors.New("merchant is already on the other list")
+
+// MerchantLists holds the analyst-managed allow and deny lists. Entries are
+// keyed by normalized merchant name and map to the name as submitted. It is
+// safe for concurrent use.
+type MerchantLists struct {
+	mu    sync.RWMutex
+	lists map[string]map[string]string
+}
+
+func NewMerchantLists() *MerchantLists {
+	return &MerchantLists{lists: map[string]map[string]string{listAllow: {}, listDeny: {}}}
+}
+
+func otherList(list string) string {
+	if list == listAllow {
+		return listDeny
+	}
+	return listAllow
+}
+
+// Add puts merchant on list. Adding a merchant already on the list is a
+// no-op; adding one that is on the other list fails with errListConflict.
+func (m *MerchantLists) Add(list, merchant string) error {
+	key := normalizeMerchant(merchant)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.lists[otherList(list)][key]; ok {
+		return errListConflict
+	}
+	if _, ok := m.lists[list][key]; !ok {
+		m.lists[list][key] = strings.TrimSpace(merchant)
+	}
+	return nil
+}
+
+// Remove takes merchant off list, if present.
+func (m *MerchantLists) Remove(list, merchant string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.lists[list], normalizeMerchant(merchant))
+}
+
+// Lookup returns the list merchant is on, or "" if neither.
+func (m *MerchantLists) Lookup(merchant string) string {
+	key := normalizeMerchant(merchant)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, list := range []string{listDeny, listAllow} {
+		if _, ok := m.lists[list][key]; ok {
+			return list
+		}
+	}
+	return ""
+}
+
+// MerchantListsView is the body of GET /merchants.
+type MerchantListsView struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// View returns both lists, sorted.
+func (m *MerchantLists) View() MerchantListsView {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := func(list string) []string {
+		out := make([]string, 0, len(m.lists[list]))
+		for _, name := range m.lists[list] {
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	return MerchantListsView{Allow: names(listAllow), Deny: names(listDeny)}
+}
+
+// applyMerchantLists decides t outright when its merchant is on one of the
+// tenant's allow or deny lists. ok is false otherwise.
+func (tn *Tenant) applyMerchantLists(t Transaction) (d Decision, ok bool) {
+	switch tn.merchantLists.Lookup(t.Merchant) {
+	case listDeny:
+		return Decision{RiskLevel: "HIGH", Reason: fmt.Sprintf("merchant %q is deny-listed", t.Merchant)}, true
+	case listAllow:
+		return Decision{RiskLevel: "LOW", Reason: fmt.Sprintf("merchant %q is allow-listed", t.Merchant)}, true
+	}
+	return Decision{}, false
+}
+
+// merchantRequest is the body of POST and DELETE /merchants/{allow,deny}.
+type merchantRequest struct {
+	Merchant string `json:"merchant"`
+}
+
+// merchantListChange is the audit record of a merchant list edit.
+type merchantListChange struct {
+	List     string `json:"list"`
+	Merchant string `json:"merchant"`
+}
+
+// merchants serves GET /merchants for the calling tenant.
+func (s *Server) merchants(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.tenant(r.Context()).merchantLists.View())
+}
+
+// merchantList serves POST (add) and DELETE (remove) for one of the calling
+// tenant's lists. Both are idempotent.
+func (s *Server) merchantList(list string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		var req merchantRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		if strings.TrimSpace(req.Merchant) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "merchant is required")
+			return
+		}
+		lists := s.tenant(r.Context()).merchantLists
+		if r.Method == http.MethodDelete {
+			lists.Remove(list, req.Merchant)
+			logFor(r.Context()).Info("merchant list entry removed", "list", list, "merchant", req.Merchant)
+			auditLog.Record(r, auditMerchantRemove, merchantListChange{List: list, Merchant: req.Merchant})
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err := lists.Add(list, req.Merchant); err != nil {
+			writeError(w, http.StatusConflict, fmt.Sprintf("%q is on the %s list; remove it there first", req.Merchant, otherList(list)))
+			return
+		}
+		logFor(r.Context()).Info("merchant list entry added", "list", list, "merchant", req.Merchant)
+		auditLog.Record(r, auditMerchantAdd, merchantListChange{List: list, Merchant: req.Merchant})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lists.View())
+	}
+}
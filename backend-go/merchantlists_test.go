@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMerchantDenyList(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	body := `{"amount": 10, "merchant": "Corner Shop"}`
+	for i := 0; i < 2; i++ {
+		if rec := do(api, "POST", "/merchants/deny", `{"merchant": " CORNER shop"}`); rec.Code != http.StatusOK {
+			t.Fatalf("add %d: status %d: %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	if v := decode[MerchantListsView](t, do(api, "GET", "/merchants", "")); len(v.Deny) != 1 || len(v.Allow) != 0 {
+		t.Fatalf("GET /merchants: %+v", v)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "HIGH" {
+		t.Fatalf("deny-listed merchant: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+
+	if rec := do(api, "DELETE", "/merchants/deny", `{"merchant": "corner shop"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("remove: status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "LOW" {
+		t.Fatalf("after removal: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestMerchantAllowList(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	if rec := do(api, "POST", "/merchants/allow", `{"merchant": "Payroll Co"}`); rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 2500, "merchant": "payroll co"}`)); res.RiskLevel != "LOW" {
+		t.Fatalf("allow-listed merchant: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestMerchantListConflict(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	do(api, "POST", "/merchants/allow", `{"merchant": "Corner Shop"}`)
+	if rec := do(api, "POST", "/merchants/deny", `{"merchant": "corner shop"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("status %d, want 409", rec.Code)
+	}
+	if v := decode[MerchantListsView](t, do(api, "GET", "/merchants", "")); len(v.Deny) != 0 || len(v.Allow) != 1 {
+		t.Fatalf("rejected add changed the lists: %+v", v)
+	}
+	if rec := do(api, "POST", "/merchants/deny", `{"merchant": ""}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("empty merchant: status %d, want 422", rec.Code)
+	}
+}
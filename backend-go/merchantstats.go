@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Merchant outlier rule settings: a debit more than outlierDeviations
+// standard deviations above its merchant's mean amount is escalated to at
+// least MEDIUM, once the merchant has outlierMinSamples recorded debits. A
+// deviation count of zero disables the rule. outlierWindow is roughly how
+// many recent debits a merchant's statistics reflect, and outlierMaxMerchants
+// caps how many merchants are tracked per tenant.
+var (
+	outlierDeviations   = 3.0
+	outlierMinSamples   = 30
+	outlierWindow       = 1000
+	outlierMaxMerchants = 10000
+)
+
+// RollingStats keeps the running count, mean and variance of a stream of
+// amounts without storing them. The first window values are weighted equally,
+// as in Welford's online algorithm; after that each value has weight
+// 1/window and older ones decay exponentially, so the statistics follow a
+// merchant whose amounts drift. It is safe for concurrent use.
+type RollingStats struct {
+	mu       sync.Mutex
+	window   int
+	n        int
+	mean     float64
+	variance float64
+	seen     uint64 // merchantStats.clock at the last Add
+}
+
+// NewRollingStats returns empty stats that decay after window values. A
+// window below one never decays.
+func NewRollingStats(window int) *RollingStats {
+	return &RollingStats{window: window}
+}
+
+// Add records x.
+func (s *RollingStats) Add(x float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(x)
+}
+
+// add is Add with s.mu held.
+func (s *RollingStats) add(x float64) {
+	s.n++
+	w := 1 / float64(s.n)
+	if s.window > 0 && s.n > s.window {
+		w = 1 / float64(s.window)
+	}
+	delta := x - s.mean
+	s.mean += w * delta
+	s.variance = (1 - w) * (s.variance + w*delta*delta)
+}
+
+// Stats returns the number of values recorded, their weighted mean and their
+// weighted standard deviation.
+func (s *RollingStats) Stats() (n int, mean, stddev float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n, s.mean, math.Sqrt(s.variance)
+}
+
+// merchantStats holds a RollingStats of USD debit amounts per merchant,
+// forgetting the least recently seen merchants beyond outlierMaxMerchants.
+// It is safe for concurrent use.
+type merchantStats struct {
+	mu    sync.Mutex
+	stats map[string]*RollingStats
+	clock uint64
+}
+
+func newMerchantStats() *merchantStats {
+	return &merchantStats{stats: make(map[string]*RollingStats)}
+}
+
+// add records amount for merchant.
+func (m *merchantStats) add(merchant string, amount float64) {
+	key := normalizeMerchant(merchant)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock++
+	s, ok := m.stats[key]
+	if !ok {
+		if outlierMaxMerchants > 0 && len(m.stats) >= outlierMaxMerchants {
+			m.evict()
+		}
+		s = NewRollingStats(outlierWindow)
+		m.stats[key] = s
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = m.clock
+	s.add(amount)
+}
+
+// evict forgets the least recently seen tenth of the merchants, so the sort
+// is paid once per many new merchants rather than on each. m.mu must be held.
+func (m *merchantStats) evict() {
+	type entry struct {
+		key  string
+		seen uint64
+	}
+	entries := make([]entry, 0, len(m.stats))
+	for k, s := range m.stats {
+		s.mu.Lock()
+		entries = append(entries, entry{k, s.seen})
+		s.mu.Unlock()
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seen < entries[j].seen })
+	for _, e := range entries[:max(len(entries)/10, 1)] {
+		delete(m.stats, e.key)
+	}
+}
+
+// len returns the number of merchants tracked.
+func (m *merchantStats) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.stats)
+}
+
+// of returns merchant's count, mean and standard deviation. Merchants with no
+// recorded debits have none.
+func (m *merchantStats) of(merchant string) (n int, mean, stddev float64) {
+	m.mu.Lock()
+	s, ok := m.stats[normalizeMerchant(merchant)]
+	m.mu.Unlock()
+	if !ok {
+		return 0, 0, 0
+	}
+	return s.Stats()
+}
+
+// rebuildMerchantStats replays the tenant's stored debits, oldest first, into
+// its merchant statistics, so the outlier rule keeps its baselines across a
+// restart. It is called once at startup, before the tenant serves requests.
+func (tn *Tenant) rebuildMerchantStats(ctx context.Context) (int, error) {
+	n := 0
+	err := tn.store.Rescore(ctx, false, func(r Record) Record {
+		if tn.recordMerchantAmount(r.Transaction) {
+			n++
+		}
+		return r
+	})
+	return n, err
+}
+
+// recordMerchantAmount adds recorded transaction t to its merchant's stats
+// and reports whether it counted. Credits and transactions without a
+// merchant are not counted.
+func (tn *Tenant) recordMerchantAmount(t Transaction) bool {
+	if t.Merchant == "" || isCredit(t) {
+		return false
+	}
+	tn.merchantStats.add(t.Merchant, float64(inUSD(t).Amount))
+	return true
+}
+
+// outlierCheck compares t, already converted to USD, against its merchant's
+// distribution of earlier debits. ok is true when the amount is an outlier.
+func (tn *Tenant) outlierCheck(t Transaction) (d Decision, ok bool) {
+	if outlierDeviations <= 0 || t.Merchant == "" || isCredit(t) {
+		return Decision{}, false
+	}
+	n, mean, stddev := tn.merchantStats.of(t.Merchant)
+	if n < outlierMinSamples || float64(t.Amount) <= mean+outlierDeviations*stddev {
+		return Decision{}, false
+	}
+	return Decision{
+		RiskLevel: "MEDIUM",
+		Reason: fmt.Sprintf("merchant outlier: $%s is more than %g standard deviations above %s's $%s mean",
+			t.Amount, outlierDeviations, t.Merchant, Money(math.Round(mean))),
+	}, true
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRollingStatsMatchesWelford(t *testing.T) {
+	s := NewRollingStats(100)
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		s.Add(x)
+	}
+	if n, mean, stddev := s.Stats(); n != 8 || mean != 5 || math.Abs(stddev-2) > 1e-9 {
+		t.Fatalf("got n %d, mean %v, stddev %v; want 8, 5, 2", n, mean, stddev)
+	}
+}
+
+func TestRollingStatsDecays(t *testing.T) {
+	s := NewRollingStats(100)
+	for i := 0; i < 1000; i++ {
+		s.Add(100)
+	}
+	for i := 0; i < 500; i++ {
+		s.Add(1000)
+	}
+	// After five windows at the new level, the old one has all but gone.
+	if _, mean, _ := s.Stats(); mean < 990 {
+		t.Fatalf("mean %v still follows the old amounts", mean)
+	}
+}
+
+func TestRollingStatsConcurrent(t *testing.T) {
+	s := NewRollingStats(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				s.Add(float64(j % 2))
+			}
+		}()
+	}
+	wg.Wait()
+	if n, mean, _ := s.Stats(); n != 8000 || math.Abs(mean-0.5) > 1e-9 {
+		t.Fatalf("got n %d, mean %v", n, mean)
+	}
+}
+
+func TestMerchantOutlierFlagged(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	// A stable distribution around $50, from different accounts so velocity
+	// and account anomalies stay out of it.
+	for i := 0; i < outlierMinSamples; i++ {
+		tx := Transaction{Merchant: "Corner Shop", Amount: dollars(int64(45 + i%11)), AccountID: fmt.Sprintf("acct-%d", i)}
+		if res := processAt(t, tn, noon.Add(time.Duration(i)*time.Hour), tx); res.RiskLevel != "LOW" {
+			t.Fatalf("transaction %d: got %s (%s)", i, res.RiskLevel, res.Reason)
+		}
+	}
+	for _, tc := range []struct {
+		amount Money
+		want   string
+	}{
+		{dollars(55), "LOW"},
+		{dollars(57), "LOW"},
+		{dollars(400), "MEDIUM"},
+	} {
+		d, err := tn.evaluate(t.Context(), Transaction{Merchant: "corner shop", Amount: tc.amount, AccountID: "new"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.RiskLevel != tc.want || (tc.want == "MEDIUM") != strings.HasPrefix(d.Reason, "merchant outlier:") {
+			t.Errorf("$%s: got %s (%s), want %s", tc.amount, d.RiskLevel, d.Reason, tc.want)
+		}
+	}
+	// Another merchant has no baseline yet.
+	if d, _ := tn.evaluate(t.Context(), Transaction{Merchant: "Bakery", Amount: dollars(400)}); d.RiskLevel != "LOW" {
+		t.Errorf("merchant without samples: got %s (%s)", d.RiskLevel, d.Reason)
+	}
+}
+
+func TestMerchantStatsEvictsLeastRecent(t *testing.T) {
+	setVar(t, &outlierMaxMerchants, 10)
+	m := newMerchantStats()
+	for i := 0; i < 10; i++ {
+		m.add(fmt.Sprintf("m%d", i), 1)
+	}
+	m.add("m0", 1)
+	m.add("m10", 1)
+	if n := m.len(); n != 10 {
+		t.Fatalf("%d merchants tracked, want 10", n)
+	}
+	if n, _, _ := m.of("m1"); n != 0 {
+		t.Fatal("least recently seen merchant kept")
+	}
+	for _, name := range []string{"m0", "m10"} {
+		if n, _, _ := m.of(name); n == 0 {
+			t.Fatalf("%s evicted", name)
+		}
+	}
+}
+
+func TestRebuildMerchantStats(t *testing.T) {
+	setClock(t, noon)
+	store := NewMemoryStore(defaultHistorySize)
+	for i := 0; i < outlierMinSamples; i++ {
+		if err := store.Append(t.Context(), Record{Transaction: Transaction{Merchant: "Corner Shop", Amount: dollars(int64(45 + i%11))}, RiskLevel: "LOW", Timestamp: noon}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Append(t.Context(), Record{Transaction: Transaction{Merchant: "Corner Shop", Amount: dollars(90000), Type: "credit"}, RiskLevel: "LOW", Timestamp: noon})
+
+	// A restarted tenant over the same store.
+	tn := newTestTenant(t, defaultTenant)
+	tn.store = store
+	n, err := tn.rebuildMerchantStats(t.Context())
+	if err != nil || n != outlierMinSamples {
+		t.Fatalf("rebuilt from %d debits (%v), want %d", n, err, outlierMinSamples)
+	}
+	if d, _ := tn.evaluate(t.Context(), Transaction{Merchant: "Corner Shop", Amount: dollars(400)}); !strings.HasPrefix(d.Reason, "merchant outlier:") {
+		t.Fatalf("after rebuild: got %s (%s)", d.RiskLevel, d.Reason)
+	}
+}
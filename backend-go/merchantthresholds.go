@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// amountThreshold replaces the global MEDIUM and HIGH amount thresholds for
+// one merchant or currency.
+type amountThreshold struct {
+	Medium Money `json:"medium"`
+	High   Money `json:"high"`
+}
+
+// merchantThresholds maps normalized merchant names to their own USD
+// thresholds. Merchants not listed are judged against the configured
+// Settings.
+var merchantThresholds = map[string]amountThreshold{}
+
+// loadMerchantThresholds reads a JSON object of merchant name to
+// {"medium": ..., "high": ...} from path.
+func loadMerchantThresholds(path string) (map[string]amountThreshold, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read merchant thresholds: %w", err)
+	}
+	var raw map[string]amountThreshold
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse merchant thresholds: %w", err)
+	}
+	m := make(map[string]amountThreshold, len(raw))
+	for name, th := range raw {
+		if th.Medium <= 0 || th.High <= 0 {
+			return nil, fmt.Errorf("merchant %q: thresholds must be positive", name)
+		}
+		if th.Medium >= th.High {
+			return nil, fmt.Errorf("merchant %q: medium must be less than high", name)
+		}
+		m[normalizeMerchant(name)] = th
+	}
+	return m, nil
+}
+
+// forMerchant returns s with the thresholds of merchant substituted when it
+// has its own.
+func (s Settings) forMerchant(merchant string) Settings {
+	if th, ok := merchantThresholds[normalizeMerchant(merchant)]; ok {
+		s.MediumThreshold, s.HighThreshold = th.Medium, th.High
+	}
+	return s
+}
+
+// merchantFloor maps normalized merchant names to the lowest risk level their
+// transactions may be given. Merchants not listed have no floor.
+var merchantFloor = map[string]string{}
+
+// loadMerchantFloor reads a JSON object of merchant name to minimum risk
+// level from path.
+func loadMerchantFloor(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read merchant floors: %w", err)
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse merchant floors: %w", err)
+	}
+	m := make(map[string]string, len(raw))
+	for name, level := range raw {
+		if !validLevels[level] {
+			return nil, fmt.Errorf("merchant %q: floor must be LOW, MEDIUM or HIGH", name)
+		}
+		m[normalizeMerchant(name)] = level
+	}
+	return m, nil
+}
+
+// applyMerchantFloor raises d to merchant's floor. It never lowers a level.
+func applyMerchantFloor(d Decision, merchant string) Decision {
+	floor, ok := merchantFloor[normalizeMerchant(merchant)]
+	if !ok || levelRank[d.RiskLevel] >= levelRank[floor] {
+		return d
+	}
+	return Decision{RiskLevel: floor, Reason: fmt.Sprintf("%s; merchant floor %s", d.Reason, floor)}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerchantThresholds(t *testing.T) {
+	setClock(t, noon)
+	setVar(t, &merchantThresholds, map[string]amountThreshold{"hardware hub": {Medium: dollars(100), High: dollars(500)}})
+	tn := newTestTenant(t, defaultTenant)
+	for _, tc := range []struct {
+		merchant string
+		amount   Money
+		want     string
+	}{
+		{"Hardware Hub", dollars(600), "HIGH"},
+		{"HARDWARE HUB ", dollars(200), "MEDIUM"},
+		{"Hardware Hub", dollars(50), "LOW"},
+		{"Corner Shop", dollars(600), "LOW"},
+		{"Corner Shop", dollars(2000), "MEDIUM"},
+	} {
+		d, err := tn.evaluate(t.Context(), Transaction{Merchant: tc.merchant, Amount: tc.amount})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.RiskLevel != tc.want {
+			t.Errorf("%s $%s: got %s (%s), want %s", tc.merchant, tc.amount, d.RiskLevel, d.Reason, tc.want)
+		}
+	}
+}
+
+func TestLoadMerchantThresholds(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) string {
+		t.Helper()
+		path := filepath.Join(dir, "thresholds.json")
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	m, err := loadMerchantThresholds(write(`{" Apple Store": {"medium": 1000, "high": 5000}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th := m["apple store"]; th.Medium != dollars(1000) || th.High != dollars(5000) {
+		t.Fatalf("got %+v", m)
+	}
+	for _, body := range []string{
+		`{"m": {"medium": 500, "high": 500}}`,
+		`{"m": {"medium": 0, "high": 500}}`,
+		`not json`,
+	} {
+		if _, err := loadMerchantThresholds(write(body)); err == nil {
+			t.Errorf("%s: no error", body)
+		}
+	}
+}
+
+func TestMerchantFloor(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &merchantFloor, map[string]string{"lucky casino": "MEDIUM"})
+	for _, tc := range []struct {
+		body, want string
+	}{
+		{`{"amount": 5, "merchant": "Lucky Casino"}`, "MEDIUM"},
+		{`{"amount": 5, "merchant": " LUCKY casino"}`, "MEDIUM"},
+		{`{"amount": 20000, "merchant": "Lucky Casino"}`, "HIGH"},
+		{`{"amount": 5, "merchant": "Corner Shop"}`, "LOW"},
+	} {
+		res := decode[ScoreResult](t, do(api, "POST", "/risk", tc.body))
+		if res.RiskLevel != tc.want {
+			t.Errorf("%s: got %s (%s), want %s", tc.body, res.RiskLevel, res.Reason, tc.want)
+		}
+	}
+	// A final decision, such as an allow-listed merchant's LOW, is floored too.
+	setVar(t, &auditLog, &AuditLog{})
+	do(api, "POST", "/merchants/allow", `{"merchant": "Lucky Casino"}`)
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 5, "merchant": "Lucky Casino"}`)); res.RiskLevel != "MEDIUM" {
+		t.Errorf("allow-listed floored merchant: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestLoadMerchantFloor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "floor.json")
+	os.WriteFile(path, []byte(`{" Lucky Casino ": "MEDIUM"}`), 0o600)
+	m, err := loadMerchantFloor(path)
+	if err != nil || m["lucky casino"] != "MEDIUM" {
+		t.Fatalf("got %v, %v", m, err)
+	}
+	os.WriteFile(path, []byte(`{"m": "SEVERE"}`), 0o600)
+	if _, err := loadMerchantFloor(path); err == nil {
+		t.Fatal("unknown level accepted")
+	}
+}
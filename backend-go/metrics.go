@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics are kept here and written in the Prometheus text exposition format
+// by metricsHandler rather than through client_golang: the service exports a
+// handful of counters and one histogram, which doesn't justify a registry and
+// the dependency tree that comes with it.
+
+var (
+	requestsTotal = newCounterVec("risk_requests_total",
+		"Risk scoring requests by method and response status.", "method", "status")
+	decisionsTotal = newCounterVec("risk_decisions_total",
+		"Risk decisions by level.", "level")
+	webhookDropped = newCounterVec("risk_webhook_dropped_total",
+		"Webhook events dropped without a delivery attempt, by reason.", "reason")
+	kafkaDropped = newCounterVec("risk_kafka_dropped_total",
+		"Decisions not published to Kafka, by reason.", "reason")
+	requestDuration = newHistogram("risk_request_duration_seconds",
+		"Risk scoring request latency in seconds.",
+		[]float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10})
+)
+
+// counterVec is a counter partitioned by a fixed set of label names.
+type counterVec struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+// inc adds one to the series identified by labelValues, given in the order
+// the labels were declared.
+func (c *counterVec) inc(labelValues ...string) {
+	key := formatLabels(c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key]++
+	c.mu.Unlock()
+}
+
+// value returns the current count for labelValues.
+func (c *counterVec) value(labelValues ...string) float64 {
+	key := formatLabels(c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *counterVec) writeTo(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, k, formatFloat(c.values[k]))
+	}
+}
+
+// histogram counts observations into cumulative upper-bound buckets.
+type histogram struct {
+	name, help string
+	bounds     []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(name, help string, bounds []float64) *histogram {
+	return &histogram{name: name, help: help, bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *histogram) writeTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, b := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", h.name, formatFloat(b), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", h.name, formatFloat(h.sum), h.name, h.count)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, n := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=%q", n, v)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// metricsHandler serves all metrics in the Prometheus text format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	requestsTotal.writeTo(w)
+	decisionsTotal.writeTo(w)
+	webhookDropped.writeTo(w)
+	kafkaDropped.writeTo(w)
+	requestDuration.writeTo(w)
+}
+
+// metricsBackend receives request and decision observations.
+// metricsHandler serves the Prometheus backend and simpleMetricsHandler the
+// simple one.
+type metricsBackend interface {
+	observeRequest(method string, status int, elapsed time.Duration)
+	observeDecision(level string)
+}
+
+// metricsBackends are the backends instrument and recordDecision update, as
+// chosen with -metrics-backends.
+var metricsBackends = []metricsBackend{prometheusMetrics{}, simpleMetrics}
+
+// prometheusMetrics updates the counters and histogram served at /metrics.
+type prometheusMetrics struct{}
+
+func (prometheusMetrics) observeRequest(method string, status int, elapsed time.Duration) {
+	requestDuration.observe(elapsed.Seconds())
+	requestsTotal.inc(method, strconv.Itoa(status))
+}
+
+func (prometheusMetrics) observeDecision(level string) {
+	decisionsTotal.inc(level)
+}
+
+// parseMetricsBackends parses a comma-separated list of backend names:
+// prometheus and simple.
+func parseMetricsBackends(s string) ([]metricsBackend, error) {
+	var out []metricsBackend
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "prometheus":
+			out = append(out, prometheusMetrics{})
+		case "simple":
+			out = append(out, simpleMetrics)
+		default:
+			return nil, fmt.Errorf("unknown metrics backend %q: want prometheus or simple", name)
+		}
+	}
+	return out, nil
+}
+
+// recordDecision counts a scored transaction by its risk level.
+func recordDecision(level string) {
+	for _, b := range metricsBackends {
+		b.observeDecision(level)
+	}
+}
+
+// knownMethods are the request methods kept as metric labels. Any other
+// method, which an unauthenticated client can make up, is counted as OTHER so
+// it can't create new series.
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// methodLabel returns the metric label for a request method.
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "OTHER"
+}
+
+// instrument records request counts and latency for next.
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+		latencies.observe(elapsed)
+		for _, b := range metricsBackends {
+			b.observeRequest(methodLabel(r.Method), rec.status, elapsed)
+		}
+	})
+}
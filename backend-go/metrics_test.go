@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrape returns the value of one series from the /metrics text.
+func scrape(t *testing.T, text, series string) float64 {
+	t.Helper()
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), series+" "); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return f
+		}
+	}
+	return 0
+}
+
+func TestMetricsCountDecisionsByLevel(t *testing.T) {
+	api, _ := newTestAPI(t)
+	before := do(api, "GET", "/metrics", "").Body.String()
+	for _, body := range []string{
+		`{"amount": 10, "merchant": "m"}`,
+		`{"amount": 20, "merchant": "m"}`,
+		`{"amount": 20000, "merchant": "m"}`,
+	} {
+		do(api, "POST", "/risk", body)
+	}
+	after := do(api, "GET", "/metrics", "").Body.String()
+	for level, want := range map[string]float64{"LOW": 2, "MEDIUM": 0, "HIGH": 1} {
+		series := `risk_decisions_total{level="` + level + `"}`
+		if got := scrape(t, after, series) - scrape(t, before, series); got != want {
+			t.Errorf("%s rose by %v, want %v", series, got, want)
+		}
+	}
+	if !strings.Contains(after, "# TYPE risk_requests_total counter") {
+		t.Error("request counter missing from /metrics")
+	}
+}
+
+func TestMetricsCollapseUnknownMethods(t *testing.T) {
+	api, _ := newTestAPI(t)
+	before := do(api, "GET", "/metrics", "").Body.String()
+	do(api, "BREW", "/risk", "")
+	do(api, "GET", "/risk", "")
+	after := do(api, "GET", "/metrics", "").Body.String()
+	for series, want := range map[string]float64{
+		`risk_requests_total{method="OTHER",status="405"}`: 1,
+		`risk_requests_total{method="GET",status="405"}`:   1,
+		`risk_requests_total{method="BREW",status="405"}`:  0,
+	} {
+		if got := scrape(t, after, series) - scrape(t, before, series); got != want {
+			t.Errorf("%s rose by %v, want %v", series, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// apiKey is an accepted X-API-Key value, optionally bound to a tenant.
+type apiKey struct {
+	key    []byte
+	tenant string
+}
+
+// apiKeys holds the accepted X-API-Key values, loaded from $API_KEYS.
+var apiKeys []apiKey
+
+// parseAPIKeys splits a comma-separated key list, dropping empty entries. An
+// entry of the form tenant:key binds the key to that tenant.
+func parseAPIKeys(s string) ([]apiKey, error) {
+	var keys []apiKey
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+		var tenant string
+		if t, rest, ok := strings.Cut(k, ":"); ok {
+			if !validTenantID(t) || rest == "" {
+				return nil, fmt.Errorf("API key entry for tenant %q: want tenant:key", t)
+			}
+			tenant, k = t, rest
+		}
+		keys = append(keys, apiKey{key: []byte(k), tenant: tenant})
+	}
+	return keys, nil
+}
+
+// lookupAPIKey compares key against every configured key in constant time so
+// response timing doesn't reveal how close a guess was. It returns the tenant
+// the key is bound to, if any.
+func lookupAPIKey(key string) (tenant string, ok bool) {
+	found := 0
+	for _, k := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), k.key) == 1 {
+			found, tenant = 1, k.tenant
+		}
+	}
+	return tenant, found == 1
+}
+
+// validAPIKey reports whether key is one of the configured keys.
+func validAPIKey(key string) bool {
+	_, ok := lookupAPIKey(key)
+	return ok
+}
+
+// requireAPIKey rejects requests whose X-API-Key header is missing or not one
+// of the configured keys.
+func requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			writeError(w, http.StatusUnauthorized, "missing API key")
+			return
+		}
+		if !validAPIKey(key) {
+			writeError(w, http.StatusUnauthorized, "invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverMiddleware turns a panicking handler into a 500 response so one bad
+// request can't take the server down. http.ErrAbortHandler is re-panicked so
+// net/http can abort the connection as documented.
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			logFor(r.Context()).Error("panic serving request",
+				"panic", v, "path", r.URL.Path, "stack", string(debug.Stack()))
+			writeError(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
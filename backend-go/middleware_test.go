@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	setVar(t, &apiKeys, []apiKey{{key: []byte("right")}})
+	h := requireAPIKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tc := range []struct {
+		name, key string
+		status    int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "wrong", http.StatusUnauthorized},
+		{"correct", "right", http.StatusOK},
+	} {
+		r := httptest.NewRequest("POST", "/risk", nil)
+		if tc.key != "" {
+			r.Header.Set("X-API-Key", tc.key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tc.status {
+			t.Errorf("%s key: status %d, want %d", tc.name, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := parseAPIKeys(" a, ,acme:b ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys[0].key) != "a" || keys[1].tenant != "acme" || string(keys[1].key) != "b" {
+		t.Fatalf("got %+v", keys)
+	}
+	if _, err := parseAPIKeys("bad tenant!:k"); err == nil {
+		t.Fatal("invalid tenant accepted")
+	}
+}
+
+func TestRecoverMiddlewareKeepsServing(t *testing.T) {
+	h := recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/boom" {
+			panic("deliberate")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/boom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e ErrorResponse
+	err = json.NewDecoder(resp.Body).Decode(&e)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || err != nil || e.Error != "internal server error" {
+		t.Fatalf("panic: status %d, body %+v (%v)", resp.StatusCode, e, err)
+	}
+
+	resp, err = http.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("after panic: status %d", resp.StatusCode)
+	}
+}
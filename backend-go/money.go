@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is a currency amount in integer cents. It decodes from JSON numbers or
+// numeric strings without going through float64, so threshold comparisons are
+// exact.
+type Money int64
+
+// dollars returns whole-dollar amounts as Money.
+func dollars(d int64) Money {
+	return Money(d * 100)
+}
+
+var (
+	errMoneyRange   = errors.New("amount out of range")
+	errInvalidMoney = errors.New("invalid amount")
+)
+
+// maxAmount is the largest USD amount accepted for scoring, in either
+// direction. Larger amounts are rejected as input errors.
+var maxAmount = dollars(1_000_000_000_000)
+
+// parseMoney parses a decimal string such as "10000.50" or "1e4", rounding
+// half away from zero to the nearest cent.
+func parseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if !isDecimal(s) {
+		return 0, fmt.Errorf("%w %q", errInvalidMoney, s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w %q", errInvalidMoney, s)
+	}
+	r.Mul(r, big.NewRat(100, 1))
+
+	// Round half away from zero: truncate |r| + 1/2.
+	neg := r.Sign() < 0
+	r.Abs(r)
+	r.Add(r, big.NewRat(1, 2))
+	cents := new(big.Int).Quo(r.Num(), r.Denom())
+	if !cents.IsInt64() {
+		return 0, errMoneyRange
+	}
+	c := cents.Int64()
+	if neg {
+		c = -c
+	}
+	return Money(c), nil
+}
+
+// isDecimal reports whether s is a plain decimal number: an optional sign,
+// digits with at most one decimal point, and an optional exponent below
+// 65536. It rules out the fractions and base prefixes, such as "1/3" and
+// "0x10", that big.Rat would otherwise accept.
+func isDecimal(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	mantissa, exp, hasExp := strings.Cut(strings.ToLower(s), "e")
+	digits, point := 0, false
+	for _, c := range mantissa {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !point:
+			point = true
+		default:
+			return false
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if !hasExp {
+		return true
+	}
+	if exp != "" && (exp[0] == '+' || exp[0] == '-') {
+		exp = exp[1:]
+	}
+	_, err := strconv.ParseUint(exp, 10, 16)
+	return exp != "" && err == nil
+}
+
+// decimalPlaces returns the number of significant decimal places in the
+// decimal string s, so "10.001" and "1.0001e1" have 3 and "10.00" has none.
+// Invalid strings have none, and the count stops at 32.
+func decimalPlaces(s string) int {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0
+	}
+	places := 0
+	for !r.IsInt() && places < 32 {
+		r.Mul(r, big.NewRat(10, 1))
+		places++
+	}
+	return places
+}
+
+// Amount locales, which decide how digit grouping in amount strings is read.
+// With localeNone amount strings must be plain decimals. localeUS reads
+// "10,000.50" (comma groups, period decimal point) and localeEU reads
+// "10.000,50" (period groups, comma decimal point), so "1.000" is one unit
+// under localeUS and a thousand under localeEU. JSON numbers are never
+// affected.
+const (
+	localeNone = "none"
+	localeUS   = "us"
+	localeEU   = "eu"
+)
+
+var amountLocale = localeNone
+
+// parseAmountLocale validates an -amount-locale value.
+func parseAmountLocale(s string) (string, error) {
+	switch s {
+	case localeNone, localeUS, localeEU:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid amount locale %q: want none, us or eu", s)
+}
+
+// ungroupAmount rewrites an amount string written under amountLocale as a
+// plain decimal. Grouping must be regular, in threes before the decimal point
+// only; anything else is rejected rather than guessed at.
+func ungroupAmount(s string) (string, error) {
+	group, point := "", ""
+	switch amountLocale {
+	case localeUS:
+		group, point = ",", "."
+	case localeEU:
+		group, point = ".", ","
+	default:
+		return s, nil
+	}
+	t := strings.TrimSpace(s)
+	if !strings.Contains(t, group) && point == "." {
+		return t, nil
+	}
+	whole, frac, hasPoint := strings.Cut(t, point)
+	if strings.Contains(frac, group) || strings.Contains(frac, point) {
+		return "", fmt.Errorf("%w %q", errInvalidMoney, s)
+	}
+	sign := ""
+	if whole != "" && (whole[0] == '-' || whole[0] == '+') {
+		sign, whole = whole[:1], whole[1:]
+	}
+	if strings.Contains(whole, group) {
+		parts := strings.Split(whole, group)
+		for i, p := range parts {
+			if (i == 0 && (len(p) < 1 || len(p) > 3)) || (i > 0 && len(p) != 3) || strings.Trim(p, "0123456789") != "" {
+				return "", fmt.Errorf("%w %q", errInvalidMoney, s)
+			}
+		}
+		whole = strings.Join(parts, "")
+	}
+	if hasPoint {
+		return sign + whole + "." + frac, nil
+	}
+	return sign + whole, nil
+}
+
+// moneyText returns the decimal text of a JSON number or numeric string.
+// Strings are read according to amountLocale.
+func moneyText(data []byte) (string, error) {
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return "", err
+		}
+		return ungroupAmount(s)
+	}
+	return s, nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s, err := moneyText(data)
+	if err != nil {
+		return err
+	}
+	v, err := parseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// MarshalJSON encodes the amount as a JSON number with two decimal places.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// String formats the amount as a decimal with two places, e.g. "10000.50".
+func (m Money) String() string {
+	c := int64(m)
+	sign := ""
+	if c < 0 {
+		sign = "-"
+	}
+	// Avoid overflow when negating math.MinInt64.
+	u := uint64(c)
+	if c < 0 {
+		u = uint64(^c) + 1
+	}
+	return sign + strconv.FormatUint(u/100, 10) + fmt.Sprintf(".%02d", u%100)
+}
+
+// Set parses a flag value, making *Money usable with flag.Var.
+func (m *Money) Set(s string) error {
+	v, err := parseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Float64 returns the amount in currency units. It is for display and
+// statistics only; comparisons should use Money directly.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
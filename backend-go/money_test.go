@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestMoneyDecodesExactly(t *testing.T) {
+	for in, want := range map[string]Money{
+		`0.29`:       29, // float64(0.29)*100 truncates to 28
+		`4.35`:       435,
+		`1000.01`:    100001,
+		`10000.005`:  1000001,
+		`"10000.50"`: 1000050,
+		`1e4`:        1000000,
+		`-5.555`:     -556,
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err != nil || m != want {
+			t.Errorf("%s: got %d (%v), want %d", in, m, err, want)
+		}
+	}
+}
+
+func TestMoneyComparesExactlyAgainstThresholds(t *testing.T) {
+	for in, want := range map[string]string{
+		// float64 keeps these above $10000; to the cent they are not.
+		`10000.000000000002`: "MEDIUM",
+		`10000.004`:          "MEDIUM",
+		`10000.005`:          "HIGH",
+		`1000.00`:            "LOW",
+		`1000.01`:            "MEDIUM",
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err != nil {
+			t.Fatal(err)
+		}
+		if got := applyThresholds(Transaction{Amount: m}, defaultSettings).RiskLevel; got != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestMoneyRejectsOutOfRangeAndGarbage(t *testing.T) {
+	for _, in := range []string{`1e308`, `"abc"`, `"1/2"`, `""`} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Errorf("%s: decoded as %s", in, m)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for m, want := range map[Money]string{0: "0.00", 5: "0.05", -556: "-5.56", 1000050: "10000.50"} {
+		if got := m.String(); got != want {
+			t.Errorf("Money(%d) = %s, want %s", int64(m), got, want)
+		}
+	}
+}
+
+func TestMaxAmount(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &rates, map[string]float64{"USD": 1, "XAU": 1e12})
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{"amount": 1e308, "merchant": "m"}`, http.StatusUnprocessableEntity},
+		{`{"amount": 1000000000000.01, "merchant": "m"}`, http.StatusUnprocessableEntity},
+		{`{"amount": 1000000000000, "merchant": "m"}`, http.StatusOK},
+		{`{"amount": 999999999999.99, "merchant": "m"}`, http.StatusOK},
+		{`{"amount": -1000000000000.01, "merchant": "m", "type": "credit"}`, http.StatusUnprocessableEntity},
+		// Within range in its own currency, but beyond maxAmount in USD.
+		{`{"amount": 2, "merchant": "m", "currency": "XAU"}`, http.StatusUnprocessableEntity},
+	} {
+		if rec := do(api, "POST", "/risk", tc.body); rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.body, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestMaxAmountConfigurable(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &maxAmount, maxAmount)
+	if err := maxAmount.Set("5000"); err != nil {
+		t.Fatal(err)
+	}
+	if rec := do(api, "POST", "/risk", `{"amount": 5000.01, "merchant": "m"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", rec.Code)
+	}
+	if rec := do(api, "POST", "/risk", `{"amount": 5000, "merchant": "m"}`); rec.Code != http.StatusOK {
+		t.Fatalf("status %d at the maximum: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestConvertAmountOverflow(t *testing.T) {
+	if _, err := convertAmount(Money(math.MaxInt64/2), 4); !errors.Is(err, errConversionOverflow) {
+		t.Fatalf("positive overflow: %v", err)
+	}
+	if _, err := convertAmount(Money(math.MinInt64/2), 4); !errors.Is(err, errConversionOverflow) {
+		t.Fatalf("negative overflow: %v", err)
+	}
+	if got, err := convertAmount(dollars(100), 1.08); err != nil || got != dollars(108) {
+		t.Fatalf("got %s, %v", got, err)
+	}
+}
+
+func TestRiskAcceptsStringAmounts(t *testing.T) {
+	api, tn := newTestAPI(t)
+	for _, tc := range []struct {
+		body string
+		want Money
+	}{
+		{`{"amount": "10000.50", "merchant": "m"}`, 1000050},
+		{`{"amount": 10000.50, "merchant": "m"}`, 1000050},
+		{`{"amount": " 25 ", "merchant": "m"}`, dollars(25)},
+		{`{"amount": "2.5e1", "merchant": "m"}`, dollars(25)},
+	} {
+		rec := do(api, "POST", "/risk", tc.body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tc.body, rec.Code, rec.Body)
+		}
+		if res := decode[ScoreResult](t, rec); tc.want == 1000050 && res.RiskLevel != "HIGH" {
+			t.Errorf("%s: got %s", tc.body, res.RiskLevel)
+		}
+	}
+	hist, err := tn.store.History(t.Context(), HistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range hist {
+		if a := r.Transaction.Amount; a != 1000050 && a != dollars(25) {
+			t.Errorf("stored amount %s", a)
+		}
+	}
+}
+
+func TestRiskRejectsUnparseableStringAmounts(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, amount := range []string{`"abc"`, `""`, `"1/3"`, `"12.5.6"`, `"0x10"`, `"0b11"`, `"."`, `"1e"`, `"+-1"`, `"1e99999"`} {
+		rec := do(api, "POST", "/risk", `{"amount": `+amount+`, "merchant": "m"}`)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422: %s", amount, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestAmountLocales(t *testing.T) {
+	const rejected = Money(math.MinInt64)
+	for _, tc := range []struct {
+		locale, amount string
+		want           Money
+	}{
+		{localeUS, `"10,000.50"`, 1000050},
+		{localeUS, `"-1,234,567.89"`, -123456789},
+		{localeUS, `"1.000"`, dollars(1)},
+		{localeUS, `"1,000"`, dollars(1000)},
+		{localeUS, `"10.000,50"`, rejected},
+		{localeUS, `"1,00,000"`, rejected},
+		{localeUS, `"1,000.5,0"`, rejected},
+		{localeEU, `"10.000,50"`, 1000050},
+		{localeEU, `"1.000"`, dollars(1000)},
+		{localeEU, `"1,000"`, dollars(1)},
+		{localeEU, `"10,000.50"`, rejected},
+		{localeEU, `"1.0000"`, rejected},
+		{localeNone, `"10,000.50"`, rejected},
+		{localeNone, `"1.000"`, dollars(1)},
+		// JSON numbers are never regrouped.
+		{localeEU, `1.5`, 150},
+	} {
+		setVar(t, &amountLocale, tc.locale)
+		var m Money
+		err := json.Unmarshal([]byte(tc.amount), &m)
+		switch {
+		case tc.want == rejected && err == nil:
+			t.Errorf("%s %s: accepted as %s", tc.locale, tc.amount, m)
+		case tc.want != rejected && (err != nil || m != tc.want):
+			t.Errorf("%s %s: got %s, %v; want %s", tc.locale, tc.amount, m, err, tc.want)
+		}
+	}
+}
+
+func TestRiskGroupedAmounts(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &amountLocale, localeEU)
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": "10.000,50", "merchant": "m"}`)); res.RiskLevel != "HIGH" {
+		t.Fatalf("got %s (%s), want HIGH", res.RiskLevel, res.Reason)
+	}
+	if rec := do(api, "POST", "/risk", `{"amount": "10,000.50", "merchant": "m"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("US grouping under eu: status %d, want 422", rec.Code)
+	}
+}
+
+func TestParseAmountLocale(t *testing.T) {
+	if _, err := parseAmountLocale("fr"); err == nil {
+		t.Fatal("unknown locale accepted")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentJSON = "application/json"
+	contentXML  = "application/xml"
+)
+
+// negotiate picks the response media type for an Accept header. JSON is the
+// default for a missing header or wildcards; XML is chosen when the client
+// prefers application/xml (or text/xml). ok is false when the client accepts
+// neither.
+func negotiate(accept string) (contentType string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return contentJSON, true
+	}
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		var ct string
+		switch mt {
+		case contentJSON, "application/*", "*/*":
+			ct = contentJSON
+		case contentXML, "text/xml":
+			ct = contentXML
+		default:
+			continue
+		}
+		// Ties go to the earlier entry, and JSON wins ties with wildcards.
+		if q > bestQ {
+			best, bestQ = ct, q
+		}
+	}
+	return best, best != ""
+}
+
+// writeNegotiated encodes v as contentType, which must come from negotiate.
+func writeNegotiated(w http.ResponseWriter, contentType string, v any) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	if contentType == contentXML {
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(v)
+		return
+	}
+	json.NewEncoder(w).Encode(v)
+}
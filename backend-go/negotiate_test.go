@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+)
+
+func TestRiskContentNegotiation(t *testing.T) {
+	api, _ := newTestAPI(t)
+	body := `{"amount": 20000, "merchant": "m"}`
+
+	rec := do(api, "POST", "/risk", body)
+	if ct := rec.Header().Get("Content-Type"); ct != contentJSON {
+		t.Fatalf("default Content-Type %q", ct)
+	}
+	if res := decode[ScoreResult](t, rec); res.RiskLevel != "HIGH" {
+		t.Fatalf("JSON result %+v", res)
+	}
+
+	rec = do(api, "POST", "/risk", body, "Accept", "application/xml")
+	if ct := rec.Header().Get("Content-Type"); ct != contentXML {
+		t.Fatalf("XML Content-Type %q", ct)
+	}
+	var res ScoreResult
+	if err := xml.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode XML %q: %v", rec.Body, err)
+	}
+	if res.XMLName.Local != "risk_result" || res.RiskLevel != "HIGH" || res.Reason == "" {
+		t.Fatalf("XML result %+v", res)
+	}
+
+	rec = do(api, "POST", "/risk", body, "Accept", "text/csv")
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("unsupported Accept: status %d, want 406", rec.Code)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		accept, want string
+		ok           bool
+	}{
+		{"", contentJSON, true},
+		{"*/*", contentJSON, true},
+		{"text/xml", contentXML, true},
+		{"application/json;q=0.5, application/xml", contentXML, true},
+		{"application/xml;q=0.5, */*", contentJSON, true},
+		{"text/html", "", false},
+	} {
+		got, ok := negotiate(tc.accept)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("negotiate(%q) = %q, %v; want %q, %v", tc.accept, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Business hours: when offHoursEnabled is set, transactions timestamped
+// outside [businessStart, businessEnd) in businessLocation are escalated one
+// level. The bounds are offsets from local midnight. The rule is opt-in and
+// main refuses to enable it without an explicit -business-tz, since hours
+// measured in the wrong timezone would escalate a whole region's daytime
+// traffic.
+var (
+	businessStart    = 6 * time.Hour
+	businessEnd      = 22 * time.Hour
+	businessLocation = time.UTC
+	offHoursEnabled  = false
+)
+
+// parseBusinessHours parses a range such as "06:00-22:00".
+func parseBusinessHours(s string) (start, end time.Duration, err error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("business hours %q: want HH:MM-HH:MM", s)
+	}
+	if start, err = parseClock(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseClock(to); err != nil {
+		return 0, 0, err
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("business hours %q: start must be before end", s)
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	c, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return time.Duration(c.Hour())*time.Hour + time.Duration(c.Minute())*time.Minute, nil
+}
+
+// offHours reports whether t falls outside business hours.
+func offHours(t time.Time) bool {
+	t = t.In(businessLocation)
+	since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	return since < businessStart || since >= businessEnd
+}
+
+// escalate raises a risk level by one step; HIGH stays HIGH.
+func escalate(level string) string {
+	switch level {
+	case "LOW":
+		return "MEDIUM"
+	default:
+		return "HIGH"
+	}
+}
+
+// applyOffHours escalates d one level when at is outside business hours.
+func applyOffHours(d Decision, at time.Time) Decision {
+	if !offHoursEnabled || !offHours(at) {
+		return d
+	}
+	return Decision{
+		RiskLevel: escalate(d.RiskLevel),
+		Reason:    fmt.Sprintf("%s; off-hours transaction at %s", d.Reason, at.In(businessLocation).Format("15:04 MST")),
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOffHoursDisabledByDefault(t *testing.T) {
+	if offHoursEnabled {
+		t.Fatal("off-hours escalation must be opt-in")
+	}
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	at := time.Date(2026, time.March, 10, 3, 0, 0, 0, time.UTC)
+	d, _ := tn.decide(t.Context(), Transaction{Merchant: "m", Amount: dollars(10), Timestamp: at})
+	if d.RiskLevel != "LOW" {
+		t.Fatalf("3am with the rule off: got %s (%s)", d.RiskLevel, d.Reason)
+	}
+}
+
+func TestOffHoursEscalation(t *testing.T) {
+	setClock(t, noon)
+	setVar(t, &offHoursEnabled, true)
+	setVar(t, &businessLocation, time.FixedZone("EST", -5*60*60))
+	tn := newTestTenant(t, defaultTenant)
+	local := func(h int) time.Time { return time.Date(2026, time.March, 10, h, 0, 0, 0, businessLocation) }
+
+	for _, tc := range []struct {
+		name  string
+		tx    Transaction
+		level string
+	}{
+		{"3am local", Transaction{Merchant: "m", Amount: dollars(10), Timestamp: local(3)}, "MEDIUM"},
+		{"midday local", Transaction{Merchant: "m", Amount: dollars(10), Timestamp: local(12)}, "LOW"},
+		{"midday UTC is 7am local", Transaction{Merchant: "m", Amount: dollars(10), Timestamp: noon}, "LOW"},
+		{"22:00 local is off-hours", Transaction{Merchant: "m", Amount: dollars(10), Timestamp: local(22)}, "MEDIUM"},
+		{"combines with the amount rule", Transaction{Merchant: "m", Amount: dollars(2500), Timestamp: local(3)}, "HIGH"},
+	} {
+		d, _ := tn.decide(t.Context(), tc.tx)
+		if d.RiskLevel != tc.level {
+			t.Errorf("%s: got %s (%s), want %s", tc.name, d.RiskLevel, d.Reason, tc.level)
+		}
+	}
+}
+
+func TestParseBusinessHours(t *testing.T) {
+	start, end, err := parseBusinessHours("08:30-24:00")
+	if err != nil || start != 8*time.Hour+30*time.Minute || end != 24*time.Hour {
+		t.Fatalf("got %s-%s, %v", start, end, err)
+	}
+	for _, s := range []string{"22:00-06:00", "0800-1700", "08:00", "25:00-26:00"} {
+		if _, _, err := parseBusinessHours(s); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The OpenAPI document served at /openapi.json is built from apiOperations
+// and the Go types the handlers decode and encode, so field names, types and
+// required-ness follow the structs' json tags.
+
+// oaSchema is an OpenAPI 3.0 schema object.
+type oaSchema struct {
+	Ref                  string               `json:"$ref,omitempty"`
+	Type                 string               `json:"type,omitempty"`
+	Format               string               `json:"format,omitempty"`
+	Description          string               `json:"description,omitempty"`
+	Enum                 []string             `json:"enum,omitempty"`
+	Items                *oaSchema            `json:"items,omitempty"`
+	Properties           map[string]*oaSchema `json:"properties,omitempty"`
+	Required             []string             `json:"required,omitempty"`
+	AdditionalProperties *oaSchema            `json:"additionalProperties,omitempty"`
+}
+
+// param documents a query parameter.
+type param struct {
+	name, description string
+}
+
+// operation documents one method on a path.
+type operation struct {
+	method, summary string
+	// request is the JSON body type; nil means no body. requestType
+	// overrides the media type.
+	request     reflect.Type
+	requestType string
+	// response is the success body type, sent with status; nil means no
+	// body.
+	response reflect.Type
+	status   int
+	query    []param
+	// errors lists the error statuses beyond those every endpoint shares.
+	errors []int
+}
+
+func typeFor[T any]() reflect.Type { return reflect.TypeOf((*T)(nil)).Elem() }
+
+// apiOperations documents every route registered by registerRoutes, keyed
+// by OpenAPI path.
+var apiOperations = map[string][]operation{
+	"/risk": {{
+		method: http.MethodPost, summary: "Score and record a transaction",
+		request: typeFor[Transaction](), response: typeFor[ScoreResult](), status: http.StatusOK,
+		query:  []param{{"dry_run", "score without recording when true"}},
+		errors: []int{http.StatusNotAcceptable, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	}},
+	"/risk/batch": {{
+		method: http.MethodPost, summary: "Score and record a batch of transactions",
+		request: typeFor[[]Transaction](), response: typeFor[[]BatchResult](), status: http.StatusOK,
+		errors: []int{http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	}},
+	"/risk/whatif": {{
+		method: http.MethodPost, summary: "Score a transaction against a proposed rule set without recording it",
+		request: typeFor[whatifRequest](), response: typeFor[ExplainResult](), status: http.StatusOK,
+		errors: []int{http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	}},
+	"/risk/upload": {{
+		method: http.MethodPost, summary: "Score a CSV of amount,merchant,account,timestamp rows",
+		request: typeFor[string](), requestType: "text/csv", response: typeFor[UploadSummary](), status: http.StatusOK,
+		errors: []int{http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType},
+	}},
+	"/jobs": {{
+		method: http.MethodPost, summary: "Queue a batch of transactions for background scoring",
+		request: typeFor[[]Transaction](), response: typeFor[Job](), status: http.StatusAccepted,
+		errors: []int{http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusServiceUnavailable},
+	}},
+	"/jobs/{id}": {{
+		method: http.MethodGet, summary: "Poll a job's progress and fetch its results",
+		response: typeFor[Job](), status: http.StatusOK,
+		errors: []int{http.StatusNotFound},
+	}},
+	"/explain": {{
+		method: http.MethodPost, summary: "Score a transaction without recording it and trace every rule",
+		request: typeFor[Transaction](), response: typeFor[ExplainResult](), status: http.StatusOK,
+		errors: []int{http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	}},
+	"/transactions": {{
+		method: http.MethodGet, summary: "List recorded transactions, newest first",
+		response: typeFor[HistoryPage](), status: http.StatusOK,
+		query: []param{
+			{"from", "RFC 3339 lower bound on the transaction timestamp"},
+			{"to", "RFC 3339 exclusive upper bound on the transaction timestamp"},
+			{"status", "review status of HIGH decisions: pending, cleared or confirmed"},
+			{"limit", "page size, at most 1000"},
+			{"cursor", "next_cursor from the previous page"},
+		},
+		errors: []int{http.StatusUnprocessableEntity},
+	}},
+	"/transactions/{id}/status": {{
+		method: http.MethodPost, summary: "Change the review status of a HIGH decision",
+		request: typeFor[statusRequest](), response: typeFor[Record](), status: http.StatusOK,
+		errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+	}},
+	"/rules": {{
+		method: http.MethodGet, summary: "List the active rules in evaluation order",
+		response: typeFor[RulesView](), status: http.StatusOK,
+	}},
+	"/rules/reload": {{
+		method: http.MethodPost, summary: "Reload the rules file",
+		response: typeFor[reloadResponse](), status: http.StatusOK,
+		errors: []int{http.StatusUnprocessableEntity},
+	}},
+	"/config": {
+		{method: http.MethodGet, summary: "Show the scoring settings", response: typeFor[Settings](), status: http.StatusOK},
+		{
+			method: http.MethodPatch, summary: "Change the scoring settings",
+			request: typeFor[settingsPatch](), response: typeFor[Settings](), status: http.StatusOK,
+			errors: []int{http.StatusUnprocessableEntity},
+		},
+	},
+	"/audit": {{
+		method: http.MethodGet, summary: "List the tenant's configuration changes, oldest first",
+		response: typeFor[[]AuditEntry](), status: http.StatusOK,
+	}},
+	"/reports/ctr": {{
+		method: http.MethodGet, summary: "Currency Transaction Report for a day",
+		response: typeFor[CTRReport](), status: http.StatusOK,
+		query: []param{{"date", "report day as YYYY-MM-DD"}},
+	}},
+	"/reports/merchants": {{
+		method: http.MethodGet, summary: "Decision counts per merchant, most HIGH first",
+		response: typeFor[MerchantReport](), status: http.StatusOK,
+		query: []param{
+			{"from", "RFC 3339 lower bound on the transaction timestamp"},
+			{"to", "RFC 3339 exclusive upper bound on the transaction timestamp"},
+			{"limit", "maximum number of merchants"},
+		},
+		errors: []int{http.StatusUnprocessableEntity},
+	}},
+	"/stats": {{
+		method: http.MethodGet, summary: "Summarize recorded transactions",
+		response: typeFor[Stats](), status: http.StatusOK,
+	}},
+	"/stats/latency": {
+		{method: http.MethodGet, summary: "Scoring latency percentiles", response: typeFor[LatencySummary](), status: http.StatusOK},
+		{method: http.MethodDelete, summary: "Reset the latency percentiles", status: http.StatusNoContent},
+	},
+	"/replay": {{
+		method: http.MethodPost, summary: "Re-decide recorded transactions under the current rules",
+		response: typeFor[ReplaySummary](), status: http.StatusOK,
+		query: []param{{"commit", "store the new decisions when true"}},
+	}},
+	"/simulate": {{
+		method: http.MethodPost, summary: "Score and record synthetic transactions (development only, enabled with -simulate)",
+		request: typeFor[simulateRequest](), response: typeFor[SimulateResult](), status: http.StatusOK,
+		errors: []int{http.StatusNotFound, http.StatusUnprocessableEntity},
+	}},
+	"/debug/info": {{
+		method: http.MethodGet, summary: "Effective configuration and runtime statistics (enabled with -debug)",
+		response: typeFor[DebugInfo](), status: http.StatusOK,
+		errors: []int{http.StatusNotFound},
+	}},
+	"/merchants": {{
+		method: http.MethodGet, summary: "Show the merchant allow and deny lists",
+		response: typeFor[MerchantListsView](), status: http.StatusOK,
+	}},
+	"/merchants/allow": merchantListOperations("allow"),
+	"/merchants/deny":  merchantListOperations("deny"),
+}
+
+func merchantListOperations(list string) []operation {
+	return []operation{
+		{
+			method: http.MethodPost, summary: "Add a merchant to the " + list + " list",
+			request: typeFor[merchantRequest](), response: typeFor[MerchantListsView](), status: http.StatusOK,
+			errors: []int{http.StatusConflict, http.StatusUnprocessableEntity},
+		},
+		{
+			method: http.MethodDelete, summary: "Remove a merchant from the " + list + " list",
+			request: typeFor[merchantRequest](), status: http.StatusNoContent,
+			errors: []int{http.StatusUnprocessableEntity},
+		},
+	}
+}
+
+// fieldDocs refines generated properties, keyed by component and property.
+var fieldDocs = map[string]map[string]*oaSchema{
+	"Transaction": {
+		"amount": {Type: "number", Description: "decimal amount in currency; a numeric string is also accepted"},
+		"type":   {Type: "string", Enum: []string{TypeDebit, TypeCredit}},
+	},
+	"WhatifRequest": {
+		"rules": {Type: "array", Items: &oaSchema{Ref: "#/components/schemas/Rule"}},
+	},
+	"StatusRequest": {
+		"status": {Type: "string", Enum: []string{StatusPending, StatusCleared, StatusConfirmed}},
+	},
+}
+
+// requiredFields overrides the required properties derived from json tags
+// where a payload's schema is looser than its struct.
+var requiredFields = map[string][]string{
+	"Transaction": transactionSchema.Required,
+}
+
+var (
+	moneyType   = typeFor[Money]()
+	timeType    = typeFor[time.Time]()
+	xmlNameType = typeFor[xml.Name]()
+)
+
+// specBuilder collects the named schemas referenced by operations.
+type specBuilder struct {
+	components map[string]*oaSchema
+}
+
+func componentName(t reflect.Type) string {
+	n := t.Name()
+	return strings.ToUpper(n[:1]) + n[1:]
+}
+
+func (b *specBuilder) schemaFor(t reflect.Type) *oaSchema {
+	switch t {
+	case moneyType:
+		return &oaSchema{Type: "number", Description: "decimal amount"}
+	case timeType:
+		return &oaSchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schemaFor(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return &oaSchema{Type: "array", Items: b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &oaSchema{Type: "object", AdditionalProperties: b.schemaFor(t.Elem())}
+	case reflect.String:
+		return &oaSchema{Type: "string"}
+	case reflect.Bool:
+		return &oaSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &oaSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &oaSchema{Type: "number"}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t, "")
+		}
+		name := componentName(t)
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = nil // reserve against recursion
+			b.components[name] = b.object(t, name)
+		}
+		return &oaSchema{Ref: "#/components/schemas/" + name}
+	}
+	return &oaSchema{}
+}
+
+// object builds the schema of struct t, flattening embedded structs the way
+// encoding/json does.
+func (b *specBuilder) object(t reflect.Type, name string) *oaSchema {
+	s := &oaSchema{Type: "object", Properties: map[string]*oaSchema{}}
+	b.addFields(s, t, name)
+	if req, ok := requiredFields[name]; ok {
+		s.Required = append([]string(nil), req...)
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+func (b *specBuilder) addFields(s *oaSchema, t reflect.Type, name string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type == xmlNameType {
+			continue
+		}
+		tag, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			b.addFields(s, f.Type, name)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if doc, ok := fieldDocs[name][tag]; ok {
+			s.Properties[tag] = doc
+		} else {
+			s.Properties[tag] = b.schemaFor(f.Type)
+		}
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, tag)
+		}
+	}
+}
+
+func jsonContent(s *oaSchema) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": s}}
+}
+
+// buildOpenAPI assembles the OpenAPI 3.0 document.
+func buildOpenAPI() map[string]any {
+	b := &specBuilder{components: map[string]*oaSchema{}}
+	errorRef := b.schemaFor(typeFor[ErrorResponse]())
+	errorResponse := func(status int) map[string]any {
+		return map[string]any{"description": http.StatusText(status), "content": jsonContent(errorRef)}
+	}
+
+	paths := map[string]any{}
+	for path, ops := range apiOperations {
+		item := map[string]any{}
+		for _, op := range ops {
+			o := map[string]any{"summary": op.summary}
+			var params []map[string]any
+			if strings.Contains(path, "{id}") {
+				params = append(params, map[string]any{"name": "id", "in": "path", "required": true, "schema": &oaSchema{Type: "string"}})
+			}
+			for _, p := range op.query {
+				params = append(params, map[string]any{"name": p.name, "in": "query", "description": p.description, "schema": &oaSchema{Type: "string"}})
+			}
+			if params != nil {
+				o["parameters"] = params
+			}
+			if op.request != nil {
+				content := jsonContent(b.schemaFor(op.request))
+				if op.requestType != "" {
+					content = map[string]any{op.requestType: map[string]any{"schema": b.schemaFor(op.request)}}
+				}
+				o["requestBody"] = map[string]any{"required": true, "content": content}
+			}
+			ok := map[string]any{"description": http.StatusText(op.status)}
+			if op.response != nil {
+				ok["content"] = jsonContent(b.schemaFor(op.response))
+			}
+			responses := map[string]any{strconv.Itoa(op.status): ok}
+			for _, status := range append([]int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError}, op.errors...) {
+				responses[strconv.Itoa(status)] = errorResponse(status)
+			}
+			o["responses"] = responses
+			item[strings.ToLower(op.method)] = o
+		}
+		paths[path] = item
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Risk engine API",
+			"version": strings.TrimPrefix(apiVersion, "/"),
+		},
+		"servers":  []map[string]any{{"url": apiVersion}},
+		"security": []map[string]any{{"apiKey": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// openAPIDoc is the encoded document, built on first request.
+var openAPIDoc = sync.OnceValues(func() ([]byte, error) {
+	return json.MarshalIndent(buildOpenAPI(), "", "  ")
+})
+
+// openAPIHandler serves GET /openapi.json.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	doc, err := openAPIDoc()
+	if err != nil {
+		logFor(r.Context()).Error("encode openapi document", "error", err)
+		writeError(w, http.StatusInternalServerError, "openapi document unavailable")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
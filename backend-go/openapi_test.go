@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// openAPIDocument is the part of an OpenAPI 3.0 document the tests check.
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	api, _ := newTestAPI(t)
+	rec := do(api, "GET", "/openapi.json", "", "X-API-Key", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	doc := decode[openAPIDocument](t, rec)
+	if !strings.HasPrefix(doc.OpenAPI, "3.0.") || doc.Info.Title == "" || doc.Info.Version == "" {
+		t.Fatalf("missing openapi version or info: %+v", doc)
+	}
+	post, ok := doc.Paths["/risk"]["post"]
+	if !ok {
+		t.Fatal("POST /risk is not documented")
+	}
+	for _, want := range []string{`"#/components/schemas/Transaction"`, `"#/components/schemas/ScoreResult"`, `"422"`} {
+		if !strings.Contains(string(post), want) {
+			t.Errorf("POST /risk does not mention %s", want)
+		}
+	}
+
+	// Every $ref must name a component.
+	refs := strings.Split(rec.Body.String(), `"$ref": "#/components/schemas/`)[1:]
+	if len(refs) == 0 {
+		t.Fatal("document has no schema references")
+	}
+	for _, part := range refs {
+		name, _, _ := strings.Cut(part, `"`)
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("$ref to undefined schema %q", name)
+		}
+	}
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	for path := range apiRoutes(NewServer(NewTenants(newTestTenant(t, defaultTenant)))) {
+		documented := false
+		for p := range apiOperations {
+			if p == path || strings.HasSuffix(path, "/") && strings.HasPrefix(p, path) {
+				documented = true
+			}
+		}
+		if !documented {
+			t.Errorf("route %s is not documented", path)
+		}
+	}
+}
+
+func TestOpenAPIOperationsAreServed(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	mux := api.(*http.ServeMux)
+	for path, ops := range apiOperations {
+		target := strings.ReplaceAll(path, "{id}", "x")
+		for _, op := range ops {
+			r := httptest.NewRequest(op.method, target, strings.NewReader(""))
+			if _, pattern := mux.Handler(r); pattern == "" {
+				t.Errorf("%s %s is not routed", op.method, path)
+				continue
+			}
+			if rec := do(api, op.method, target, ""); rec.Code == http.StatusMethodNotAllowed {
+				t.Errorf("%s %s: method not allowed", op.method, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// RiskService mirrors the REST scoring endpoints (/v1/risk and
+// /v1/risk/batch) for internal callers that speak gRPC. It is served on
+// -grpc-addr by grpc.go; regenerate the stubs in proto/riskv1 with go generate.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: proto/risk.proto
+
+package riskv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RiskService_Score_FullMethodName      = "/risk.v1.RiskService/Score"
+	RiskService_ScoreBatch_FullMethodName = "/risk.v1.RiskService/ScoreBatch"
+)
+
+// RiskServiceClient is the client API for RiskService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RiskServiceClient interface {
+	// Score evaluates a single transaction, like POST /v1/risk.
+	Score(ctx context.Context, in *ScoreRequest, opts ...grpc.CallOption) (*ScoreResponse, error)
+	// ScoreBatch evaluates a stream of transactions, answering each in order.
+	ScoreBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ScoreRequest, ScoreResponse], error)
+}
+
+type riskServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRiskServiceClient(cc grpc.ClientConnInterface) RiskServiceClient {
+	return &riskServiceClient{cc}
+}
+
+func (c *riskServiceClient) Score(ctx context.Context, in *ScoreRequest, opts ...grpc.CallOption) (*ScoreResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScoreResponse)
+	err := c.cc.Invoke(ctx, RiskService_Score_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riskServiceClient) ScoreBatch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ScoreRequest, ScoreResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RiskService_ServiceDesc.Streams[0], RiskService_ScoreBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ScoreRequest, ScoreResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RiskService_ScoreBatchClient = grpc.BidiStreamingClient[ScoreRequest, ScoreResponse]
+
+// RiskServiceServer is the server API for RiskService service.
+// All implementations must embed UnimplementedRiskServiceServer
+// for forward compatibility.
+type RiskServiceServer interface {
+	// Score evaluates a single transaction, like POST /v1/risk.
+	Score(context.Context, *ScoreRequest) (*ScoreResponse, error)
+	// ScoreBatch evaluates a stream of transactions, answering each in order.
+	ScoreBatch(grpc.BidiStreamingServer[ScoreRequest, ScoreResponse]) error
+	mustEmbedUnimplementedRiskServiceServer()
+}
+
+// UnimplementedRiskServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRiskServiceServer struct{}
+
+func (UnimplementedRiskServiceServer) Score(context.Context, *ScoreRequest) (*ScoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Score not implemented")
+}
+func (UnimplementedRiskServiceServer) ScoreBatch(grpc.BidiStreamingServer[ScoreRequest, ScoreResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ScoreBatch not implemented")
+}
+func (UnimplementedRiskServiceServer) mustEmbedUnimplementedRiskServiceServer() {}
+func (UnimplementedRiskServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRiskServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RiskServiceServer will
+// result in compilation errors.
+type UnsafeRiskServiceServer interface {
+	mustEmbedUnimplementedRiskServiceServer()
+}
+
+func RegisterRiskServiceServer(s grpc.ServiceRegistrar, srv RiskServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRiskServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RiskService_ServiceDesc, srv)
+}
+
+func _RiskService_Score_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiskServiceServer).Score(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RiskService_Score_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiskServiceServer).Score(ctx, req.(*ScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RiskService_ScoreBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RiskServiceServer).ScoreBatch(&grpc.GenericServerStream[ScoreRequest, ScoreResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RiskService_ScoreBatchServer = grpc.BidiStreamingServer[ScoreRequest, ScoreResponse]
+
+// RiskService_ServiceDesc is the grpc.ServiceDesc for RiskService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RiskService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "risk.v1.RiskService",
+	HandlerType: (*RiskServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Score",
+			Handler:    _RiskService_Score_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ScoreBatch",
+			Handler:       _RiskService_ScoreBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/risk.proto",
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Rate limit applied per API key, or per client IP for requests without a
+// valid key. A rate of zero or less disables it.
+var (
+	rateLimitRPS   = 100.0
+	rateLimitBurst = 200
+)
+
+// limiters holds one token bucket per client.
+var limiters = newLimiterSet()
+
+// clientLimiter is a client's token bucket and when it was last used.
+type clientLimiter struct {
+	lim  *rate.Limiter
+	last time.Time
+}
+
+// limiterSet maps client keys to buckets. Buckets idle for limiterIdleTTL
+// are dropped so the map doesn't grow without bound.
+type limiterSet struct {
+	mu        sync.Mutex
+	buckets   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+const limiterIdleTTL = 5 * time.Minute
+
+func newLimiterSet() *limiterSet {
+	return &limiterSet{buckets: map[string]*clientLimiter{}}
+}
+
+// allow takes a token from key's bucket, which refills at rateLimitRPS up to
+// rateLimitBurst. When none is available it returns false and how long until
+// one will be.
+func (l *limiterSet) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > limiterIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &clientLimiter{lim: rate.NewLimiter(rate.Limit(rateLimitRPS), rateLimitBurst)}
+		l.buckets[key] = b
+	}
+	b.last = now
+	r := b.lim.ReserveN(now, 1)
+	if wait := r.DelayFrom(now); wait > 0 {
+		r.CancelAt(now)
+		return false, wait
+	}
+	return true, 0
+}
+
+// rateLimitKey identifies the client: its API key when valid, otherwise its
+// IP, so random keys can't be used to mint fresh buckets.
+func rateLimitKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" && validAPIKey(key) {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimit rejects clients that exceed their bucket with 429 and a
+// Retry-After header, unless the rate limit is disabled.
+func rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rateLimitRPS <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ok, wait := limiters.allow(rateLimitKey(r), time.Now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPerKey(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &apiKeys, []apiKey{{key: []byte(testKey)}, {key: []byte("other-key")}})
+	setVar(t, &rateLimitRPS, 0.01)
+	setVar(t, &rateLimitBurst, 3)
+
+	for i := range 3 {
+		if rec := do(api, "GET", "/transactions", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d within burst: status %d", i, rec.Code)
+		}
+	}
+	rec := do(api, "GET", "/transactions", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over limit: status %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("429 without Retry-After")
+	}
+	if rec := do(api, "GET", "/transactions", "", "X-API-Key", "other-key"); rec.Code != http.StatusOK {
+		t.Fatalf("second key affected by the first: status %d", rec.Code)
+	}
+}
+
+func TestLimiterRefills(t *testing.T) {
+	setVar(t, &rateLimitRPS, 1.0)
+	setVar(t, &rateLimitBurst, 1)
+	l := newLimiterSet()
+	if ok, _ := l.allow("k", noon); !ok {
+		t.Fatal("first request refused")
+	}
+	ok, wait := l.allow("k", noon)
+	if ok || wait != time.Second {
+		t.Fatalf("empty bucket: ok=%v wait=%s, want refused for 1s", ok, wait)
+	}
+	if ok, _ := l.allow("k", noon.Add(time.Second)); !ok {
+		t.Fatal("bucket did not refill")
+	}
+}
+
+func TestRateLimitZeroDisables(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &rateLimitRPS, 0.0)
+	setVar(t, &rateLimitBurst, 1)
+	for i := range 3 {
+		if rec := do(api, "GET", "/transactions", ""); rec.Code != http.StatusOK || rec.Header().Get("Retry-After") != "" {
+			t.Fatalf("request %d with the limit disabled: status %d", i, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	secretAccount      = "ACCT-9876543210"
+	secretCounterparty = "Jane Q Public"
+)
+
+// captureLogs sends every log line, at any level, to the returned buffer.
+func captureLogs(t *testing.T, json bool) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler = slog.NewTextHandler(&buf, opts)
+	if json {
+		h = slog.NewJSONHandler(&buf, opts)
+	}
+	setVar(t, &logger, slog.New(h))
+	return &buf
+}
+
+func TestDecisionLogsAreRedacted(t *testing.T) {
+	for _, json := range []bool{false, true} {
+		api, _ := newTestAPI(t)
+		buf := captureLogs(t, json)
+		do(api, "POST", "/risk", fmt.Sprintf(`{"amount": 20000, "merchant": "m", "account_id": %q, "counterparty": %q}`, secretAccount, secretCounterparty))
+		out := buf.String()
+		if !strings.Contains(out, "scored transaction") {
+			t.Fatalf("decision not logged: %s", out)
+		}
+		if strings.Contains(out, secretAccount) || strings.Contains(out, secretCounterparty) {
+			t.Fatalf("json=%v: log contains a full identifier:\n%s", json, out)
+		}
+		if !strings.Contains(out, "3210") {
+			t.Fatalf("json=%v: masked account lost its last four characters:\n%s", json, out)
+		}
+	}
+}
+
+func TestPanicLogIsRedacted(t *testing.T) {
+	buf := captureLogs(t, false)
+	tx := Transaction{Merchant: "m", AccountID: secretAccount, Counterparty: secretCounterparty}
+	for _, v := range []any{tx, fmt.Sprintf("bad transaction %v", tx), fmt.Errorf("bad transaction %+v", tx)} {
+		h := recoverMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic(v) }))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	out := buf.String()
+	if strings.Count(out, "panic serving request") != 3 {
+		t.Fatalf("panics not logged:\n%s", out)
+	}
+	if strings.Contains(out, secretAccount) || strings.Contains(out, secretCounterparty) {
+		t.Fatalf("panic log contains a full identifier:\n%s", out)
+	}
+}
+
+func TestMask(t *testing.T) {
+	for in, want := range map[string]string{
+		"":            "",
+		"1234":        "****",
+		"12345":       "*2345",
+		"ACCT-987654": "*******7654",
+		"Zoë Müller":  "******ller",
+	} {
+		if got := mask(in); got != want {
+			t.Errorf("mask(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// replaySampleSize caps the number of changed decisions listed in a /replay
+// response.
+const replaySampleSize = 100
+
+// ReplaySummary is the response body of POST /replay.
+type ReplaySummary struct {
+	Total     int            `json:"total"`
+	Changed   int            `json:"changed"`
+	Committed bool           `json:"committed"`
+	Sample    []ReplayChange `json:"sample"`
+}
+
+// ReplayChange is one stored decision that would change under the current
+// rules.
+type ReplayChange struct {
+	Transaction Transaction `json:"transaction"`
+	Before      Decision    `json:"before"`
+	After       Decision    `json:"after"`
+}
+
+// replay serves POST /replay: it re-decides every stored transaction under
+// the current rules and settings and reports how many would change level.
+// Stored decisions are only updated with ?commit=true.
+//
+// Replay uses decide and the merchant floor, not evaluate: the velocity,
+// daily-limit, account-anomaly and merchant-outlier checks depend on the
+// history as it was when the transaction arrived, which the store no longer
+// reflects. A stored decision given by one of those rules is therefore never
+// lowered; replay only raises it. The SQLite store also keeps amounts only to
+// the cent, so the sub-unit precision check does not fire on replay there.
+func (s *Server) replay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	commit := false
+	if v := r.URL.Query().Get("commit"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "commit must be true or false")
+			return
+		}
+		commit = b
+	}
+
+	summary := ReplaySummary{Committed: commit, Sample: []ReplayChange{}}
+	tn := s.tenant(r.Context())
+	err := tn.store.Rescore(r.Context(), commit, func(rec Record) Record {
+		summary.Total++
+		d, _ := tn.decide(r.Context(), rec.Transaction)
+		d = applyMerchantFloor(d, rec.Transaction.Merchant)
+		if d.RiskLevel == rec.RiskLevel {
+			return rec
+		}
+		if historyBased(rec.Reason) && levelRank[d.RiskLevel] < levelRank[rec.RiskLevel] {
+			return rec
+		}
+		summary.Changed++
+		if len(summary.Sample) < replaySampleSize {
+			summary.Sample = append(summary.Sample, ReplayChange{
+				Transaction: rec.Transaction,
+				Before:      Decision{RiskLevel: rec.RiskLevel, Reason: rec.Reason}.stamped(rec.Timestamp),
+				After:       d.stamped(now()),
+			})
+		}
+		rec.RiskLevel, rec.Reason = d.RiskLevel, d.Reason
+		return rec
+	})
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	logFor(r.Context()).Info("replayed decisions", "total", summary.Total, "changed", summary.Changed, "committed", commit)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayKeepsVelocityDecision(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	tx := Transaction{Amount: dollars(20), Merchant: "Corner Shop", AccountID: "acct-1"}
+	for i := 0; i < 6; i++ {
+		processAt(t, tn, noon.Add(time.Duration(i)*time.Second), tx)
+	}
+	processAt(t, tn, noon.Add(time.Hour), Transaction{Amount: dollars(1500), Merchant: "m"})
+	if rec := do(api, "PATCH", "/config", `{"medium_threshold": 5000, "high_threshold": 20000}`); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH /config: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(api, "POST", "/replay?commit=true", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := decode[ReplaySummary](t, rec); got.Total != 7 || got.Changed != 1 {
+		t.Fatalf("summary %+v, want 7 total and 1 changed", got)
+	}
+	records, err := tn.store.Recent(t.Context(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var velocity, threshold int
+	for _, r := range records {
+		switch {
+		case strings.HasPrefix(r.Reason, "velocity:"):
+			velocity++
+			if r.RiskLevel != "HIGH" {
+				t.Errorf("velocity decision lowered to %s", r.RiskLevel)
+			}
+		case r.Transaction.Merchant == "m":
+			threshold++
+			if r.RiskLevel != "LOW" {
+				t.Errorf("threshold decision not re-decided: %s (%s)", r.RiskLevel, r.Reason)
+			}
+		}
+	}
+	if velocity != 1 || threshold != 1 {
+		t.Fatalf("got %d velocity and %d threshold records, want 1 each", velocity, threshold)
+	}
+}
+
+func TestReplayAppliesMerchantFloor(t *testing.T) {
+	api, tn := newTestAPI(t)
+	processAt(t, tn, noon, Transaction{Amount: dollars(10), Merchant: "Corner Shop"})
+	setVar(t, &merchantFloor, map[string]string{"corner shop": "MEDIUM"})
+
+	rec := do(api, "POST", "/replay?commit=true", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := decode[ReplaySummary](t, rec); got.Changed != 1 {
+		t.Fatalf("summary %+v, want 1 changed", got)
+	}
+	records, err := tn.store.Recent(t.Context(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].RiskLevel != "MEDIUM" {
+		t.Fatalf("records %+v, want one MEDIUM record", records)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ctrThreshold is the same-day per-account total above which a Currency
+// Transaction Report is required.
+var ctrThreshold = dollars(10000)
+
+// reportLocation is the timezone whose midnight bounds a reporting day.
+var reportLocation = time.UTC
+
+// CTRReport lists the accounts whose same-day totals exceed ctrThreshold.
+type CTRReport struct {
+	Date      string       `json:"date"`
+	Timezone  string       `json:"timezone"`
+	Threshold Money        `json:"threshold"`
+	Accounts  []CTRAccount `json:"accounts"`
+}
+
+// CTRAccount is one account's aggregate for the report day.
+type CTRAccount struct {
+	AccountID string `json:"account_id"`
+	Total     Money  `json:"total"`
+	Count     int    `json:"transaction_count"`
+}
+
+// ctrReport handles GET /reports/ctr?date=YYYY-MM-DD.
+func (s *Server) ctrReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	day, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("date"), reportLocation)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "date must be given as YYYY-MM-DD")
+		return
+	}
+
+	report, err := buildCTRReport(r.Context(), s.tenant(r.Context()).store, day)
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// buildCTRReport aggregates the store's transactions for day, which must be
+// midnight in reportLocation.
+func buildCTRReport(ctx context.Context, s Store, day time.Time) (CTRReport, error) {
+	report := CTRReport{
+		Date:      day.Format("2006-01-02"),
+		Timezone:  reportLocation.String(),
+		Threshold: ctrThreshold,
+		Accounts:  []CTRAccount{},
+	}
+	totals, err := s.AccountTotals(ctx, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return CTRReport{}, err
+	}
+	for account, at := range totals {
+		if at.Total > ctrThreshold {
+			report.Accounts = append(report.Accounts, CTRAccount{AccountID: account, Total: at.Total, Count: at.Count})
+		}
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		a, b := report.Accounts[i], report.Accounts[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.AccountID < b.AccountID
+	})
+	return report, nil
+}
+
+// MerchantReport is the response body of GET /reports/merchants.
+type MerchantReport struct {
+	Merchants []MerchantRisk `json:"merchants"`
+}
+
+// MerchantRisk is one merchant's decisions over the report range.
+type MerchantRisk struct {
+	Merchant string      `json:"merchant"`
+	Count    int         `json:"transaction_count"`
+	ByLevel  LevelCounts `json:"by_level"`
+}
+
+// merchantReport handles GET /reports/merchants: per-merchant decision counts,
+// most HIGH decisions first. ?from= and ?to= (RFC 3339) bound the transaction
+// timestamp to [from, to) and ?limit= caps the number of merchants.
+func (s *Server) merchantReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+	var from, to time.Time
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if v := query.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*p.dst = t
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		writeError(w, http.StatusUnprocessableEntity, "from must not be after to")
+		return
+	}
+
+	report, err := buildMerchantReport(r.Context(), s.tenant(r.Context()).store, from, to, limit)
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// buildMerchantReport ranks merchants by HIGH decisions, then by transaction
+// count. A limit of zero keeps every merchant.
+func buildMerchantReport(ctx context.Context, s Store, from, to time.Time, limit int) (MerchantReport, error) {
+	totals, err := s.MerchantTotals(ctx, from, to)
+	if err != nil {
+		return MerchantReport{}, err
+	}
+	report := MerchantReport{Merchants: make([]MerchantRisk, 0, len(totals))}
+	for _, mt := range totals {
+		c := mt.Levels
+		report.Merchants = append(report.Merchants, MerchantRisk{Merchant: mt.Merchant, Count: c.Low + c.Medium + c.High, ByLevel: c})
+	}
+	sort.Slice(report.Merchants, func(i, j int) bool {
+		a, b := report.Merchants[i], report.Merchants[j]
+		if a.ByLevel.High != b.ByLevel.High {
+			return a.ByLevel.High > b.ByLevel.High
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Merchant < b.Merchant
+	})
+	if limit > 0 && len(report.Merchants) > limit {
+		report.Merchants = report.Merchants[:limit]
+	}
+	return report, nil
+}
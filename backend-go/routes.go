@@ -0,0 +1,65 @@
+package main
+
+import "net/http"
+
+// apiVersion prefixes the versioned API routes. The unversioned paths remain
+// as aliases for existing integrators and serve the same handlers.
+const apiVersion = "/v1"
+
+// registerRoutes mounts the service's endpoints on mux. Operational endpoints
+// (/healthz, /readyz, /metrics, /metrics/simple, /openapi.json) are
+// unversioned and unauthenticated; API endpoints are served under apiVersion
+// and at their bare paths.
+func registerRoutes(mux *http.ServeMux, api *Server) {
+	mux.HandleFunc("/healthz", healthz)
+	mux.HandleFunc("/readyz", readyz)
+	mux.HandleFunc("/metrics", metricsHandler)
+	mux.HandleFunc("/metrics/simple", simpleMetricsHandler)
+	mux.HandleFunc("/openapi.json", openAPIHandler)
+
+	for path, h := range apiRoutes(api) {
+		mux.Handle(apiVersion+path, h)
+		mux.Handle(path, h)
+	}
+}
+
+// apiRoutes returns the API endpoints by unversioned path. Paths ending in a
+// slash serve a subtree.
+func apiRoutes(api *Server) map[string]http.Handler {
+	// admin applies rate limiting, API-key auth, tenant resolution and
+	// signature checks to an endpoint that changes the tenant's own rules.
+	// It ignores X-Ruleset, since the alternate rule sets are shared by every
+	// tenant.
+	admin := func(h http.Handler) http.Handler {
+		return rateLimit(requireAPIKey(api.requireTenant(requireSignature(h))))
+	}
+	// protect is admin plus X-Ruleset selection, for a data endpoint.
+	protect := func(h http.Handler) http.Handler {
+		return admin(api.withRuleset(h))
+	}
+	return map[string]http.Handler{
+		"/risk":              instrument(protect(http.HandlerFunc(api.checkRisk))),
+		"/risk/batch":        instrument(protect(gzipMiddleware(http.HandlerFunc(api.checkBatch)))),
+		"/risk/whatif":       protect(http.HandlerFunc(api.whatif)),
+		"/risk/upload":       instrument(protect(http.HandlerFunc(api.uploadCSV))),
+		"/jobs":              protect(http.HandlerFunc(api.jobsHandler)),
+		"/jobs/":             protect(http.HandlerFunc(api.jobsHandler)),
+		"/explain":           protect(http.HandlerFunc(api.explain)),
+		"/transactions":      protect(gzipMiddleware(http.HandlerFunc(api.listTransactions))),
+		"/transactions/":     protect(http.HandlerFunc(api.transactionStatus)),
+		"/rules":             protect(http.HandlerFunc(api.rulesHandler)),
+		"/rules/reload":      admin(http.HandlerFunc(api.reloadRules)),
+		"/config":            protect(http.HandlerFunc(api.configHandler)),
+		"/audit":             protect(http.HandlerFunc(api.auditHandler)),
+		"/reports/ctr":       protect(http.HandlerFunc(api.ctrReport)),
+		"/reports/merchants": protect(http.HandlerFunc(api.merchantReport)),
+		"/stats":             protect(http.HandlerFunc(api.statsHandler)),
+		"/stats/latency":     protect(http.HandlerFunc(latencyHandler)),
+		"/simulate":          protect(http.HandlerFunc(api.simulate)),
+		"/debug/info":        protect(http.HandlerFunc(api.debugInfo)),
+		"/replay":            protect(http.HandlerFunc(api.replay)),
+		"/merchants":         protect(http.HandlerFunc(api.merchants)),
+		"/merchants/allow":   protect(api.merchantList(listAllow)),
+		"/merchants/deny":    protect(api.merchantList(listDeny)),
+	}
+}
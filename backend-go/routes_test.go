@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestVersionedRoutesMatchAliases(t *testing.T) {
+	for _, body := range []string{
+		`{"id": "tx-1", "amount": 50, "merchant": "Corner Shop"}`,
+		`{"id": "tx-2", "amount": 2500, "merchant": "Corner Shop"}`,
+		`{"id": "tx-3", "amount": 600, "merchant": "Starbucks"}`,
+	} {
+		bare, _ := newTestAPI(t)
+		versioned, _ := newTestAPI(t)
+		a, b := do(bare, "POST", "/risk", body), do(versioned, "POST", "/v1/risk", body)
+		if a.Code != http.StatusOK || b.Code != http.StatusOK {
+			t.Fatalf("%s: statuses %d, %d", body, a.Code, b.Code)
+		}
+		if a.Body.String() != b.Body.String() {
+			t.Errorf("%s: /risk returned %s, /v1/risk returned %s", body, a.Body, b.Body)
+		}
+	}
+}
+
+func TestVersionedRoutesRequireAuth(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, path := range []string{"/v1/transactions", "/v1/rules", "/transactions"} {
+		if rec := do(api, "GET", path, "", "X-API-Key", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s with a bad key: status %d, want 401", path, rec.Code)
+		}
+	}
+	if rec := do(api, "GET", "/v1/healthz", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("/v1/healthz: status %d; operational endpoints are unversioned", rec.Code)
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Rule is a single risk condition. It matches when the transaction's merchant
+// equals Merchant and its category code equals MCC (an empty field matches
+// anything), the USD amount satisfies Operator against Amount, and the When
+// expression holds. A rule must have an Operator, a When expression, or both.
+// A Fuzzy rule also matches merchant names within MaxDistance edits of
+// Merchant (default defaultFuzzyDistance), to catch typosquatting.
+type Rule struct {
+	Merchant    string `json:"merchant,omitempty"`
+	Fuzzy       bool   `json:"fuzzy,omitempty"`
+	MaxDistance int    `json:"max_distance,omitempty"`
+	MCC         string `json:"mcc,omitempty"`
+	Operator    string `json:"operator,omitempty"`
+	Amount      Money  `json:"amount"`
+	When        string `json:"when,omitempty"`
+	RiskLevel   string `json:"risk_level"`
+	Priority    int    `json:"priority"`
+	Reason      string `json:"reason,omitempty"`
+
+	cond cond // compiled When; nil if When is empty
+}
+
+// RuleSet is a list of rules in evaluation order; see sortByPriority.
+type RuleSet []Rule
+
+// defaultFuzzyDistance is the edit distance a Fuzzy rule tolerates when it
+// sets no MaxDistance.
+const defaultFuzzyDistance = 1
+
+var validLevels = map[string]bool{"LOW": true, "MEDIUM": true, "HIGH": true}
+
+// levelRank orders the risk levels from least to most severe.
+var levelRank = map[string]int{"LOW": 0, "MEDIUM": 1, "HIGH": 2}
+
+// loadRules reads a JSON array of rules from path, validates it and returns
+// the rules sorted by priority.
+func loadRules(path string) (RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+	return parseRules(data)
+}
+
+// decodeRulesEnv parses the value of RULES_JSON: a base64-encoded JSON array
+// of rules, as read by loadRules.
+func decodeRulesEnv(v string) (RuleSet, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+	if err != nil {
+		return nil, fmt.Errorf("RULES_JSON is not valid base64: %w", err)
+	}
+	rs, err := parseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("RULES_JSON: %w", err)
+	}
+	return rs, nil
+}
+
+// rulesFromEnv returns the rules in $RULES_JSON, or nil when it is unset.
+func rulesFromEnv() (RuleSet, error) {
+	v := os.Getenv("RULES_JSON")
+	if v == "" {
+		return nil, nil
+	}
+	return decodeRulesEnv(v)
+}
+
+// loadTenantRules fills rs with a tenant's rules: from tenantPath when the
+// tenant has its own file, otherwise envRules when RULES_JSON was set, and
+// otherwise the file at fallback. It returns the rules and where they came
+// from.
+func loadTenantRules(rs *ActiveRules, tenantPath string, envRules RuleSet, fallback string) (RuleSet, string, error) {
+	if tenantPath == "" && envRules != nil {
+		rs.Set(envRules)
+		return envRules, "RULES_JSON", nil
+	}
+	path := tenantPath
+	if path == "" {
+		path = fallback
+	}
+	loaded, err := rs.Load(path)
+	return loaded, path, err
+}
+
+func parseRules(data []byte) (RuleSet, error) {
+	var rs RuleSet
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	for i, r := range rs {
+		if r.When != "" {
+			c, err := parseCond(r.When)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: when: %w", i, err)
+			}
+			rs[i].cond = c
+		}
+		if _, ok := operators[r.Operator]; !ok && (r.Operator != "" || r.When == "") {
+			return nil, fmt.Errorf("rule %d: unknown operator %q", i, r.Operator)
+		}
+		if !validLevels[r.RiskLevel] {
+			return nil, fmt.Errorf("rule %d: invalid risk_level %q", i, r.RiskLevel)
+		}
+		if r.Fuzzy && r.Merchant == "" {
+			return nil, fmt.Errorf("rule %d: fuzzy needs a merchant", i)
+		}
+		if r.MaxDistance < 0 || (r.MaxDistance > 0 && !r.Fuzzy) {
+			return nil, fmt.Errorf("rule %d: max_distance needs fuzzy and must not be negative", i)
+		}
+	}
+	sortByPriority(rs)
+	return rs, nil
+}
+
+// sortByPriority orders rs for evaluation: lower Priority first, with rules of
+// equal priority kept in the order they were listed so first-match-wins is
+// deterministic.
+func sortByPriority(rs RuleSet) {
+	sort.SliceStable(rs, func(i, j int) bool { return rs[i].Priority < rs[j].Priority })
+}
+
+var operators = map[string]func(a, b Money) bool{
+	">":  func(a, b Money) bool { return a > b },
+	">=": func(a, b Money) bool { return a >= b },
+	"<":  func(a, b Money) bool { return a < b },
+	"<=": func(a, b Money) bool { return a <= b },
+	"==": func(a, b Money) bool { return a == b },
+}
+
+func (r Rule) matches(t Transaction) bool {
+	ok, _ := r.check(t, false)
+	return ok
+}
+
+// check reports whether r matches t. When explain is set it also says why:
+// the rule's description on a match, otherwise the first condition that
+// failed.
+func (r Rule) check(t Transaction, explain bool) (ok bool, why string) {
+	if r.Merchant != "" && !sameMerchant(r.Merchant, t.Merchant) && !r.nearMatch(t) {
+		if explain {
+			why = fmt.Sprintf("merchant %q is not %q", t.Merchant, r.Merchant)
+		}
+		return false, why
+	}
+	if r.MCC != "" && r.MCC != strings.TrimSpace(t.MCC) {
+		if explain {
+			why = fmt.Sprintf("MCC %q is not %s", t.MCC, describeMCC(r.MCC))
+		}
+		return false, why
+	}
+	if r.Operator != "" {
+		amount := inUSD(t).Amount
+		if !operators[r.Operator](amount, r.Amount) {
+			if explain {
+				why = fmt.Sprintf("amount %s is not %s %s", amount, r.Operator, r.Amount)
+			}
+			return false, why
+		}
+	}
+	if r.cond != nil && !r.cond.eval(t) {
+		if explain {
+			why = "when " + r.When + " is false"
+		}
+		return false, why
+	}
+	if explain {
+		why = r.reasonFor(t)
+	}
+	return true, why
+}
+
+// nearMatch reports whether r is Fuzzy and t's merchant is a near miss of
+// r's.
+func (r Rule) nearMatch(t Transaction) bool {
+	if !r.Fuzzy {
+		return false
+	}
+	d := r.MaxDistance
+	if d == 0 {
+		d = defaultFuzzyDistance
+	}
+	return nearMerchant(r.Merchant, t.Merchant, d)
+}
+
+// reasonFor explains a match of r against t, naming the merchant a near-miss
+// name was likely meant to be.
+func (r Rule) reasonFor(t Transaction) string {
+	if r.nearMatch(t) {
+		return fmt.Sprintf("%s (merchant %q resembles %q)", r.describe(), strings.TrimSpace(t.Merchant), r.Merchant)
+	}
+	return r.describe()
+}
+
+// describe returns the rule's reason, or a generated one if it has none.
+func (r Rule) describe() string {
+	if r.Reason != "" {
+		return r.Reason
+	}
+	if r.When != "" {
+		return "when " + r.When
+	}
+	if r.Merchant != "" {
+		return fmt.Sprintf("amount %s %s at %s", r.Operator, r.Amount, r.Merchant)
+	}
+	if r.MCC != "" {
+		return fmt.Sprintf("amount %s %s for MCC %s", r.Operator, r.Amount, describeMCC(r.MCC))
+	}
+	return fmt.Sprintf("amount %s %s", r.Operator, r.Amount)
+}
+
+// Evaluate returns the decision of the first matching rule in evaluation
+// order. ok is false when no rule matches.
+func (rs RuleSet) Evaluate(t Transaction) (d Decision, ok bool) {
+	return rs.evaluate(t, PolicyFirstMatch, nil)
+}
+
+// EvaluatePolicy is Evaluate under the combining policy. Under
+// PolicyMaxSeverity the most severe matching level wins, explained by the
+// reasons of every match at that level, in evaluation order.
+func (rs RuleSet) EvaluatePolicy(t Transaction, policy string) (d Decision, ok bool) {
+	return rs.evaluate(t, policy, nil)
+}
+
+// RuleTrace is one rule's outcome in an evaluation trace.
+type RuleTrace struct {
+	Rule    Rule   `json:"rule"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+// Trace evaluates every rule against t, not just up to the first match, and
+// returns each outcome in evaluation order along with EvaluatePolicy's
+// result.
+func (rs RuleSet) Trace(t Transaction, policy string) (trace []RuleTrace, d Decision, ok bool) {
+	trace = make([]RuleTrace, 0, len(rs))
+	d, ok = rs.evaluate(t, policy, &trace)
+	return trace, d, ok
+}
+
+// evaluate is EvaluatePolicy, also recording every rule's outcome in trace
+// when it is non-nil.
+func (rs RuleSet) evaluate(t Transaction, policy string, trace *[]RuleTrace) (d Decision, ok bool) {
+	var reasons []string
+	for _, r := range rs {
+		matched, why := r.check(t, trace != nil)
+		if trace != nil {
+			*trace = append(*trace, RuleTrace{Rule: r, Matched: matched, Reason: why})
+		}
+		if !matched {
+			continue
+		}
+		switch {
+		case !ok:
+			d, ok = Decision{RiskLevel: r.RiskLevel}, true
+			reasons = []string{r.reasonFor(t)}
+		case policy != PolicyMaxSeverity:
+		case levelRank[r.RiskLevel] > levelRank[d.RiskLevel]:
+			d.RiskLevel = r.RiskLevel
+			reasons = []string{r.reasonFor(t)}
+		case r.RiskLevel == d.RiskLevel:
+			reasons = append(reasons, r.reasonFor(t))
+		}
+		if trace == nil && policy != PolicyMaxSeverity {
+			break
+		}
+	}
+	d.Reason = strings.Join(reasons, "; ")
+	return d, ok
+}
+
+// ActiveRules holds the rule set in use and the file it was loaded from. It
+// is safe for concurrent use. The set is published through an atomic pointer,
+// so scoring never waits on a reload and always sees one whole rule set.
+type ActiveRules struct {
+	rules atomic.Pointer[RuleSet]
+
+	// mu serializes loads so path always names the file of the newest set.
+	mu   sync.Mutex
+	path string
+}
+
+// Get returns the current rule set. Callers must not modify it.
+func (a *ActiveRules) Get() RuleSet {
+	if rs := a.rules.Load(); rs != nil {
+		return *rs
+	}
+	return nil
+}
+
+// Load reads the rules at path and, if they are valid, makes them the active
+// set and remembers path for Reload. On error the active set is unchanged.
+func (a *ActiveRules) Load(path string) (RuleSet, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rs, err := loadRules(path)
+	if err != nil {
+		return nil, err
+	}
+	a.path = path
+	a.rules.Store(&rs)
+	return rs, nil
+}
+
+// Set makes rs the active set. Having no file, it cannot be reloaded.
+func (a *ActiveRules) Set(rs RuleSet) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.path = ""
+	a.rules.Store(&rs)
+}
+
+// Reload re-reads the file passed to the last successful Load.
+func (a *ActiveRules) Reload() (RuleSet, error) {
+	a.mu.Lock()
+	path := a.path
+	a.mu.Unlock()
+	if path == "" {
+		return nil, errors.New("no rules file loaded")
+	}
+	return a.Load(path)
+}
+
+// RulesView is the response body of GET /rules. Warnings name the rules that
+// earlier ones shadow.
+type RulesView struct {
+	Rules    RuleSet  `json:"rules"`
+	Warnings []string `json:"warnings"`
+}
+
+// rulesHandler serves GET /rules: the tenant's active rules in evaluation
+// order, with any shadowed-rule warnings.
+func (s *Server) rulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rs := s.tenant(r.Context()).rules.Get()
+	if rs == nil {
+		rs = RuleSet{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RulesView{Rules: rs, Warnings: rs.shadowWarnings()})
+}
+
+// reloadResponse is the body of a successful POST /rules/reload.
+type reloadResponse struct {
+	Count    int      `json:"count"`
+	Warnings []string `json:"warnings"`
+}
+
+// reloadRules serves POST /rules/reload: it re-reads the rules file and swaps
+// it in. An invalid file leaves the current rules active and returns 422.
+func (s *Server) reloadRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rs, err := s.tenant(r.Context()).rules.Reload()
+	if err != nil {
+		logFor(r.Context()).Warn("rules reload failed", "error", err)
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	tn := s.tenant(r.Context())
+	logFor(r.Context()).Info("reloaded rules", "count", len(rs))
+	res := reloadResponse{Count: len(rs), Warnings: logRuleWarnings(logFor(r.Context()), tn.ID, rs)}
+	auditLog.Record(r, auditRulesReload, res)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
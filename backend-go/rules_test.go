@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// hardcodedLevel is the decision logic rules.json replaced.
+func hardcodedLevel(t Transaction) string {
+	switch {
+	case t.Merchant == "Starbucks" && t.Amount > dollars(500):
+		return "HIGH"
+	case t.Merchant == "Apple Store" && t.Amount < dollars(5000):
+		return "LOW"
+	case t.Amount > dollars(10000):
+		return "HIGH"
+	case t.Amount > dollars(1000):
+		return "MEDIUM"
+	}
+	return "LOW"
+}
+
+func TestSampleRulesMatchHardcodedLogic(t *testing.T) {
+	rs, err := loadRules("rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amounts := []Money{0, 1250, 50000, 50001, 100000, 100001, 499900, 500000, 1000000, 1000001, 1500000}
+	for _, merchant := range []string{"Starbucks", "Apple Store", "Corner Shop"} {
+		for _, amount := range amounts {
+			tx := Transaction{Merchant: merchant, Amount: amount}
+			d, ok := rs.Evaluate(tx)
+			if !ok {
+				d = applyThresholds(tx, defaultSettings)
+			}
+			if want := hardcodedLevel(tx); d.RiskLevel != want {
+				t.Errorf("%s $%s: got %s, want %s", merchant, amount, d.RiskLevel, want)
+			}
+		}
+	}
+}
+
+func TestParseRulesRejectsInvalidRules(t *testing.T) {
+	for _, tc := range []struct{ name, json string }{
+		{"not an array", `{"operator": ">"}`},
+		{"unknown operator", `[{"operator": "~", "amount": 1, "risk_level": "HIGH"}]`},
+		{"no condition", `[{"amount": 1, "risk_level": "HIGH"}]`},
+		{"bad level", `[{"operator": ">", "amount": 1, "risk_level": "SEVERE"}]`},
+	} {
+		if _, err := parseRules([]byte(tc.json)); err == nil {
+			t.Errorf("%s: parsed without error", tc.name)
+		}
+	}
+}
+
+func TestRulePriorityOrdering(t *testing.T) {
+	rs, err := parseRules([]byte(`[
+		{"operator": ">", "amount": 1000, "risk_level": "MEDIUM", "priority": 20, "reason": "big"},
+		{"merchant": "Apple Store", "operator": "<", "amount": 5000, "risk_level": "LOW", "priority": 10, "reason": "apple"},
+		{"operator": ">", "amount": 100, "risk_level": "HIGH", "priority": 30, "reason": "first at 30"},
+		{"operator": ">", "amount": 100, "risk_level": "LOW", "priority": 30, "reason": "second at 30"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		tx     Transaction
+		reason string
+	}{
+		{Transaction{Merchant: "Apple Store", Amount: dollars(4000)}, "apple"},
+		{Transaction{Merchant: "m", Amount: dollars(4000)}, "big"},
+		{Transaction{Merchant: "m", Amount: dollars(500)}, "first at 30"},
+	} {
+		d, ok := rs.Evaluate(tc.tx)
+		if !ok || d.Reason != tc.reason {
+			t.Errorf("%s $%s: got %q (matched %v), want %q", tc.tx.Merchant, tc.tx.Amount, d.Reason, ok, tc.reason)
+		}
+	}
+}
+
+func TestGetRulesShowsEffectiveOrder(t *testing.T) {
+	api, tn := newTestAPI(t)
+	rs, err := parseRules([]byte(`[
+		{"operator": ">", "amount": 1, "risk_level": "LOW", "priority": 5, "reason": "b"},
+		{"operator": ">", "amount": 1, "risk_level": "LOW", "priority": 1, "reason": "a"},
+		{"operator": ">", "amount": 1, "risk_level": "LOW", "priority": 5, "reason": "c"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tn.rules.Set(rs)
+	view := decode[RulesView](t, do(api, "GET", "/rules", ""))
+	var got []string
+	for _, r := range view.Rules {
+		got = append(got, r.Reason)
+	}
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Fatalf("GET /rules order %v, want [a b c]", got)
+	}
+}
+
+func TestRulesReload(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	path := filepath.Join(t.TempDir(), "rules.json")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`[{"operator": ">", "amount": 1, "risk_level": "LOW"}]`)
+	if _, err := tn.rules.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if view := decode[RulesView](t, do(api, "GET", "/rules", "")); len(view.Rules) != 1 {
+		t.Fatalf("GET /rules: %d rules, want 1", len(view.Rules))
+	}
+
+	write(`[{"operator": ">", "amount": 1, "risk_level": "LOW"}, {"operator": ">", "amount": 2, "risk_level": "HIGH"}]`)
+	rec := do(api, "POST", "/rules/reload", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reload: status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[reloadResponse](t, rec); res.Count != 2 {
+		t.Fatalf("reload count %d, want 2", res.Count)
+	}
+
+	write(`[{"operator": "~", "amount": 1, "risk_level": "LOW"}]`)
+	rec = do(api, "POST", "/rules/reload", "")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid file: status %d, want 422", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "unknown operator") {
+		t.Fatalf("422 body lacks the parse error: %s", rec.Body)
+	}
+	if n := len(tn.rules.Get()); n != 2 {
+		t.Fatalf("failed reload left %d rules active, want the previous 2", n)
+	}
+}
+
+func TestRulesReloadUnderLoad(t *testing.T) {
+	tn := newTestTenant(t, defaultTenant)
+	dir := t.TempDir()
+	sets := map[string]string{
+		"a.json": `[{"operator": ">", "amount": 0, "risk_level": "LOW", "reason": "set a"}, {"operator": ">", "amount": 0, "risk_level": "HIGH", "reason": "set a shadowed"}]`,
+		"b.json": `[{"operator": ">", "amount": 0, "risk_level": "HIGH", "reason": "set b"}]`,
+	}
+	for name, body := range sets {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tn.rules.Load(filepath.Join(dir, "a.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	consistent := map[Decision]bool{
+		{RiskLevel: "LOW", Reason: "set a"}:  true,
+		{RiskLevel: "HIGH", Reason: "set b"}: true,
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				d, err := tn.evaluate(context.Background(), Transaction{Amount: dollars(10), Merchant: "m"})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !consistent[Decision{RiskLevel: d.RiskLevel, Reason: d.Reason}] {
+					t.Errorf("inconsistent decision %s (%s)", d.RiskLevel, d.Reason)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		name := "a.json"
+		if i%2 == 0 {
+			name = "b.json"
+		}
+		if _, err := tn.rules.Load(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestRulesFromEnv(t *testing.T) {
+	t.Setenv("RULES_JSON", "")
+	if rs, err := rulesFromEnv(); rs != nil || err != nil {
+		t.Fatalf("unset: got %v, %v", rs, err)
+	}
+
+	t.Setenv("RULES_JSON", base64.StdEncoding.EncodeToString([]byte(`[{"operator": ">", "amount": 1, "risk_level": "HIGH", "reason": "from env"}]`)))
+	rs, err := rulesFromEnv()
+	if err != nil || len(rs) != 1 || rs[0].Reason != "from env" {
+		t.Fatalf("valid: got %v, %v", rs, err)
+	}
+
+	for name, v := range map[string]string{
+		"not base64":    "not base64!",
+		"not JSON":      base64.StdEncoding.EncodeToString([]byte(`{`)),
+		"invalid rules": base64.StdEncoding.EncodeToString([]byte(`[{"operator": "~", "amount": 1, "risk_level": "LOW"}]`)),
+	} {
+		t.Setenv("RULES_JSON", v)
+		if _, err := rulesFromEnv(); err == nil || !strings.Contains(err.Error(), "RULES_JSON") {
+			t.Errorf("%s: error %v does not name RULES_JSON", name, err)
+		}
+	}
+}
+
+func TestLoadTenantRulesPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name, reason string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(`[{"operator": ">", "amount": 1, "risk_level": "LOW", "reason": "`+reason+`"}]`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	fallback, tenantFile := file("rules.json", "file"), file("acme.json", "tenant")
+	env := RuleSet{{Operator: ">", Amount: 1, RiskLevel: "HIGH", Reason: "env"}}
+
+	for _, tc := range []struct {
+		name       string
+		tenantPath string
+		env        RuleSet
+		want       string
+	}{
+		{"env over -rules", "", env, "env"},
+		{"-rules without env", "", nil, "file"},
+		{"tenant file over env", tenantFile, env, "tenant"},
+	} {
+		rs := &ActiveRules{}
+		if _, _, err := loadTenantRules(rs, tc.tenantPath, tc.env, fallback); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := rs.Get()[0].Reason; got != tc.want {
+			t.Errorf("%s: rules from %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCombinePolicy(t *testing.T) {
+	rs, err := parseRules([]byte(`[
+		{"operator": ">", "amount": 100, "risk_level": "MEDIUM", "priority": 1, "reason": "over 100"},
+		{"merchant": "Lucky Casino", "operator": ">", "amount": 0, "risk_level": "HIGH", "priority": 2, "reason": "casino"},
+		{"operator": ">", "amount": 200, "risk_level": "HIGH", "priority": 3, "reason": "over 200"},
+		{"operator": ">", "amount": 0, "risk_level": "LOW", "priority": 4, "reason": "any"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		tx            Transaction
+		policy        string
+		level, reason string
+	}{
+		{Transaction{Merchant: "Lucky Casino", Amount: dollars(300)}, PolicyFirstMatch, "MEDIUM", "over 100"},
+		{Transaction{Merchant: "Lucky Casino", Amount: dollars(300)}, PolicyMaxSeverity, "HIGH", "casino; over 200"},
+		{Transaction{Merchant: "m", Amount: dollars(150)}, PolicyMaxSeverity, "MEDIUM", "over 100"},
+		{Transaction{Merchant: "m", Amount: dollars(50)}, PolicyMaxSeverity, "LOW", "any"},
+	} {
+		d, ok := rs.EvaluatePolicy(tc.tx, tc.policy)
+		if !ok || d.RiskLevel != tc.level || d.Reason != tc.reason {
+			t.Errorf("%s %s $%s: got %s %q (matched %v), want %s %q", tc.policy, tc.tx.Merchant, tc.tx.Amount, d.RiskLevel, d.Reason, ok, tc.level, tc.reason)
+		}
+	}
+}
+
+func TestCombinePolicyConfig(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	rs, err := parseRules([]byte(`[
+		{"operator": ">", "amount": 100, "risk_level": "LOW", "priority": 1, "reason": "routine"},
+		{"mcc": "7995", "operator": ">", "amount": 0, "risk_level": "HIGH", "priority": 2, "reason": "gambling"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tn.rules.Set(rs)
+	body := `{"amount": 500, "merchant": "Lucky Casino", "mcc": "7995"}`
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "LOW" {
+		t.Fatalf("first-match: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	if rec := do(api, "PATCH", "/config", `{"combine_policy": "max-severity"}`); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH: status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "HIGH" || res.Reason != "gambling" {
+		t.Fatalf("max-severity: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	if rec := do(api, "PATCH", "/config", `{"combine_policy": "vote"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown policy: status %d, want 422", rec.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rulesetHeader names an alternate rule set to score one request with, so a
+// gateway can canary new rules on part of the traffic.
+const rulesetHeader = "X-Ruleset"
+
+// alternateRuleSets holds the rule sets requests may select with
+// rulesetHeader, keyed by name. They are loaded once at startup and shared by
+// every tenant, so no request may change them: /rules/reload is not served
+// through withRuleset.
+var alternateRuleSets = map[string]*ActiveRules{}
+
+// loadRuleSets loads every <name>.json in dir as the alternate rule set name.
+func loadRuleSets(dir string) (map[string]*ActiveRules, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("read rule sets: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("read rule sets: %w", err)
+	}
+	sets := make(map[string]*ActiveRules, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		rs := &ActiveRules{}
+		if _, err := rs.Load(path); err != nil {
+			return nil, fmt.Errorf("rule set %q: %w", name, err)
+		}
+		sets[name] = rs
+	}
+	return sets, nil
+}
+
+// withRuleset scores the request with the alternate rule set named by
+// rulesetHeader, if any, by giving it a view of its tenant that uses those
+// rules. History, thresholds and velocity stay the tenant's own. An unknown
+// name is answered with 400. It must run inside requireTenant.
+func (s *Server) withRuleset(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSpace(r.Header.Get(rulesetHeader))
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rs, ok := alternateRuleSets[name]
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown rule set %q", name))
+			return
+		}
+		canary := *s.tenant(r.Context())
+		canary.rules = rs
+		logFor(r.Context()).Debug("using alternate rule set", "ruleset", name)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey, &canary)))
+	})
+}
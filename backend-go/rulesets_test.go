@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadTestRuleSets writes a strict rule set flagging Starbucks above $100 and
+// loads it as the alternate set "strict". It returns the file's path.
+func loadTestRuleSets(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "strict.json")
+	if err := os.WriteFile(path, []byte(`[{"merchant": "Starbucks", "operator": ">", "amount": 100, "risk_level": "HIGH", "reason": "strict coffee"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sets, err := loadRuleSets(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setVar(t, &alternateRuleSets, sets)
+	return path
+}
+
+func TestRulesetHeaderSelectsAlternate(t *testing.T) {
+	api, tn := newTestAPI(t)
+	loadTestRuleSets(t)
+	body := `{"amount": 300, "merchant": "Starbucks"}`
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body)); res.RiskLevel != "LOW" {
+		t.Fatalf("default rules: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body, rulesetHeader, "strict")); res.RiskLevel != "HIGH" || res.Reason != "strict coffee" {
+		t.Fatalf("strict rules: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	// The canary decision is recorded in the tenant's own history.
+	if n := tn.store.(*MemoryStore).Len(); n != 2 {
+		t.Fatalf("%d records, want 2", n)
+	}
+	if rec := do(api, "POST", "/risk", body, rulesetHeader, "nope"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown rule set: status %d, want 400", rec.Code)
+	}
+}
+
+func TestRulesReloadIgnoresRulesetHeader(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	path := loadTestRuleSets(t)
+	// A reload naming the alternate set must not re-read or replace it.
+	if err := os.WriteFile(path, []byte(`[]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rec := do(api, "POST", "/rules/reload", "", rulesetHeader, "strict")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := decode[reloadResponse](t, rec); got.Count != len(tn.rules.Get()) || got.Count != 2 {
+		t.Fatalf("reloaded %d rules, want the tenant's 2", got.Count)
+	}
+	if n := len(alternateRuleSets["strict"].Get()); n != 1 {
+		t.Fatalf("alternate set now has %d rules, want 1", n)
+	}
+}
+
+func TestLoadRuleSetsErrors(t *testing.T) {
+	if _, err := loadRuleSets(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("missing directory accepted")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`[{"operator": "~"}]`), 0o600)
+	if _, err := loadRuleSets(dir); err == nil {
+		t.Fatal("invalid rule set accepted")
+	}
+}
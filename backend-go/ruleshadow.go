@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// amountRange is the closed range of cents an amount condition accepts.
+type amountRange struct {
+	lo, hi int64
+}
+
+// amountRangeOf returns the amounts r's Operator and Amount accept.
+func amountRangeOf(r Rule) amountRange {
+	a := int64(r.Amount)
+	switch r.Operator {
+	case ">":
+		return amountRange{a + 1, math.MaxInt64}
+	case ">=":
+		return amountRange{a, math.MaxInt64}
+	case "<":
+		return amountRange{math.MinInt64, a - 1}
+	case "<=":
+		return amountRange{math.MinInt64, a}
+	case "==":
+		return amountRange{a, a}
+	}
+	return amountRange{math.MinInt64, math.MaxInt64}
+}
+
+func (r amountRange) contains(o amountRange) bool {
+	return r.lo <= o.lo && o.hi <= r.hi
+}
+
+// fuzzyDistance is how many edits away from Merchant r still matches.
+func (r Rule) fuzzyDistance() int {
+	switch {
+	case !r.Fuzzy:
+		return 0
+	case r.MaxDistance == 0:
+		return defaultFuzzyDistance
+	}
+	return r.MaxDistance
+}
+
+// covers reports whether every transaction b matches is certain to match r
+// too. It is conservative: When expressions only cover identical ones, so a
+// false result does not prove b is reachable.
+func (r Rule) covers(b Rule) bool {
+	if r.Merchant != "" && (b.Merchant == "" || !sameMerchant(r.Merchant, b.Merchant) || r.fuzzyDistance() < b.fuzzyDistance()) {
+		return false
+	}
+	if r.MCC != "" && r.MCC != b.MCC {
+		return false
+	}
+	if r.When != "" && r.When != b.When {
+		return false
+	}
+	return amountRangeOf(r).contains(amountRangeOf(b))
+}
+
+// shadowWarnings lists the rules of rs that can never decide a transaction
+// under first-match evaluation because an earlier rule matches everything
+// they do. Rules are numbered from 0 in evaluation order, as GET /rules
+// lists them.
+func (rs RuleSet) shadowWarnings() []string {
+	warnings := []string{}
+	for j, b := range rs {
+		for i, a := range rs[:j] {
+			if a.covers(b) {
+				warnings = append(warnings, fmt.Sprintf("rule %d (%s) is shadowed by rule %d (%s), which matches every transaction it does and is evaluated first",
+					j, b.describe(), i, a.describe()))
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// logRuleWarnings logs the shadowed rules of a newly loaded set.
+func logRuleWarnings(log *slog.Logger, tenant string, rs RuleSet) []string {
+	warnings := rs.shadowWarnings()
+	for _, w := range warnings {
+		log.Warn("unreachable rule", "tenant", tenant, "warning", w)
+	}
+	return warnings
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShadowWarnings(t *testing.T) {
+	for _, tc := range []struct {
+		name, rules string
+		shadowed    []int
+	}{
+		{"broader amount first", `[
+			{"merchant": "Starbucks", "operator": ">", "amount": 100, "risk_level": "MEDIUM"},
+			{"merchant": "starbucks ", "operator": ">", "amount": 500, "risk_level": "HIGH"}
+		]`, []int{1}},
+		{"catch-all first", `[
+			{"operator": ">=", "amount": 0, "risk_level": "LOW", "priority": 1},
+			{"mcc": "7995", "operator": ">", "amount": 0, "risk_level": "HIGH", "priority": 2},
+			{"merchant": "m", "operator": "==", "amount": 5, "risk_level": "HIGH", "priority": 3}
+		]`, []int{1, 2}},
+		{"exact rule before a wider fuzzy one", `[
+			{"merchant": "Starbucks", "operator": ">", "amount": 100, "risk_level": "HIGH"},
+			{"merchant": "Starbucks", "fuzzy": true, "operator": ">", "amount": 100, "risk_level": "MEDIUM"}
+		]`, nil},
+		{"clean", `[
+			{"merchant": "Starbucks", "operator": ">", "amount": 500, "risk_level": "HIGH"},
+			{"merchant": "Apple Store", "operator": "<", "amount": 5000, "risk_level": "LOW"},
+			{"mcc": "7995", "operator": ">", "amount": 0, "risk_level": "MEDIUM"},
+			{"operator": ">", "amount": 100000, "risk_level": "HIGH", "when": "currency == \"USD\""}
+		]`, nil},
+	} {
+		rs, err := parseRules([]byte(tc.rules))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := rs.shadowWarnings()
+		if len(got) != len(tc.shadowed) {
+			t.Errorf("%s: warnings %q, want rules %v shadowed", tc.name, got, tc.shadowed)
+			continue
+		}
+		for i, j := range tc.shadowed {
+			if !strings.HasPrefix(got[i], fmt.Sprintf("rule %d ", j)) {
+				t.Errorf("%s: warning %q, want rule %d", tc.name, got[i], j)
+			}
+		}
+	}
+}
+
+func TestShadowWarningsSurfaced(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &auditLog, &AuditLog{})
+	logs := captureLogs(t, false)
+	if v := decode[RulesView](t, do(api, "GET", "/rules", "")); len(v.Warnings) != 0 {
+		t.Fatalf("rules.json has warnings: %q", v.Warnings)
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`[
+		{"operator": ">", "amount": 100, "risk_level": "MEDIUM"},
+		{"merchant": "Starbucks", "operator": ">", "amount": 500, "risk_level": "HIGH"}
+	]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tn.rules.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	res := decode[reloadResponse](t, do(api, "POST", "/rules/reload", ""))
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "rule 1") {
+		t.Fatalf("reload warnings %q", res.Warnings)
+	}
+	if !strings.Contains(logs.String(), "unreachable rule") {
+		t.Fatalf("warning not logged:\n%s", logs)
+	}
+	if v := decode[RulesView](t, do(api, "GET", "/rules", "")); len(v.Warnings) != 1 || v.Warnings[0] != res.Warnings[0] {
+		t.Fatalf("GET /rules warnings %q", v.Warnings)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema used to check request payloads before
+// they are decoded. It marshals to a standard JSON Schema document.
+type Schema struct {
+	Type                 []string           `json:"type"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// Violation is one way a payload fails its schema.
+type Violation struct {
+	// Field is the path to the offending value, e.g. "amount" or
+	// "[2].merchant". It is empty for the payload itself.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Field == "" {
+		return v.Message
+	}
+	return v.Field + ": " + v.Message
+}
+
+var noAdditional = false
+
+// transactionSchema describes a Transaction payload. Amounts may be numbers
+// or numeric strings, matching Money's decoding.
+var transactionSchema = &Schema{
+	Type: []string{"object"},
+	Properties: map[string]*Schema{
+		"id":           {Type: []string{"string"}},
+		"amount":       {Type: []string{"number", "string"}},
+		"merchant":     {Type: []string{"string"}},
+		"currency":     {Type: []string{"string"}},
+		"mcc":          {Type: []string{"string"}},
+		"counterparty": {Type: []string{"string"}},
+		"account_id":   {Type: []string{"string"}},
+		"country":      {Type: []string{"string"}},
+		"timestamp":    {Type: []string{"string"}, Format: "date-time"},
+		"type":         {Type: []string{"string"}, Enum: []string{TypeDebit, TypeCredit}},
+	},
+	Required:             []string{"amount"},
+	AdditionalProperties: &noAdditional,
+}
+
+// batchSchema describes a /risk/batch payload.
+var batchSchema = &Schema{Type: []string{"array"}, Items: transactionSchema}
+
+// Validate checks the JSON document data against s and returns every
+// violation found, in a stable order.
+func (s *Schema) Validate(data []byte) []Violation {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []Violation{{Message: "invalid JSON"}}
+	}
+	var vs []Violation
+	s.check("", v, &vs)
+	return vs
+}
+
+func (s *Schema) check(path string, v any, vs *[]Violation) {
+	got := jsonType(v)
+	if !s.allows(got) {
+		*vs = append(*vs, Violation{Field: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(s.Type, " or "), got)})
+		return
+	}
+	switch v := v.(type) {
+	case string:
+		if len(s.Enum) > 0 && !contains(s.Enum, v) {
+			*vs = append(*vs, Violation{Field: path, Message: "expected one of " + strings.Join(s.Enum, ", ")})
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				*vs = append(*vs, Violation{Field: path, Message: "expected an RFC 3339 date-time"})
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.check(fmt.Sprintf("%s[%d]", path, i), item, vs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*vs = append(*vs, Violation{Field: joinPath(path, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			switch {
+			case ok:
+				prop.check(joinPath(path, name), v[name], vs)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				*vs = append(*vs, Violation{Field: joinPath(path, name), Message: "unknown field"})
+			}
+		}
+	}
+}
+
+func (s *Schema) allows(typ string) bool {
+	for _, t := range s.Type {
+		if t == typ || t == "number" && typ == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type name of a value decoded with
+// UseNumber.
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// decodeValidated reads the request body like decodeBody, checks it against
+// s and only then decodes it into v. Schema violations are answered with 422
+// and the list of violations. On failure it returns false.
+func decodeValidated(w http.ResponseWriter, r *http.Request, s *Schema, v any) bool {
+	var raw json.RawMessage
+	if !decodeBody(w, r, &raw) {
+		return false
+	}
+	if vs := s.Validate(raw); len(vs) > 0 {
+		writeViolations(w, vs)
+		return false
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		if errors.Is(err, errMoneyRange) {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("amount exceeds the maximum of $%s", maxAmount))
+			return false
+		}
+		if errors.Is(err, errInvalidMoney) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid JSON payload")
+		return false
+	}
+	return true
+}
+
+// writeViolations answers 422 with the schema violations in the error body.
+func writeViolations(w http.ResponseWriter, vs []Violation) {
+	writeErrorResponse(w, ErrorResponse{
+		Error:      "payload does not match the schema: " + vs[0].String(),
+		Status:     http.StatusUnprocessableEntity,
+		Violations: vs,
+	})
+}
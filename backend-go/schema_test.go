@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSchemaRejectsPayloads(t *testing.T) {
+	api, tn := newTestAPI(t)
+	for _, tc := range []struct {
+		name, body, field string
+	}{
+		{"extra field", `{"amount": 10, "merchant": "m", "merchnat": "typo"}`, "merchnat"},
+		{"wrong-type amount", `{"amount": true, "merchant": "m"}`, "amount"},
+		{"wrong-type merchant", `{"amount": 10, "merchant": 7}`, "merchant"},
+		{"missing amount", `{"merchant": "m"}`, "amount"},
+	} {
+		rec := do(api, "POST", "/risk", tc.body)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422", tc.name, rec.Code)
+			continue
+		}
+		resp := decode[ErrorResponse](t, rec)
+		found := false
+		for _, v := range resp.Violations {
+			found = found || strings.Contains(v.String(), tc.field)
+		}
+		if !found {
+			t.Errorf("%s: violations %+v do not name %s", tc.name, resp.Violations, tc.field)
+		}
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("%d rejected payloads recorded", n)
+	}
+}
+
+func TestSchemaListsEveryViolation(t *testing.T) {
+	vs := transactionSchema.Validate([]byte(`{"amount": [], "merchant": 1, "extra": null}`))
+	if len(vs) != 3 {
+		t.Fatalf("got %d violations, want 3: %+v", len(vs), vs)
+	}
+}
+
+func TestSchemaAcceptsValidPayload(t *testing.T) {
+	api, _ := newTestAPI(t)
+	body := `{"id": "tx-1", "amount": "12.50", "merchant": "m", "currency": "USD", "account_id": "acct-1", "type": "debit", "timestamp": "2026-03-10T12:00:00Z"}`
+	rec := do(api, "POST", "/risk", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, rec); res.RiskLevel != "LOW" {
+		t.Fatalf("got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import "encoding/xml"
+
+// ScoreResult is the response body for a scored transaction.
+type ScoreResult struct {
+	XMLName xml.Name `json:"-" xml:"risk_result"`
+	// TransactionID is the scored transaction's ID.
+	TransactionID string `json:"transaction_id" xml:"transaction_id"`
+	Decision
+	RiskScore int `json:"risk_score" xml:"risk_score"`
+	// DryRun marks a result that was not recorded.
+	DryRun bool `json:"dry_run,omitempty" xml:"dry_run,omitempty"`
+}
+
+// Score cutoffs used to derive a risk level from a 0–100 score.
+const (
+	mediumScoreCutoff = 40
+	highScoreCutoff   = 70
+)
+
+// amountBands maps an amount ceiling to its base score. The last band has no
+// ceiling and applies to everything above the previous one.
+var amountBands = []struct {
+	UpTo  Money
+	Score int
+}{
+	{dollars(100), 5},
+	{dollars(1000), 20},
+	{dollars(5000), 45},
+	{dollars(10000), 60},
+}
+
+const topBandScore = 85
+
+// merchantBumps add points on top of the amount band for merchant context.
+var merchantBumps = []struct {
+	Merchant string
+	Over     Money
+	Points   int
+}{
+	{"Starbucks", dollars(500), 40},
+}
+
+// scoreTransaction computes a 0–100 risk score for t given its decision. The
+// decision picks the band the score falls into; amount bands and merchant
+// bumps place the score within that band, so the level derived from the score
+// always matches the decision.
+func scoreTransaction(t Transaction, d Decision) ScoreResult {
+	t = inUSD(t)
+	score := topBandScore
+	for _, b := range amountBands {
+		if t.Amount <= b.UpTo {
+			score = b.Score
+			break
+		}
+	}
+	for _, b := range merchantBumps {
+		if sameMerchant(t.Merchant, b.Merchant) && t.Amount > b.Over {
+			score += b.Points
+		}
+	}
+
+	lo, hi := scoreBand(d.RiskLevel)
+	if score < lo {
+		score = lo
+	}
+	if score > hi {
+		score = hi
+	}
+	d.RiskLevel = levelForScore(score)
+	return ScoreResult{Decision: d, RiskScore: score}
+}
+
+// scoreBand returns the inclusive score range for a risk level.
+func scoreBand(level string) (int, int) {
+	switch level {
+	case "HIGH":
+		return highScoreCutoff, 100
+	case "MEDIUM":
+		return mediumScoreCutoff, highScoreCutoff - 1
+	default:
+		return 0, mediumScoreCutoff - 1
+	}
+}
+
+// levelForScore maps a score onto LOW/MEDIUM/HIGH using the fixed cutoffs.
+func levelForScore(score int) string {
+	switch {
+	case score >= highScoreCutoff:
+		return "HIGH"
+	case score >= mediumScoreCutoff:
+		return "MEDIUM"
+	default:
+		return "LOW"
+	}
+}
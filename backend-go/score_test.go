@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestScoreTransactionBoundaries(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		tx    Transaction
+		level string
+		score int
+	}{
+		{"top of first band", Transaction{Amount: dollars(100)}, "LOW", 5},
+		{"just over first band", Transaction{Amount: dollars(100) + 1}, "LOW", 20},
+		{"LOW clamped below medium cutoff", Transaction{Amount: dollars(4999), Merchant: "Apple Store"}, "LOW", mediumScoreCutoff - 1},
+		{"MEDIUM band", Transaction{Amount: dollars(1000) + 1}, "MEDIUM", 45},
+		{"MEDIUM at top band edge", Transaction{Amount: dollars(10000)}, "MEDIUM", 60},
+		{"HIGH above every band", Transaction{Amount: dollars(10000) + 1}, "HIGH", topBandScore},
+		{"HIGH raised to cutoff", Transaction{Amount: dollars(50)}, "HIGH", highScoreCutoff},
+		{"merchant bump", Transaction{Amount: dollars(500) + 1, Merchant: "Starbucks"}, "HIGH", highScoreCutoff},
+	} {
+		res := scoreTransaction(tc.tx, Decision{RiskLevel: tc.level})
+		if res.RiskScore != tc.score || res.RiskLevel != tc.level {
+			t.Errorf("%s: got %s/%d, want %s/%d", tc.name, res.RiskLevel, res.RiskScore, tc.level, tc.score)
+		}
+	}
+}
+
+func TestLevelForScoreCutoffs(t *testing.T) {
+	for score, want := range map[int]string{
+		0:                     "LOW",
+		mediumScoreCutoff - 1: "LOW",
+		mediumScoreCutoff:     "MEDIUM",
+		highScoreCutoff - 1:   "MEDIUM",
+		highScoreCutoff:       "HIGH",
+		100:                   "HIGH",
+	} {
+		if got := levelForScore(score); got != want {
+			t.Errorf("levelForScore(%d) = %s, want %s", score, got, want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import "context"
+
+// Server holds the dependencies shared by the HTTP handlers.
+type Server struct {
+	tenants *Tenants
+}
+
+// NewServer returns a Server for tenants.
+func NewServer(tenants *Tenants) *Server {
+	return &Server{tenants: tenants}
+}
+
+// tenant returns the tenant a request was resolved to by requireTenant, or
+// the default tenant when the context carries none.
+func (s *Server) tenant(ctx context.Context) *Tenant {
+	if t := tenantFrom(ctx); t != nil {
+		return t
+	}
+	return s.tenants.Default()
+}
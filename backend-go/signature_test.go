@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequireSignature(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &signingSecret, []byte("s3cret"))
+	body := `{"amount": 10, "merchant": "m"}`
+	sig := signRequest(signingSecret, "POST", "/risk", []byte(body))
+
+	for _, tc := range []struct {
+		name, target, body string
+		header             []string
+		code               int
+	}{
+		{"correctly signed", "/risk", body, []string{signatureHeader, sig}, http.StatusOK},
+		{"sha256= prefix", "/risk", body, []string{signatureHeader, "sha256=" + sig}, http.StatusOK},
+		{"tampered body", "/risk", strings.Replace(body, "10", "99", 1), []string{signatureHeader, sig}, http.StatusUnauthorized},
+		{"missing signature", "/risk", body, nil, http.StatusUnauthorized},
+		{"different path", "/risk/batch", "[" + body + "]", []string{signatureHeader, sig}, http.StatusUnauthorized},
+		{"different query", "/risk?dry_run=true", body, []string{signatureHeader, sig}, http.StatusUnauthorized},
+		{"not hex", "/risk", body, []string{signatureHeader, "zz"}, http.StatusUnauthorized},
+	} {
+		if rec := do(api, "POST", tc.target, tc.body, tc.header...); rec.Code != tc.code {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.code, rec.Body)
+		}
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 2 {
+		t.Fatalf("%d transactions recorded, want only the 2 signed ones", n)
+	}
+	if rec := do(api, "GET", "/transactions", ""); rec.Code != http.StatusOK {
+		t.Fatalf("GET needs no signature: status %d", rec.Code)
+	}
+}
+
+func TestSignatureSignsMethodAndQuery(t *testing.T) {
+	secret := []byte("k")
+	base := signRequest(secret, "POST", "/replay", nil)
+	if base == signRequest(secret, "PATCH", "/replay", nil) {
+		t.Error("method not covered by the signature")
+	}
+	if base == signRequest(secret, "POST", "/replay?commit=true", nil) {
+		t.Error("query not covered by the signature")
+	}
+}
+
+func TestSignatureUsesRouteBodyLimit(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &signingSecret, []byte("s3cret"))
+	setVar(t, &maxBodyBytes, 64)
+	csv := "amount,merchant\n" + strings.Repeat("10,Corner Shop\n", 20)
+	sig := signRequest(signingSecret, "POST", "/v1/risk/upload", []byte(csv))
+	rec := do(api, "POST", "/v1/risk/upload", csv, signatureHeader, sig, "Content-Type", "text/csv")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("signed upload over maxBodyBytes: status %d: %s", rec.Code, rec.Body)
+	}
+
+	big := `{"amount": 10, "merchant": "` + strings.Repeat("m", 100) + `"}`
+	rec = do(api, "POST", "/risk", big, signatureHeader, signRequest(signingSecret, "POST", "/risk", []byte(big)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("signed /risk over maxBodyBytes: status %d, want 413", rec.Code)
+	}
+}
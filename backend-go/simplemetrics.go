@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a lock-free set of counters for deployments that don't scrape
+// Prometheus. It is served as JSON at /metrics/simple.
+type Metrics struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	low      atomic.Int64
+	medium   atomic.Int64
+	high     atomic.Int64
+}
+
+// simpleMetrics is the Metrics backend.
+var simpleMetrics = &Metrics{}
+
+func (m *Metrics) observeRequest(_ string, status int, _ time.Duration) {
+	m.requests.Add(1)
+	if status >= 400 {
+		m.errors.Add(1)
+	}
+}
+
+func (m *Metrics) observeDecision(level string) {
+	switch level {
+	case "LOW":
+		m.low.Add(1)
+	case "MEDIUM":
+		m.medium.Add(1)
+	case "HIGH":
+		m.high.Add(1)
+	}
+}
+
+// MetricsSnapshot is the response body of GET /metrics/simple. Errors counts
+// scoring requests answered with a 4xx or 5xx status.
+type MetricsSnapshot struct {
+	Requests  int64            `json:"requests"`
+	Errors    int64            `json:"errors"`
+	Decisions map[string]int64 `json:"decisions"`
+}
+
+// Snapshot returns the current counts. Each counter is read atomically, but
+// the snapshot as a whole is not taken at a single instant.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		Requests: m.requests.Load(),
+		Errors:   m.errors.Load(),
+		Decisions: map[string]int64{
+			"LOW":    m.low.Load(),
+			"MEDIUM": m.medium.Load(),
+			"HIGH":   m.high.Load(),
+		},
+	}
+}
+
+// simpleMetricsHandler serves GET /metrics/simple.
+func simpleMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(simpleMetrics.Snapshot())
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestSimpleMetricsUnderConcurrentLoad(t *testing.T) {
+	api, _ := newTestAPI(t)
+	m := &Metrics{}
+	setVar(t, &simpleMetrics, m)
+	setVar(t, &metricsBackends, []metricsBackend{m})
+
+	bodies := []string{
+		`{"amount": 10, "merchant": "m"}`,
+		`{"amount": 2500, "merchant": "m"}`,
+		`{"amount": 20000, "merchant": "m"}`,
+		`{"amount": -1, "merchant": "m"}`,
+	}
+	const rounds = 25
+	var wg sync.WaitGroup
+	for i := 0; i < rounds; i++ {
+		for _, body := range bodies {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				do(api, "POST", "/risk", body)
+			}()
+		}
+	}
+	wg.Wait()
+
+	got := decode[MetricsSnapshot](t, do(api, "GET", "/metrics/simple", "", "X-API-Key", ""))
+	if got.Requests != rounds*4 || got.Errors != rounds {
+		t.Errorf("requests %d, errors %d; want %d and %d", got.Requests, got.Errors, rounds*4, rounds)
+	}
+	for _, level := range []string{"LOW", "MEDIUM", "HIGH"} {
+		if got.Decisions[level] != rounds {
+			t.Errorf("%s decisions %d, want %d", level, got.Decisions[level], rounds)
+		}
+	}
+}
+
+func TestParseMetricsBackends(t *testing.T) {
+	got, err := parseMetricsBackends("simple, prometheus")
+	if err != nil || len(got) != 2 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	if got[0] != metricsBackend(simpleMetrics) {
+		t.Errorf("first backend %T, want the simple metrics", got[0])
+	}
+	if _, err := parseMetricsBackends("statsd"); err == nil {
+		t.Error("unknown backend accepted")
+	}
+}
+
+func TestSimpleMetricsHandlerMethod(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if rec := do(api, "POST", "/metrics/simple", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// simulateEnabled turns on POST /simulate. It records synthetic transactions
+// in the tenant's history, so it is meant for development and load tests only.
+var simulateEnabled = false
+
+// maxSimulateCount caps the transactions one /simulate request generates.
+const maxSimulateCount = 10000
+
+// Amount distributions for /simulate.
+const (
+	distUniform   = "uniform"
+	distLogNormal = "lognormal"
+)
+
+// defaultSimulateMerchants are drawn from when a /simulate request names none.
+var defaultSimulateMerchants = []string{"Starbucks", "Apple Store", "Amazon", "Shell", "Walmart"}
+
+// amountDistribution describes how /simulate draws amounts: uniformly between
+// Min and Max, or log-normally around Median with shape Sigma.
+type amountDistribution struct {
+	Kind   string  `json:"kind"`
+	Min    Money   `json:"min,omitempty"`
+	Max    Money   `json:"max,omitempty"`
+	Median Money   `json:"median,omitempty"`
+	Sigma  float64 `json:"sigma,omitempty"`
+}
+
+func (d amountDistribution) validate() error {
+	switch d.Kind {
+	case distUniform:
+		if d.Min < 0 || d.Max <= d.Min {
+			return &ValidationError{Field: "amount", Message: "uniform amounts need 0 <= min < max"}
+		}
+	case distLogNormal:
+		if d.Median <= 0 || d.Sigma <= 0 {
+			return &ValidationError{Field: "amount", Message: "lognormal amounts need a positive median and sigma"}
+		}
+	default:
+		return &ValidationError{Field: "amount", Message: "amount kind must be uniform or lognormal"}
+	}
+	return nil
+}
+
+// draw returns one amount, capped at maxAmount.
+func (d amountDistribution) draw(rng *rand.Rand) Money {
+	var v float64
+	switch d.Kind {
+	case distUniform:
+		v = float64(d.Min) + rng.Float64()*float64(d.Max-d.Min)
+	case distLogNormal:
+		v = float64(d.Median) * math.Exp(d.Sigma*rng.NormFloat64())
+	}
+	if v >= float64(maxAmount) {
+		return maxAmount
+	}
+	return Money(math.Round(v))
+}
+
+// simulateRequest is the body of POST /simulate. A zero Seed picks one from
+// the clock; a fixed seed reproduces the same transactions.
+type simulateRequest struct {
+	Count     int                `json:"count"`
+	Amount    amountDistribution `json:"amount"`
+	Merchants []string           `json:"merchants,omitempty"`
+	Seed      int64              `json:"seed,omitempty"`
+}
+
+// SimulateResult is the response body of POST /simulate.
+type SimulateResult struct {
+	Count  int            `json:"count"`
+	Levels map[string]int `json:"levels"`
+	Seed   int64          `json:"seed"`
+}
+
+// simulate serves POST /simulate when simulateEnabled is set: it generates
+// synthetic transactions, scores and records each through the normal pipeline
+// and returns how many landed at each level.
+func (s *Server) simulate(w http.ResponseWriter, r *http.Request) {
+	if !simulateEnabled {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req simulateRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Count <= 0 || req.Count > maxSimulateCount {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("count must be between 1 and %d", maxSimulateCount))
+		return
+	}
+	if err := req.Amount.validate(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	merchants := req.Merchants
+	if len(merchants) == 0 {
+		merchants = defaultSimulateMerchants
+	}
+	seed := req.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	tn := s.tenant(r.Context())
+	rng := rand.New(rand.NewSource(seed))
+	res := SimulateResult{Count: req.Count, Levels: map[string]int{"LOW": 0, "MEDIUM": 0, "HIGH": 0}, Seed: seed}
+	for i := 0; i < req.Count; i++ {
+		t := Transaction{Amount: req.Amount.draw(rng), Merchant: merchants[rng.Intn(len(merchants))]}
+		scored, err := tn.process(r.Context(), t)
+		if err != nil {
+			writeProcessError(w, r, err)
+			return
+		}
+		res.Levels[scored.RiskLevel]++
+	}
+	logFor(r.Context()).Info("simulated transactions", "count", req.Count, "seed", seed, "levels", res.Levels)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
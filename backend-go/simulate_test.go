@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+)
+
+func TestSimulateHistogram(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &simulateEnabled, true)
+	// Corner Shop matches no rule, so the amount thresholds alone decide:
+	// 10..900 is all LOW and 20000..30000 all HIGH.
+	for _, tc := range []struct {
+		amount string
+		want   string
+	}{
+		{`{"kind": "uniform", "min": 10, "max": 900}`, "LOW"},
+		{`{"kind": "uniform", "min": 20000, "max": 30000}`, "HIGH"},
+	} {
+		rec := do(api, "POST", "/simulate", `{"count": 50, "seed": 7, "merchants": ["Corner Shop"], "amount": `+tc.amount+`}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		res := decode[SimulateResult](t, rec)
+		sum := 0
+		for _, n := range res.Levels {
+			sum += n
+		}
+		if res.Count != 50 || sum != 50 || res.Levels[tc.want] != 50 || res.Seed != 7 {
+			t.Errorf("%s: got %+v, want all 50 %s", tc.amount, res, tc.want)
+		}
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 100 {
+		t.Fatalf("%d records, want 100", n)
+	}
+}
+
+func TestSimulateMixedDistribution(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &simulateEnabled, true)
+	res := decode[SimulateResult](t, do(api, "POST", "/simulate", `{"count": 500, "seed": 1, "merchants": ["Corner Shop"], "amount": {"kind": "lognormal", "median": 1500, "sigma": 1.5}}`))
+	if res.Levels["LOW"]+res.Levels["MEDIUM"]+res.Levels["HIGH"] != 500 {
+		t.Fatalf("histogram %v does not sum to 500", res.Levels)
+	}
+	for _, level := range []string{"LOW", "MEDIUM", "HIGH"} {
+		if res.Levels[level] == 0 {
+			t.Errorf("no %s decisions in %v", level, res.Levels)
+		}
+	}
+}
+
+func TestSimulateValidation(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if rec := do(api, "POST", "/simulate", `{"count": 1, "amount": {"kind": "uniform", "max": 1}}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("disabled: status %d, want 404", rec.Code)
+	}
+	setVar(t, &simulateEnabled, true)
+	for _, body := range []string{
+		`{"count": 0, "amount": {"kind": "uniform", "max": 1}}`,
+		`{"count": 10001, "amount": {"kind": "uniform", "max": 1}}`,
+		`{"count": 1, "amount": {"kind": "uniform", "min": 5, "max": 5}}`,
+		`{"count": 1, "amount": {"kind": "lognormal", "median": 100}}`,
+		`{"count": 1, "amount": {"kind": "pareto"}}`,
+	} {
+		if rec := do(api, "POST", "/simulate", body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422", body, rec.Code)
+		}
+	}
+}
+
+func TestSimulateDrawCapped(t *testing.T) {
+	d := amountDistribution{Kind: distLogNormal, Median: maxAmount, Sigma: 5}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		if v := d.draw(rng); v > maxAmount || v < 0 {
+			t.Fatalf("draw %s outside [0, maxAmount]", v)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Stats summarizes the scored transactions held by a store. Amounts are in
+// USD.
+type Stats struct {
+	Total         int         `json:"total"`
+	ByLevel       LevelCounts `json:"by_level"`
+	AverageAmount Money       `json:"average_amount"`
+	MaxAmount     Money       `json:"max_amount"`
+	// Credits are counted here and left out of the figures above.
+	Credits CreditStats `json:"credits"`
+}
+
+// CreditStats summarizes stored credits. Total is the net USD amount.
+type CreditStats struct {
+	Count int   `json:"count"`
+	Total Money `json:"total"`
+}
+
+// LevelCounts breaks a transaction count down by risk level.
+type LevelCounts struct {
+	Low    int `json:"low"`
+	Medium int `json:"medium"`
+	High   int `json:"high"`
+}
+
+func (c *LevelCounts) add(level string, n int) {
+	switch level {
+	case "LOW":
+		c.Low += n
+	case "MEDIUM":
+		c.Medium += n
+	case "HIGH":
+		c.High += n
+	}
+}
+
+// statsBuilder accumulates Stats from records or pre-aggregated rows.
+type statsBuilder struct {
+	stats Stats
+	sum   Money
+}
+
+func (b *statsBuilder) add(level string, n int, sum, max Money) {
+	b.stats.Total += n
+	b.stats.ByLevel.add(level, n)
+	b.sum += sum
+	if max > b.stats.MaxAmount {
+		b.stats.MaxAmount = max
+	}
+}
+
+func (b *statsBuilder) addCredits(n int, sum Money) {
+	b.stats.Credits.Count += n
+	b.stats.Credits.Total += sum
+}
+
+func (b *statsBuilder) result() Stats {
+	if b.stats.Total > 0 {
+		b.stats.AverageAmount = b.sum / Money(b.stats.Total)
+	}
+	return b.stats
+}
+
+// statsHandler serves GET /stats.
+func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	st, err := s.tenant(r.Context()).store.Stats(r.Context())
+	if err != nil {
+		writeStoreError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func seedStats(t *testing.T, s Store) {
+	t.Helper()
+	for _, r := range []Record{
+		{Transaction: Transaction{Merchant: "m", Amount: dollars(100), Timestamp: noon}, RiskLevel: "LOW"},
+		{Transaction: Transaction{Merchant: "m", Amount: dollars(200), Timestamp: noon}, RiskLevel: "LOW"},
+		{Transaction: Transaction{Merchant: "m", Amount: dollars(3000), Timestamp: noon}, RiskLevel: "MEDIUM"},
+		{Transaction: Transaction{Merchant: "m", Amount: dollars(20000), Timestamp: noon}, RiskLevel: "HIGH"},
+		{Transaction: Transaction{Merchant: "m", Amount: dollars(-50), Type: TypeCredit, Timestamp: noon}, RiskLevel: "LOW"},
+	} {
+		r.Timestamp = noon
+		if err := s.Append(t.Context(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var wantStats = Stats{
+	Total:         4,
+	ByLevel:       LevelCounts{Low: 2, Medium: 1, High: 1},
+	AverageAmount: dollars(5825),
+	MaxAmount:     dollars(20000),
+	Credits:       CreditStats{Count: 1, Total: dollars(-50)},
+}
+
+func TestStoreStats(t *testing.T) {
+	for name, s := range map[string]Store{
+		"memory": NewMemoryStore(defaultHistorySize),
+		"sqlite": openTestSQLite(t, filepath.Join(t.TempDir(), "risk.db")),
+	} {
+		seedStats(t, s)
+		got, err := s.Stats(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != wantStats {
+			t.Errorf("%s: got %+v, want %+v", name, got, wantStats)
+		}
+	}
+}
+
+func TestStatsEndpoint(t *testing.T) {
+	api, tn := newTestAPI(t)
+	if got := decode[Stats](t, do(api, "GET", "/stats", "")); got != (Stats{}) {
+		t.Fatalf("empty store: %+v", got)
+	}
+	seedStats(t, tn.store)
+	if got := decode[Stats](t, do(api, "GET", "/stats", "")); got != wantStats {
+		t.Fatalf("got %+v, want %+v", got, wantStats)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Review states of a HIGH decision. A HIGH decision starts pending; an
+// analyst clears it or confirms it as fraud. A cleared decision may be
+// reopened or confirmed, but a confirmed one is final.
+const (
+	StatusPending   = "pending"
+	StatusCleared   = "cleared"
+	StatusConfirmed = "confirmed"
+)
+
+var validStatuses = map[string]bool{StatusPending: true, StatusCleared: true, StatusConfirmed: true}
+
+// statusTransitions lists the states each state may move to.
+var statusTransitions = map[string]map[string]bool{
+	StatusPending: {StatusCleared: true, StatusConfirmed: true},
+	StatusCleared: {StatusPending: true, StatusConfirmed: true},
+}
+
+var (
+	errRecordNotFound    = errors.New("transaction not found")
+	errInvalidTransition = errors.New("invalid status transition")
+)
+
+// checkTransition reports whether a record in status from may move to to.
+func checkTransition(from, to string) error {
+	if from == "" {
+		return fmt.Errorf("%w: only HIGH-risk transactions are reviewed", errInvalidTransition)
+	}
+	if !statusTransitions[from][to] {
+		return fmt.Errorf("%w: %s to %s", errInvalidTransition, from, to)
+	}
+	return nil
+}
+
+// statusRequest is the body of POST /transactions/{id}/status.
+type statusRequest struct {
+	Status string `json:"status"`
+}
+
+// recordID resolves the {id} of a /transactions/{id} path: a transaction ID,
+// or failing that the numeric ID of a record, for records stored without a
+// transaction ID.
+func recordID(ctx context.Context, store Store, key string) (int64, error) {
+	rec, err := store.FindTransaction(ctx, key)
+	if err == nil {
+		return rec.ID, nil
+	}
+	if !errors.Is(err, errRecordNotFound) {
+		return 0, err
+	}
+	if id, perr := strconv.ParseInt(key, 10, 64); perr == nil && id > 0 {
+		return id, nil
+	}
+	return 0, err
+}
+
+// transactionStatus serves POST /transactions/{id}/status: it moves a HIGH
+// decision to a new review state and returns the updated record. It is
+// mounted on the /transactions/ subtree and parses the path itself.
+func (s *Server) transactionStatus(w http.ResponseWriter, r *http.Request) {
+	_, rest, _ := strings.Cut(r.URL.Path, "/transactions/")
+	idPart, ok := strings.CutSuffix(rest, "/status")
+	if !ok || strings.Contains(idPart, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if idPart == "" {
+		writeError(w, http.StatusBadRequest, "transaction id is required")
+		return
+	}
+	var req statusRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if !validStatuses[req.Status] {
+		writeError(w, http.StatusUnprocessableEntity, "status must be pending, cleared or confirmed")
+		return
+	}
+	store := s.tenant(r.Context()).store
+	id, err := recordID(r.Context(), store, idPart)
+	var rec Record
+	if err == nil {
+		rec, err = store.SetStatus(r.Context(), id, req.Status)
+	}
+	switch {
+	case errors.Is(err, errRecordNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, errInvalidTransition):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeStoreError(w, r, err)
+		return
+	}
+	logFor(r.Context()).Info("transaction status changed", "id", id, "transaction_id", rec.Transaction.ID, "status", rec.Status)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStatusTransition(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m", "id": "tx-1"}`)); res.RiskLevel != "HIGH" {
+		t.Fatalf("got %s", res.RiskLevel)
+	}
+	rec := do(api, "POST", "/transactions/tx-1/status", `{"status": "confirmed"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := decode[Record](t, rec); got.Status != StatusConfirmed || got.Transaction.ID != "tx-1" {
+		t.Fatalf("record %+v", got)
+	}
+}
+
+func TestStatusInvalidTransition(t *testing.T) {
+	api, _ := newTestAPI(t)
+	do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m", "id": "tx-high"}`)
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m", "id": "tx-low"}`)
+	do(api, "POST", "/transactions/tx-high/status", `{"status": "confirmed"}`)
+	for _, tc := range []struct {
+		target, body string
+		want         int
+	}{
+		{"/transactions/tx-high/status", `{"status": "pending"}`, http.StatusConflict},
+		{"/transactions/tx-low/status", `{"status": "cleared"}`, http.StatusConflict},
+		{"/transactions/tx-high/status", `{"status": "closed"}`, http.StatusUnprocessableEntity},
+		{"/transactions/tx-none/status", `{"status": "cleared"}`, http.StatusNotFound},
+	} {
+		if rec := do(api, "POST", tc.target, tc.body); rec.Code != tc.want {
+			t.Errorf("%s %s: status %d, want %d", tc.target, tc.body, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestHistoryStatusFilter(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, id := range []string{"tx-1", "tx-2", "tx-3"} {
+		do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m", "id": "`+id+`"}`)
+	}
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m", "id": "tx-low"}`)
+	do(api, "POST", "/transactions/tx-2/status", `{"status": "cleared"}`)
+
+	page := decode[HistoryPage](t, do(api, "GET", "/transactions?status=pending", ""))
+	if len(page.Transactions) != 2 {
+		t.Fatalf("got %d pending, want 2: %+v", len(page.Transactions), page.Transactions)
+	}
+	for _, r := range page.Transactions {
+		if r.Status != StatusPending || r.Transaction.ID == "tx-2" {
+			t.Errorf("record %s in status %q", r.Transaction.ID, r.Status)
+		}
+	}
+	if rec := do(api, "GET", "/transactions?status=open", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad status filter: status %d, want 400", rec.Code)
+	}
+}
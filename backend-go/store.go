@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Record is a scored transaction kept for investigation.
+type Record struct {
+	Transaction Transaction `json:"transaction"`
+	RiskLevel   string      `json:"risk_level"`
+	Reason      string      `json:"reason,omitempty"`
+	Timestamp   time.Time   `json:"timestamp"`
+	// Status is the analyst review state of a HIGH decision; see status.go.
+	// It is empty for other levels.
+	Status string `json:"status,omitempty"`
+
+	// ID identifies the record. It is assigned by the store in insertion
+	// order and backs history cursors.
+	ID int64 `json:"id"`
+}
+
+// HistoryQuery selects a page of stored records, newest first. Zero From or
+// To leaves that end of the [From, To) range on the transaction timestamp
+// open; a zero Before starts from the newest record; an empty Status matches
+// any status.
+type HistoryQuery struct {
+	From, To time.Time
+	Before   int64
+	Status   string
+	Limit    int
+}
+
+// matches reports whether r falls within q's time range and cursor.
+func (q HistoryQuery) matches(r Record) bool {
+	at := r.Transaction.Timestamp
+	if !q.From.IsZero() && at.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !at.Before(q.To) {
+		return false
+	}
+	if q.Status != "" && r.Status != q.Status {
+		return false
+	}
+	return q.Before == 0 || r.ID < q.Before
+}
+
+// Store holds scored transactions. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Append records a scored transaction.
+	Append(ctx context.Context, r Record) error
+	// Recent returns up to limit records, newest first.
+	Recent(ctx context.Context, limit int) ([]Record, error)
+	// History returns up to q.Limit records matching q, newest first.
+	History(ctx context.Context, q HistoryQuery) ([]Record, error)
+	// AccountTotal sums the USD amounts recorded for account with
+	// transaction timestamps on day, where the day runs midnight to midnight
+	// in reportLocation.
+	AccountTotal(ctx context.Context, account string, day time.Time) (Money, error)
+	// AccountTotals sums USD amounts per account for debit records with
+	// transaction timestamps in [from, to).
+	AccountTotals(ctx context.Context, from, to time.Time) (map[string]accountTotal, error)
+	// MerchantTotals counts records per merchant and risk level with
+	// transaction timestamps in [from, to), keyed by normalizeMerchant. A
+	// zero from or to leaves that end open.
+	MerchantTotals(ctx context.Context, from, to time.Time) (map[string]merchantTotal, error)
+	// AccountBaseline returns the average USD amount and number of debit
+	// records for account with transaction timestamps after since.
+	AccountBaseline(ctx context.Context, account string, since time.Time) (avg Money, n int, err error)
+	// Rescore calls fn with every stored record, oldest first. When commit is
+	// true, records whose level or reason fn changed are replaced with fn's
+	// result, and records newly raised to HIGH become pending review. fn
+	// must not call back into the store.
+	Rescore(ctx context.Context, commit bool, fn func(Record) Record) error
+	// Stats summarizes all stored records.
+	Stats(ctx context.Context) (Stats, error)
+	// SetStatus moves the record with id to status, if checkTransition
+	// allows it, and returns the updated record. It returns errRecordNotFound
+	// for an unknown id.
+	SetStatus(ctx context.Context, id int64, status string) (Record, error)
+	// FindTransaction returns the newest record of the transaction with the
+	// given Transaction.ID, or errRecordNotFound.
+	FindTransaction(ctx context.Context, id string) (Record, error)
+}
+
+// defaultHistorySize is the number of records kept unless -history-size is set.
+const defaultHistorySize = 10000
+
+// MemoryStore keeps the most recent scored transactions in a fixed-size ring
+// buffer. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records []Record
+	next    int
+	full    bool
+	seq     int64 // records appended so far
+}
+
+// NewMemoryStore returns a store that retains up to capacity records.
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{records: make([]Record, capacity)}
+}
+
+// Append adds a record, overwriting the oldest one when the buffer is full.
+func (s *MemoryStore) Append(_ context.Context, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.records) == 0 {
+		return nil
+	}
+	s.seq++
+	r.ID = s.seq
+	s.records[s.next] = r
+	s.next = (s.next + 1) % len(s.records)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+// Len returns the number of records currently held.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.len()
+}
+
+func (s *MemoryStore) len() int {
+	if s.full {
+		return len(s.records)
+	}
+	return s.next
+}
+
+// Recent returns up to limit records, newest first.
+func (s *MemoryStore) Recent(_ context.Context, limit int) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := s.len()
+	if limit < n {
+		n = limit
+	}
+	out := make([]Record, 0, n)
+	for i := 1; i <= n; i++ {
+		idx := (s.next - i + len(s.records)) % len(s.records)
+		out = append(out, s.records[idx])
+	}
+	return out, nil
+}
+
+// History returns up to q.Limit held records matching q, newest first.
+func (s *MemoryStore) History(_ context.Context, q HistoryQuery) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Record
+	n := s.len()
+	for i := 1; i <= n && len(out) < q.Limit; i++ {
+		r := s.records[(s.next-i+len(s.records))%len(s.records)]
+		if q.matches(r) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// Rescore calls fn with every held record, oldest first, replacing changed
+// decisions when commit is true. The store is locked for the duration.
+func (s *MemoryStore) Rescore(_ context.Context, commit bool, fn func(Record) Record) error {
+	if commit {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	} else {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+	n := s.len()
+	for i := n; i >= 1; i-- {
+		idx := (s.next - i + len(s.records)) % len(s.records)
+		next := fn(s.records[idx])
+		if commit {
+			r := &s.records[idx]
+			r.RiskLevel = next.RiskLevel
+			r.Reason = next.Reason
+			if r.RiskLevel == "HIGH" && r.Status == "" {
+				r.Status = StatusPending
+			}
+		}
+	}
+	return nil
+}
+
+// SetStatus updates the review status of the held record with id.
+func (s *MemoryStore) SetStatus(_ context.Context, id int64, status string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.len()
+	for i := 1; i <= n; i++ {
+		r := &s.records[(s.next-i+len(s.records))%len(s.records)]
+		if r.ID != id {
+			continue
+		}
+		if err := checkTransition(r.Status, status); err != nil {
+			return Record{}, err
+		}
+		r.Status = status
+		return *r, nil
+	}
+	return Record{}, errRecordNotFound
+}
+
+// FindTransaction returns the newest held record of transaction id.
+func (s *MemoryStore) FindTransaction(_ context.Context, id string) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := s.len()
+	for i := 1; i <= n; i++ {
+		if r := s.records[(s.next-i+len(s.records))%len(s.records)]; r.Transaction.ID == id {
+			return r, nil
+		}
+	}
+	return Record{}, errRecordNotFound
+}
+
+// accountTotal is an account's summed USD amount over some period.
+type accountTotal struct {
+	Total Money
+	Count int
+}
+
+// AccountTotals sums USD amounts per account for records with transaction
+// timestamps in [from, to), so backfilled transactions count on the day they
+// happened. Records without an account and credits are skipped.
+func (s *MemoryStore) AccountTotals(_ context.Context, from, to time.Time) (map[string]accountTotal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	totals := map[string]accountTotal{}
+	n := s.len()
+	for i := 0; i < n; i++ {
+		r := s.records[i]
+		at := r.Transaction.Timestamp
+		if r.Transaction.AccountID == "" || isCredit(r.Transaction) || at.Before(from) || !at.Before(to) {
+			continue
+		}
+		total := totals[r.Transaction.AccountID]
+		total.Total += inUSD(r.Transaction).Amount
+		total.Count++
+		totals[r.Transaction.AccountID] = total
+	}
+	return totals, nil
+}
+
+// merchantTotal is a merchant's decision counts over some period. Merchant is
+// one of the spellings it was recorded under.
+type merchantTotal struct {
+	Merchant string
+	Levels   LevelCounts
+}
+
+// MerchantTotals counts held records per merchant and risk level in a single
+// pass.
+func (s *MemoryStore) MerchantTotals(_ context.Context, from, to time.Time) (map[string]merchantTotal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q := HistoryQuery{From: from, To: to}
+	totals := map[string]merchantTotal{}
+	n := s.len()
+	for i := 0; i < n; i++ {
+		r := s.records[i]
+		if !q.matches(r) {
+			continue
+		}
+		key := normalizeMerchant(r.Transaction.Merchant)
+		mt, ok := totals[key]
+		if !ok {
+			mt.Merchant = r.Transaction.Merchant
+		}
+		mt.Levels.add(r.RiskLevel, 1)
+		totals[key] = mt
+	}
+	return totals, nil
+}
+
+// AccountTotal sums the USD amounts recorded for account on day.
+func (s *MemoryStore) AccountTotal(ctx context.Context, account string, day time.Time) (Money, error) {
+	from, to := dayBounds(day)
+	totals, err := s.AccountTotals(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return totals[account].Total, nil
+}
+
+// AccountBaseline returns the average USD amount and number of held records
+// for account with transaction timestamps after since. Backfilled records
+// arrive out of transaction order, so every held record is checked.
+func (s *MemoryStore) AccountBaseline(_ context.Context, account string, since time.Time) (Money, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sum Money
+	n := 0
+	for i := 0; i < s.len(); i++ {
+		r := s.records[i]
+		if r.Transaction.AccountID == account && !isCredit(r.Transaction) && r.Transaction.Timestamp.After(since) {
+			sum += inUSD(r.Transaction).Amount
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0, nil
+	}
+	return sum / Money(n), n, nil
+}
+
+// Stats summarizes every record currently held.
+func (s *MemoryStore) Stats(_ context.Context) (Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var b statsBuilder
+	for i := 0; i < s.len(); i++ {
+		r := s.records[i]
+		amount := inUSD(r.Transaction).Amount
+		if isCredit(r.Transaction) {
+			b.addCredits(1, amount)
+			continue
+		}
+		b.add(r.RiskLevel, 1, amount, amount)
+	}
+	return b.result(), nil
+}
+
+// dayBounds returns the half-open interval covering the calendar day of t in
+// reportLocation.
+func dayBounds(t time.Time) (from, to time.Time) {
+	t = t.In(reportLocation)
+	from = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, reportLocation)
+	return from, from.AddDate(0, 0, 1)
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDriver is the database/sql driver name used by OpenSQLiteStore. It is
+// registered by modernc.org/sqlite, a pure-Go driver, so the binary still
+// builds with CGO_ENABLED=0.
+const sqliteDriver = "sqlite"
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS transactions (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	transaction_id TEXT    NOT NULL DEFAULT '',
+	amount         INTEGER NOT NULL,
+	currency       TEXT    NOT NULL,
+	merchant       TEXT    NOT NULL,
+	merchant_key   TEXT    NOT NULL,
+	mcc            TEXT    NOT NULL,
+	counterparty   TEXT    NOT NULL,
+	account        TEXT    NOT NULL,
+	country        TEXT    NOT NULL,
+	type           TEXT    NOT NULL DEFAULT 'debit',
+	level          TEXT    NOT NULL,
+	reason         TEXT    NOT NULL,
+	status         TEXT    NOT NULL DEFAULT '',
+	occurred       INTEGER NOT NULL,
+	ts             INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS transactions_ts ON transactions (ts);
+CREATE INDEX IF NOT EXISTS transactions_occurred ON transactions (occurred);
+CREATE INDEX IF NOT EXISTS transactions_merchant_ts ON transactions (merchant_key, ts);
+CREATE INDEX IF NOT EXISTS transactions_account_occurred ON transactions (account, occurred);
+CREATE INDEX IF NOT EXISTS transactions_transaction_id ON transactions (transaction_id) WHERE transaction_id != '';
+CREATE INDEX IF NOT EXISTS transactions_status ON transactions (status) WHERE status != '';
+`
+
+// SQLiteStore persists scored transactions in a SQLite database. Amounts are
+// stored as integer cents and timestamps as Unix nanoseconds.
+type SQLiteStore struct {
+	db *sql.DB
+
+	insert   *sql.Stmt
+	recent   *sql.Stmt
+	count    *sql.Stmt
+	totals   *sql.Stmt
+	merchant *sql.Stmt
+	baseline *sql.Stmt
+	stats    *sql.Stmt
+	all      *sql.Stmt
+	update   *sql.Stmt
+	history  *sql.Stmt
+	get      *sql.Stmt
+	find     *sql.Stmt
+	status   *sql.Stmt
+}
+
+// recordColumns are the columns scanRecord reads, in order.
+const recordColumns = `id, transaction_id, amount, currency, merchant, mcc, counterparty, account, country, type, level, reason, status, occurred, ts`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRecord scans a row selected with recordColumns.
+func scanRecord(rows rowScanner) (Record, error) {
+	var (
+		r        Record
+		amount   int64
+		occurred int64
+		ts       int64
+	)
+	t := &r.Transaction
+	if err := rows.Scan(&r.ID, &t.ID, &amount, &t.Currency, &t.Merchant, &t.MCC, &t.Counterparty, &t.AccountID, &t.Country, &t.Type,
+		&r.RiskLevel, &r.Reason, &r.Status, &occurred, &ts); err != nil {
+		return Record{}, err
+	}
+	t.Amount = Money(amount)
+	t.Timestamp = time.Unix(0, occurred).UTC()
+	r.Timestamp = time.Unix(0, ts).UTC()
+	return r, nil
+}
+
+// OpenSQLiteStore opens (creating if needed) the database at path. Use
+// ":memory:" for a throwaway database.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// SQLite serializes writers anyway, and an in-memory database exists
+	// only on the connection that created it.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+
+	s := &SQLiteStore{db: db}
+	stmts := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&s.insert, `INSERT INTO transactions (transaction_id, amount, currency, merchant, merchant_key, mcc, counterparty, account, country, type, level, reason, status, occurred, ts)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`},
+		{&s.recent, `SELECT ` + recordColumns + ` FROM transactions ORDER BY ts DESC, id DESC LIMIT ?`},
+		{&s.all, `SELECT ` + recordColumns + ` FROM transactions ORDER BY id`},
+		{&s.history, `SELECT ` + recordColumns + ` FROM transactions
+			WHERE occurred >= ? AND occurred < ? AND id < ? AND (? = '' OR status = ?) ORDER BY id DESC LIMIT ?`},
+		{&s.update, `UPDATE transactions SET level = ?, reason = ?,
+			status = CASE WHEN ? = 'HIGH' AND status = '' THEN 'pending' ELSE status END WHERE id = ?`},
+		{&s.get, `SELECT ` + recordColumns + ` FROM transactions WHERE id = ?`},
+		{&s.find, `SELECT ` + recordColumns + ` FROM transactions WHERE transaction_id = ? ORDER BY id DESC LIMIT 1`},
+		{&s.status, `UPDATE transactions SET status = ? WHERE id = ?`},
+		{&s.count, `SELECT COUNT(*) FROM transactions`},
+		{&s.totals, `SELECT account, currency, SUM(amount), COUNT(*) FROM transactions
+			WHERE account != '' AND type != 'credit' AND occurred >= ? AND occurred < ? GROUP BY account, currency`},
+		{&s.merchant, `SELECT merchant_key, MIN(merchant), level, COUNT(*) FROM transactions
+			WHERE occurred >= ? AND occurred < ? GROUP BY merchant_key, level`},
+		{&s.baseline, `SELECT currency, SUM(amount), COUNT(*) FROM transactions
+			WHERE account = ? AND type != 'credit' AND occurred > ? GROUP BY currency`},
+		{&s.stats, `SELECT level, currency, type, COUNT(*), SUM(amount), MAX(amount) FROM transactions
+			GROUP BY level, currency, type`},
+	}
+	for _, st := range stmts {
+		if *st.dst, err = db.Prepare(st.query); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("prepare: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Close releases the prepared statements and the database.
+func (s *SQLiteStore) Close() error {
+	for _, st := range []*sql.Stmt{s.insert, s.recent, s.count, s.totals, s.merchant, s.baseline, s.stats, s.all, s.update, s.history, s.get, s.find, s.status} {
+		if st != nil {
+			st.Close()
+		}
+	}
+	return s.db.Close()
+}
+
+// Ping checks that the database is reachable.
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteStore) Append(ctx context.Context, r Record) error {
+	t := r.Transaction
+	_, err := s.insert.ExecContext(ctx, t.ID, int64(t.Amount), currencyOf(t), t.Merchant, normalizeMerchant(t.Merchant),
+		t.MCC, t.Counterparty, t.AccountID, countryOf(t), typeOf(t), r.RiskLevel, r.Reason, r.Status, t.Timestamp.UnixNano(), r.Timestamp.UnixNano())
+	if err != nil {
+		return fmt.Errorf("sqlite append: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Recent(ctx context.Context, limit int) ([]Record, error) {
+	rows, err := s.recent.QueryContext(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite recent: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Record
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite recent: %w", err)
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite recent: %w", err)
+	}
+	return out, nil
+}
+
+func (s *SQLiteStore) History(ctx context.Context, q HistoryQuery) ([]Record, error) {
+	from, to, before := int64(math.MinInt64), int64(math.MaxInt64), int64(math.MaxInt64)
+	if !q.From.IsZero() {
+		from = q.From.UnixNano()
+	}
+	if !q.To.IsZero() {
+		to = q.To.UnixNano()
+	}
+	if q.Before != 0 {
+		before = q.Before
+	}
+	rows, err := s.history.QueryContext(ctx, from, to, before, q.Status, q.Status, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite history: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Record
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite history: %w", err)
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite history: %w", err)
+	}
+	return out, nil
+}
+
+// Len returns the number of stored records, or 0 if the count fails.
+func (s *SQLiteStore) Len() int {
+	var n int
+	if err := s.count.QueryRow().Scan(&n); err != nil {
+		logger.Error("sqlite count failed", "error", err)
+	}
+	return n
+}
+
+func (s *SQLiteStore) AccountTotals(ctx context.Context, from, to time.Time) (map[string]accountTotal, error) {
+	rows, err := s.totals.QueryContext(ctx, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("sqlite totals: %w", err)
+	}
+	defer rows.Close()
+	totals := map[string]accountTotal{}
+	for rows.Next() {
+		var (
+			account, currency string
+			sum               int64
+			n                 int
+		)
+		if err := rows.Scan(&account, &currency, &sum, &n); err != nil {
+			return nil, fmt.Errorf("sqlite totals: %w", err)
+		}
+		at := totals[account]
+		at.Total += inUSD(Transaction{Amount: Money(sum), Currency: currency}).Amount
+		at.Count += n
+		totals[account] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite totals: %w", err)
+	}
+	return totals, nil
+}
+
+func (s *SQLiteStore) MerchantTotals(ctx context.Context, from, to time.Time) (map[string]merchantTotal, error) {
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+	if !from.IsZero() {
+		lo = from.UnixNano()
+	}
+	if !to.IsZero() {
+		hi = to.UnixNano()
+	}
+	rows, err := s.merchant.QueryContext(ctx, lo, hi)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite merchant totals: %w", err)
+	}
+	defer rows.Close()
+	totals := map[string]merchantTotal{}
+	for rows.Next() {
+		var (
+			key, merchant, level string
+			n                    int
+		)
+		if err := rows.Scan(&key, &merchant, &level, &n); err != nil {
+			return nil, fmt.Errorf("sqlite merchant totals: %w", err)
+		}
+		mt := totals[key]
+		if mt.Merchant == "" || merchant < mt.Merchant {
+			mt.Merchant = merchant
+		}
+		mt.Levels.add(level, n)
+		totals[key] = mt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite merchant totals: %w", err)
+	}
+	return totals, nil
+}
+
+func (s *SQLiteStore) AccountTotal(ctx context.Context, account string, day time.Time) (Money, error) {
+	from, to := dayBounds(day)
+	totals, err := s.AccountTotals(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return totals[account].Total, nil
+}
+
+func (s *SQLiteStore) AccountBaseline(ctx context.Context, account string, since time.Time) (Money, int, error) {
+	rows, err := s.baseline.QueryContext(ctx, account, since.UnixNano())
+	if err != nil {
+		return 0, 0, fmt.Errorf("sqlite baseline: %w", err)
+	}
+	defer rows.Close()
+	var (
+		sum   Money
+		total int
+	)
+	for rows.Next() {
+		var (
+			currency string
+			amount   int64
+			n        int
+		)
+		if err := rows.Scan(&currency, &amount, &n); err != nil {
+			return 0, 0, fmt.Errorf("sqlite baseline: %w", err)
+		}
+		sum += inUSD(Transaction{Amount: Money(amount), Currency: currency}).Amount
+		total += n
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("sqlite baseline: %w", err)
+	}
+	if total == 0 {
+		return 0, 0, nil
+	}
+	return sum / Money(total), total, nil
+}
+
+func (s *SQLiteStore) Stats(ctx context.Context) (Stats, error) {
+	rows, err := s.stats.QueryContext(ctx)
+	if err != nil {
+		return Stats{}, fmt.Errorf("sqlite stats: %w", err)
+	}
+	defer rows.Close()
+	var b statsBuilder
+	for rows.Next() {
+		var (
+			level, currency, typ string
+			n                    int
+			sum, max             int64
+		)
+		if err := rows.Scan(&level, &currency, &typ, &n, &sum, &max); err != nil {
+			return Stats{}, fmt.Errorf("sqlite stats: %w", err)
+		}
+		usd := func(m int64) Money { return inUSD(Transaction{Amount: Money(m), Currency: currency}).Amount }
+		if typ == TypeCredit {
+			b.addCredits(n, usd(sum))
+			continue
+		}
+		b.add(level, n, usd(sum), usd(max))
+	}
+	if err := rows.Err(); err != nil {
+		return Stats{}, fmt.Errorf("sqlite stats: %w", err)
+	}
+	return b.result(), nil
+}
+
+func (s *SQLiteStore) Rescore(ctx context.Context, commit bool, fn func(Record) Record) error {
+	type change struct {
+		id            int64
+		level, reason string
+	}
+	var changes []change
+	rows, err := s.all.QueryContext(ctx)
+	if err != nil {
+		return fmt.Errorf("sqlite rescore: %w", err)
+	}
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("sqlite rescore: %w", err)
+		}
+		next := fn(r)
+		if next.RiskLevel != r.RiskLevel || next.Reason != r.Reason {
+			changes = append(changes, change{r.ID, next.RiskLevel, next.Reason})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sqlite rescore: %w", err)
+	}
+	if !commit || len(changes) == 0 {
+		return nil
+	}
+
+	// The single connection is free again now that rows is closed.
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite rescore: %w", err)
+	}
+	update := tx.StmtContext(ctx, s.update)
+	for _, c := range changes {
+		if _, err := update.ExecContext(ctx, c.level, c.reason, c.level, c.id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("sqlite rescore: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite rescore: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) FindTransaction(ctx context.Context, id string) (Record, error) {
+	r, err := scanRecord(s.find.QueryRowContext(ctx, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, errRecordNotFound
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("sqlite find transaction: %w", err)
+	}
+	return r, nil
+}
+
+func (s *SQLiteStore) SetStatus(ctx context.Context, id int64, status string) (Record, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Record{}, fmt.Errorf("sqlite set status: %w", err)
+	}
+	defer tx.Rollback()
+	r, err := scanRecord(tx.StmtContext(ctx, s.get).QueryRowContext(ctx, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, errRecordNotFound
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("sqlite set status: %w", err)
+	}
+	if err := checkTransition(r.Status, status); err != nil {
+		return Record{}, err
+	}
+	if _, err := tx.StmtContext(ctx, s.status).ExecContext(ctx, status, id); err != nil {
+		return Record{}, fmt.Errorf("sqlite set status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Record{}, fmt.Errorf("sqlite set status: %w", err)
+	}
+	r.Status = status
+	return r, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestSQLite(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	s, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteStoreRoundTrip(t *testing.T) {
+	s := openTestSQLite(t, filepath.Join(t.TempDir(), "risk.db"))
+	ctx := t.Context()
+	if err := s.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	in := []Record{
+		{Transaction: Transaction{ID: "tx-1", Amount: Money(1234), Merchant: "Corner Shop", Currency: "EUR", AccountID: "a", Timestamp: noon.Add(-time.Hour)}, RiskLevel: "LOW", Reason: noRulesReason, Timestamp: noon},
+		{Transaction: Transaction{ID: "tx-2", Amount: dollars(20000), Merchant: "m", AccountID: "a", Timestamp: noon}, RiskLevel: "HIGH", Reason: "big", Status: "pending", Timestamp: noon.Add(time.Second)},
+	}
+	for _, r := range in {
+		if err := s.Append(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.Len(); n != 2 {
+		t.Fatalf("Len %d, want 2", n)
+	}
+	recent, err := s.Recent(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Transaction.ID != "tx-2" || recent[1].Transaction.ID != "tx-1" {
+		t.Fatalf("Recent: %+v", recent)
+	}
+	got := recent[1]
+	if got.Transaction.Amount != Money(1234) || got.Transaction.Currency != "EUR" || !got.Transaction.Timestamp.Equal(noon.Add(-time.Hour)) || !got.Timestamp.Equal(noon) {
+		t.Fatalf("record did not round-trip: %+v", got)
+	}
+
+	r, err := s.FindTransaction(ctx, "tx-2")
+	if err != nil || r.RiskLevel != "HIGH" {
+		t.Fatalf("FindTransaction: %+v, %v", r, err)
+	}
+	if _, err := s.FindTransaction(ctx, "missing"); !errors.Is(err, errRecordNotFound) {
+		t.Fatalf("FindTransaction of an unknown id: %v", err)
+	}
+	if r, err = s.SetStatus(ctx, r.ID, "confirmed"); err != nil || r.Status != "confirmed" {
+		t.Fatalf("SetStatus: %+v, %v", r, err)
+	}
+}
+
+func TestSQLiteStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "risk.db")
+	s, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Record{Transaction: Transaction{Amount: dollars(5), Merchant: "m", Timestamp: noon}, RiskLevel: "LOW", Timestamp: noon}
+	if err := s.Append(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := openTestSQLite(t, path).Len(); n != 1 {
+		t.Fatalf("%d records after reopening, want 1", n)
+	}
+}
+
+func TestSQLiteStoreAccountTotals(t *testing.T) {
+	s := openTestSQLite(t, filepath.Join(t.TempDir(), "risk.db"))
+	ctx := t.Context()
+	day := time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC)
+	for _, tx := range []Transaction{
+		{AccountID: "a", Amount: dollars(6000), Timestamp: day.Add(15 * time.Hour)},
+		{AccountID: "a", Amount: dollars(6000), Timestamp: day.Add(16 * time.Hour)},
+		{AccountID: "a", Amount: dollars(6000), Timestamp: day.Add(16 * time.Hour), Type: "credit"},
+		{AccountID: "b", Amount: dollars(100), Timestamp: day.Add(-time.Hour)},
+	} {
+		tx.Merchant = "m"
+		// Received the next day: totals must follow the transaction time.
+		if err := s.Append(ctx, Record{Transaction: tx, RiskLevel: "LOW", Timestamp: noon}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	totals, err := s.AccountTotals(ctx, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals["a"].Total != dollars(12000) || totals["a"].Count != 2 {
+		t.Fatalf("totals %+v", totals)
+	}
+	avg, n, err := s.AccountBaseline(ctx, "a", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || avg != dollars(6000) {
+		t.Fatalf("baseline avg %s over %d", avg, n)
+	}
+}
+
+func TestSQLiteStoreRescore(t *testing.T) {
+	s := openTestSQLite(t, filepath.Join(t.TempDir(), "risk.db"))
+	ctx := t.Context()
+	r := Record{Transaction: Transaction{Amount: dollars(5), Merchant: "m", Timestamp: noon}, RiskLevel: "LOW", Timestamp: noon}
+	if err := s.Append(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	raise := func(r Record) Record { r.RiskLevel, r.Reason = "HIGH", "raised"; return r }
+	if err := s.Rescore(ctx, false, raise); err != nil {
+		t.Fatal(err)
+	}
+	if recent, _ := s.Recent(ctx, 1); recent[0].RiskLevel != "LOW" {
+		t.Fatal("dry rescore changed the record")
+	}
+	if err := s.Rescore(ctx, true, raise); err != nil {
+		t.Fatal(err)
+	}
+	recent, _ := s.Recent(ctx, 1)
+	if recent[0].RiskLevel != "HIGH" || recent[0].Status != "pending" {
+		t.Fatalf("committed rescore: %+v", recent[0])
+	}
+}
+
+func TestSQLiteStoreServesAPI(t *testing.T) {
+	api, tn := newTestAPI(t)
+	tn.store = openTestSQLite(t, filepath.Join(t.TempDir(), "risk.db"))
+	for _, body := range []string{`{"amount": 10, "merchant": "a"}`, `{"amount": 20000, "merchant": "b"}`} {
+		if rec := do(api, "POST", "/risk", body); rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	rec := do(api, "GET", "/transactions", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if s := rec.Body.String(); !strings.Contains(s, `"merchant":"a"`) || !strings.Contains(s, `"merchant":"b"`) {
+		t.Fatalf("transactions %s", s)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentScoresAreAllRecorded(t *testing.T) {
+	api, _ := newTestAPI(t)
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if rec := do(api, "POST", "/risk", fmt.Sprintf(`{"id": "tx-%d", "amount": %d, "merchant": "m"}`, i, i+1)); rec.Code != http.StatusOK {
+				t.Errorf("score %d: status %d", i, rec.Code)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	rec := do(api, "GET", "/transactions?limit=1000", "")
+	page := decode[HistoryPage](t, rec)
+	if len(page.Transactions) != n {
+		t.Fatalf("history has %d records, want %d", len(page.Transactions), n)
+	}
+	for i := 1; i < len(page.Transactions); i++ {
+		if page.Transactions[i-1].ID <= page.Transactions[i].ID {
+			t.Fatalf("history not newest first at %d: %d then %d", i, page.Transactions[i-1].ID, page.Transactions[i].ID)
+		}
+	}
+}
+
+func TestMemoryStoreKeepsNewest(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(3)
+	for i := 1; i <= 4; i++ {
+		s.Append(ctx, Record{Transaction: Transaction{ID: fmt.Sprint(i)}})
+	}
+	recs, err := s.Recent(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range recs {
+		ids = append(ids, r.Transaction.ID)
+	}
+	if got := strings.Join(ids, ","); got != "4,3,2" {
+		t.Fatalf("Recent = %s, want 4,3,2", got)
+	}
+}
+
+// spyStore records which Store methods the handlers call.
+type spyStore struct {
+	*MemoryStore
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *spyStore) called(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, method)
+}
+
+func (s *spyStore) Append(ctx context.Context, r Record) error {
+	s.called("Append")
+	return s.MemoryStore.Append(ctx, r)
+}
+
+func (s *spyStore) History(ctx context.Context, q HistoryQuery) ([]Record, error) {
+	s.called("History")
+	return s.MemoryStore.History(ctx, q)
+}
+
+func (s *spyStore) AccountTotal(ctx context.Context, account string, day time.Time) (Money, error) {
+	s.called("AccountTotal")
+	return s.MemoryStore.AccountTotal(ctx, account, day)
+}
+
+// flakyStore fails the next failures calls to Append.
+type flakyStore struct {
+	*MemoryStore
+	failures atomic.Int32
+}
+
+var errStoreDown = errors.New("store unavailable")
+
+func (s *flakyStore) Append(ctx context.Context, r Record) error {
+	if s.failures.Add(-1) >= 0 {
+		return errStoreDown
+	}
+	return s.MemoryStore.Append(ctx, r)
+}
+
+func TestHandlersUseStore(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &dailyLimit, dollars(50000))
+	spy := &spyStore{MemoryStore: NewMemoryStore(defaultHistorySize)}
+	tn.store = spy
+
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m", "account_id": "acct-1"}`)
+	if got := strings.Join(spy.calls, ","); got != "AccountTotal,Append" {
+		t.Fatalf("POST /risk called %s, want AccountTotal,Append", got)
+	}
+	spy.calls = nil
+	do(api, "GET", "/transactions", "")
+	if got := strings.Join(spy.calls, ","); got != "History" {
+		t.Fatalf("GET /transactions called %s, want History", got)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultTenant serves every request when no tenants are configured.
+const defaultTenant = "default"
+
+// maxTenantIDLen bounds tenant IDs, which also name per-tenant files.
+const maxTenantIDLen = 64
+
+// validTenantID reports whether id is 1–64 ASCII letters, digits, '-' or '_'.
+func validTenantID(id string) bool {
+	if id == "" || len(id) > maxTenantIDLen {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Tenant is one client organization's isolated state: its history (and so
+// its velocity, anomaly and merchant outlier baselines), its rule set, its
+// thresholds and its merchant allow and deny lists.
+type Tenant struct {
+	ID     string
+	store  Store
+	rules  *ActiveRules
+	config *Config
+
+	velocity      *velocityScores
+	merchantStats *merchantStats
+	merchantLists *MerchantLists
+}
+
+func NewTenant(id string, store Store, rules *ActiveRules, config *Config) *Tenant {
+	return &Tenant{ID: id, store: store, rules: rules, config: config, velocity: newVelocityScores(), merchantStats: newMerchantStats(), merchantLists: NewMerchantLists()}
+}
+
+// Tenants is the fixed set of tenants a server handles.
+type Tenants struct {
+	byID map[string]*Tenant
+	// required is set when tenants were configured explicitly; requests must
+	// then identify theirs.
+	required bool
+}
+
+// NewTenants returns a set holding def as the default tenant. Adding further
+// tenants makes tenant resolution mandatory.
+func NewTenants(def *Tenant) *Tenants {
+	return &Tenants{byID: map[string]*Tenant{def.ID: def}}
+}
+
+// Add registers t.
+func (ts *Tenants) Add(t *Tenant) {
+	ts.byID[t.ID] = t
+	ts.required = true
+}
+
+// Get returns the tenant with id.
+func (ts *Tenants) Get(id string) (*Tenant, bool) {
+	t, ok := ts.byID[id]
+	return t, ok
+}
+
+// Default returns the default tenant.
+func (ts *Tenants) Default() *Tenant {
+	return ts.byID[defaultTenant]
+}
+
+// IDs returns the tenant IDs in sorted order.
+func (ts *Tenants) IDs() []string {
+	ids := make([]string, 0, len(ts.byID))
+	for id := range ts.byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// parseTenantIDs splits a comma-separated tenant list, validating each ID.
+func parseTenantIDs(s string) ([]string, error) {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		if !validTenantID(id) {
+			return nil, fmt.Errorf("invalid tenant ID %q", id)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// tenantDBPath derives a tenant's SQLite path from the -db path by inserting
+// the tenant ID before the extension: "risk.db" becomes "risk.acme.db". The
+// default tenant uses the path as given.
+func tenantDBPath(path, id string) string {
+	if id == defaultTenant || path == ":memory:" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + id + ext
+}
+
+// tenantRulesPath returns dir/<id>.json when that file exists, and fallback
+// otherwise.
+func tenantRulesPath(dir, id, fallback string) string {
+	if dir == "" {
+		return fallback
+	}
+	p := filepath.Join(dir, id+".json")
+	if _, err := os.Stat(p); err != nil {
+		return fallback
+	}
+	return p
+}
+
+// tenantFrom returns the tenant stored in ctx, or nil if there is none.
+func tenantFrom(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(tenantKey).(*Tenant)
+	return t
+}
+
+// resolveTenant picks the calling tenant for apiKey and the tenant ID the
+// caller asked for, if any. An API key bound to a tenant decides it, and the
+// requested ID must agree if sent; unbound keys name their tenant explicitly.
+// Without configured tenants everything resolves to the default tenant. The
+// error is safe to show the caller.
+func (s *Server) resolveTenant(apiKey, requested string) (*Tenant, error) {
+	bound, _ := lookupAPIKey(apiKey)
+	id := requested
+	switch {
+	case id != "" && bound != "" && id != bound:
+		return nil, errors.New("API key is not valid for this tenant")
+	case id == "":
+		id = bound
+	}
+	if id == "" {
+		if s.tenants.required {
+			return nil, errors.New("tenant could not be determined; send X-Tenant-ID")
+		}
+		id = defaultTenant
+	}
+	t, ok := s.tenants.Get(id)
+	if !ok {
+		return nil, errors.New("unknown tenant")
+	}
+	return t, nil
+}
+
+// requireTenant resolves the calling tenant from X-API-Key and X-Tenant-ID
+// with resolveTenant and stores it in the request context. It must run after
+// requireAPIKey.
+func (s *Server) requireTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, err := s.resolveTenant(r.Header.Get("X-API-Key"), r.Header.Get("X-Tenant-ID"))
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey, t)))
+	})
+}
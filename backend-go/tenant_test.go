@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// newTenantsAPI returns an API serving the tenants "acme" and "globex".
+// testKey may act for either; "acme-key" is bound to acme.
+func newTenantsAPI(t *testing.T) http.Handler {
+	t.Helper()
+	setClock(t, noon)
+	setVar(t, &apiKeys, []apiKey{{key: []byte(testKey)}, {key: []byte("acme-key"), tenant: "acme"}})
+	setVar(t, &limiters, newLimiterSet())
+	setVar(t, &auditLog, &AuditLog{})
+	tenants := NewTenants(newTestTenant(t, defaultTenant))
+	tenants.Add(newTestTenant(t, "acme"))
+	tenants.Add(newTestTenant(t, "globex"))
+	mux := http.NewServeMux()
+	registerRoutes(mux, NewServer(tenants))
+	return mux
+}
+
+func TestTenantHistoryIsolated(t *testing.T) {
+	api := newTenantsAPI(t)
+	if rec := do(api, "POST", "/risk", `{"amount": 10, "merchant": "m", "id": "acme-1"}`, "X-Tenant-ID", "acme"); rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if page := decode[HistoryPage](t, do(api, "GET", "/transactions", "", "X-Tenant-ID", "globex")); len(page.Transactions) != 0 {
+		t.Fatalf("globex sees %d of acme's transactions", len(page.Transactions))
+	}
+	page := decode[HistoryPage](t, do(api, "GET", "/transactions", "", "X-API-Key", "acme-key"))
+	if len(page.Transactions) != 1 || page.Transactions[0].Transaction.ID != "acme-1" {
+		t.Fatalf("acme history: %+v", page.Transactions)
+	}
+}
+
+func TestTenantThresholdsIndependent(t *testing.T) {
+	api := newTenantsAPI(t)
+	if rec := do(api, "PATCH", "/config", `{"medium_threshold": 100, "high_threshold": 500}`, "X-Tenant-ID", "acme"); rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	body := `{"amount": 600, "merchant": "m"}`
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body, "X-Tenant-ID", "acme")); res.RiskLevel != "HIGH" {
+		t.Fatalf("acme: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body, "X-Tenant-ID", "globex")); res.RiskLevel != "LOW" {
+		t.Fatalf("globex: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestTenantMerchantListsIsolated(t *testing.T) {
+	api := newTenantsAPI(t)
+	if rec := do(api, "POST", "/merchants/deny", `{"merchant": "Corner Shop"}`, "X-Tenant-ID", "acme"); rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if v := decode[MerchantListsView](t, do(api, "GET", "/merchants", "", "X-Tenant-ID", "globex")); len(v.Deny) != 0 {
+		t.Fatalf("globex sees acme's deny list: %+v", v)
+	}
+	body := `{"amount": 10, "merchant": "Corner Shop"}`
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body, "X-Tenant-ID", "acme")); res.RiskLevel != "HIGH" {
+		t.Fatalf("acme: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+	if res := decode[ScoreResult](t, do(api, "POST", "/risk", body, "X-Tenant-ID", "globex")); res.RiskLevel != "LOW" {
+		t.Fatalf("globex: got %s (%s)", res.RiskLevel, res.Reason)
+	}
+}
+
+func TestTenantUnresolved(t *testing.T) {
+	api := newTenantsAPI(t)
+	for _, tc := range []struct {
+		name   string
+		header []string
+	}{
+		{"no tenant", nil},
+		{"unknown tenant", []string{"X-Tenant-ID", "initech"}},
+		{"key bound elsewhere", []string{"X-API-Key", "acme-key", "X-Tenant-ID", "globex"}},
+	} {
+		if rec := do(api, "GET", "/transactions", "", tc.header...); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want 401", tc.name, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestTimeout bounds how long a handler may run before the client gets a 503.
+var requestTimeout = 5 * time.Second
+
+// Connection timeouts for the HTTP server, so slow or idle clients cannot hold
+// connections open indefinitely. serverWriteTimeout should exceed
+// requestTimeout so the 503 for a timed-out request still reaches the client.
+var (
+	serverReadHeaderTimeout = 2 * time.Second
+	serverReadTimeout       = 5 * time.Second
+	serverWriteTimeout      = 10 * time.Second
+	serverIdleTimeout       = 120 * time.Second
+)
+
+// newHTTPServer returns a server for h with the connection timeouts applied.
+func newHTTPServer(h http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		ReadTimeout:       serverReadTimeout,
+		WriteTimeout:      serverWriteTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+}
+
+// timeoutMiddleware gives each request a deadline of requestTimeout. The
+// handler runs in its own goroutine writing to a buffer; if the deadline
+// passes first the client gets a 503 and anything the handler writes later
+// is discarded. The handler's context is done from then on, which stops it
+// recording the transaction; see process.
+func timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+		r.Body = &ctxBody{ctx: ctx, ReadCloser: r.Body}
+
+		// Start from the headers set so far, such as X-Request-ID, so the
+		// handler can see them.
+		tw := &timeoutWriter{header: w.Header().Clone()}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			for k, v := range tw.header {
+				w.Header()[k] = v
+			}
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+			w.WriteHeader(tw.status)
+			w.Write(tw.buf.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				writeError(w, http.StatusServiceUnavailable, "request timed out")
+			}
+		}
+	})
+}
+
+// timeoutWriter buffers a handler's response until timeoutMiddleware decides
+// whether to send it.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.status == 0 {
+		tw.status = code
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+// ctxBody fails reads that start after the request context is done, so a
+// handler reading a slow client's body stops at its next read after the
+// deadline. A read already blocked on the connection is not interrupted;
+// serverReadTimeout bounds that.
+type ctxBody struct {
+	ctx context.Context
+	io.ReadCloser
+}
+
+func (b *ctxBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return b.ReadCloser.Read(p)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowBody returns its content only after delay.
+type slowBody struct {
+	delay time.Duration
+	r     io.Reader
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	time.Sleep(b.delay)
+	return b.r.Read(p)
+}
+
+func TestTimeoutSlowBody(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &requestTimeout, 20*time.Millisecond)
+	finished := make(chan struct{})
+	h := timeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(finished)
+		api.ServeHTTP(w, r)
+	}))
+
+	r := httptest.NewRequest("POST", "/risk", &slowBody{delay: 100 * time.Millisecond, r: strings.NewReader(`{"amount": 5, "merchant": "m"}`)})
+	r.Header.Set("X-API-Key", testKey)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want 503", rec.Code)
+	}
+	if e := decode[ErrorResponse](t, rec); e.Error != "request timed out" {
+		t.Fatalf("body %+v", e)
+	}
+
+	// The handler outlives the 503 but must not record the transaction.
+	<-finished
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("%d records stored for a timed-out request", n)
+	}
+}
+
+func TestProcessAfterContextDoneRecordsNothing(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tn.process(ctx, Transaction{Amount: dollars(5), Merchant: "m", AccountID: "a"}); err == nil {
+		t.Fatal("process succeeded with a done context")
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("%d records stored", n)
+	}
+	if s := tn.velocityScore("a", now()); s != 0 {
+		t.Fatalf("velocity score %v kept for an unrecorded transaction", s)
+	}
+}
+
+// cancelOnAppend records a transaction and then cancels the request, like a
+// timeout firing just after the write.
+type cancelOnAppend struct {
+	*MemoryStore
+	cancel context.CancelFunc
+}
+
+func (s cancelOnAppend) Append(ctx context.Context, r Record) error {
+	err := s.MemoryStore.Append(ctx, r)
+	s.cancel()
+	return err
+}
+
+func TestProcessContextDoneAfterRecordSucceeds(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	srv, _, calls := webhookTarget(t, 0)
+	setVar(t, &webhook, newWebhookNotifier(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := cancelOnAppend{NewMemoryStore(defaultHistorySize), cancel}
+	tn.store = store
+
+	res, err := tn.process(ctx, Transaction{Amount: dollars(20000), Merchant: "m", AccountID: "a"})
+	if err != nil || res.RiskLevel != "HIGH" {
+		t.Fatalf("got %s, %v; want the recorded HIGH decision", res.RiskLevel, err)
+	}
+	if n := store.Len(); n != 1 {
+		t.Fatalf("%d records stored, want 1", n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("webhook called %d times after the context was done", n)
+	}
+}
+
+func TestNewHTTPServerTimeouts(t *testing.T) {
+	setVar(t, &serverReadHeaderTimeout, time.Second)
+	setVar(t, &serverReadTimeout, 2*time.Second)
+	setVar(t, &serverWriteTimeout, 3*time.Second)
+	setVar(t, &serverIdleTimeout, 4*time.Second)
+	srv := newHTTPServer(http.NotFoundHandler())
+	if srv.ReadHeaderTimeout != time.Second || srv.ReadTimeout != 2*time.Second || srv.WriteTimeout != 3*time.Second || srv.IdleTimeout != 4*time.Second {
+		t.Fatalf("timeouts %v %v %v %v", srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+}
+
+// serveSlow starts newHTTPServer for h and returns a raw connection to it.
+func serveSlow(t *testing.T, h http.Handler) net.Conn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newHTTPServer(h)
+	srv.ErrorLog = log.New(io.Discard, "", 0)
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestReadHeaderTimeoutCutsOffSlowClient(t *testing.T) {
+	setVar(t, &serverReadHeaderTimeout, 50*time.Millisecond)
+	conn := serveSlow(t, http.NotFoundHandler())
+	// Send the request line and part of the headers, then stall like a
+	// slow-loris client.
+	if _, err := io.WriteString(conn, "GET /healthz HTTP/1.1\r\nHost: x\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	start := time.Now()
+	n, err := conn.Read(make([]byte, 1))
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("server kept the stalled connection open")
+	}
+	if n != 0 || err == nil {
+		t.Fatalf("read %d bytes (%v) from a stalled connection", n, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("connection closed after %s", d)
+	}
+}
+
+func TestReadTimeoutCutsOffSlowBody(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &serverReadTimeout, 100*time.Millisecond)
+	conn := serveSlow(t, api)
+	body := `{"amount": 5, "merchant": "m"}`
+	fmt.Fprintf(conn, "POST /risk HTTP/1.1\r\nHost: x\r\nX-API-Key: %s\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", testKey, len(body), body[:5])
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("server kept waiting for the body")
+	}
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Fatal("truncated body was scored")
+		}
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 0 {
+		t.Fatalf("%d records stored", n)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Transaction is a single payment submitted for risk scoring.
+type Transaction struct {
+	// ID is the client's identifier for the transaction, echoed in its
+	// decision and used to look it up later. One is generated when absent.
+	ID       string `json:"id,omitempty"`
+	Amount   Money  `json:"amount"`
+	Merchant string `json:"merchant"`
+	Currency string `json:"currency,omitempty"`
+	MCC      string `json:"mcc,omitempty"`
+
+	Counterparty string `json:"counterparty,omitempty"`
+	AccountID    string `json:"account_id,omitempty"`
+	// Country is the ISO 3166-1 alpha-2 code the transaction originated from.
+	Country string `json:"country,omitempty"`
+	// Type is debit (the default) or credit. Credits, such as refunds, may
+	// carry negative amounts.
+	Type string `json:"type,omitempty"`
+
+	// Timestamp is when the transaction occurred. It defaults to the time it
+	// was received.
+	Timestamp time.Time `json:"timestamp"`
+
+	// amountMissing is set when the decoded payload had no amount or a null one.
+	amountMissing bool
+	// amountPlaces is the number of significant decimal places the amount
+	// was submitted with, before it was rounded to the cent.
+	amountPlaces int
+}
+
+// UnmarshalJSON decodes a transaction while recording whether the amount was
+// actually present, so a missing or null amount isn't mistaken for 0, and the
+// precision it was given with. Unknown fields are an error.
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	type plain Transaction
+	aux := struct {
+		*plain
+		Amount *json.RawMessage `json:"amount"`
+	}{plain: (*plain)(t)}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&aux); err != nil {
+		return err
+	}
+	if aux.Amount == nil {
+		t.amountMissing = true
+		return nil
+	}
+	if err := t.Amount.UnmarshalJSON(*aux.Amount); err != nil {
+		return err
+	}
+	s, _ := moneyText(*aux.Amount)
+	t.amountPlaces = decimalPlaces(s)
+	return nil
+}
+
+// MarshalJSON encodes the amount in its currency's display precision, e.g.
+// whole yen for JPY.
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	type plain Transaction
+	return json.Marshal(struct {
+		Amount json.Number `json:"amount"`
+		plain
+	}{json.Number(formatAmount(t.Amount, currencyOf(t))), plain(t)})
+}
+
+// ValidationError reports a transaction that decoded but can't be scored.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// checkAmountRange rejects transactions whose USD amount exceeds maxAmount
+// or cannot be computed. The currency must be supported.
+func checkAmountRange(t Transaction) error {
+	usd := t.Amount
+	if cur := currencyOf(t); cur != "USD" {
+		var err error
+		if usd, err = convertAmount(t.Amount, rates[cur]); err != nil {
+			return &ValidationError{Field: "amount", Message: err.Error()}
+		}
+	}
+	if usd > maxAmount || usd < -maxAmount {
+		return &ValidationError{Field: "amount", Message: fmt.Sprintf("amount exceeds the maximum of $%s", maxAmount)}
+	}
+	return nil
+}
+
+// validateTransaction checks that a decoded transaction can be scored.
+func validateTransaction(t Transaction) error {
+	if t.amountMissing {
+		return &ValidationError{Field: "amount", Message: "amount is required"}
+	}
+	if tt := typeOf(t); tt != TypeDebit && tt != TypeCredit {
+		return &ValidationError{Field: "type", Message: fmt.Sprintf("type must be %s or %s", TypeDebit, TypeCredit)}
+	}
+	if t.Amount < 0 && !isCredit(t) {
+		return &ValidationError{Field: "amount", Message: "amount must be a non-negative finite number"}
+	}
+	if _, ok := rates[currencyOf(t)]; !ok {
+		return &ValidationError{Field: "currency", Message: fmt.Sprintf("unsupported currency %q", t.Currency)}
+	}
+	if err := checkAmountRange(t); err != nil {
+		return err
+	}
+	if c := countryOf(t); c != "" && !validCountry(c) {
+		return &ValidationError{Field: "country", Message: fmt.Sprintf("country %q is not an ISO 3166-1 alpha-2 code", t.Country)}
+	}
+	if !knownMerchant(t.Merchant) {
+		return &ValidationError{Field: "merchant", Message: "unknown merchant"}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRiskRejectsInvalidAmounts(t *testing.T) {
+	api, _ := newTestAPI(t)
+	for _, body := range []string{
+		`{"amount": -5, "merchant": "m"}`,
+		`{"amount": -0.01, "merchant": "m"}`,
+		`{"amount": "NaN", "merchant": "m"}`,
+		`{"amount": null, "merchant": "m"}`,
+		`{"merchant": "m"}`,
+	} {
+		if rec := do(api, "POST", "/risk", body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422: %s", body, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestRiskAcceptsZeroAmount(t *testing.T) {
+	api, _ := newTestAPI(t)
+	rec := do(api, "POST", "/risk", `{"amount": 0, "merchant": "m"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if res := decode[ScoreResult](t, rec); res.RiskLevel != "LOW" {
+		t.Fatalf("got %s, want LOW", res.RiskLevel)
+	}
+}
+
+func TestTransactionIDEchoed(t *testing.T) {
+	api, tn := newTestAPI(t)
+	res := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 20, "merchant": "m", "id": "client-42"}`))
+	if res.TransactionID != "client-42" {
+		t.Fatalf("transaction_id %q, want client-42", res.TransactionID)
+	}
+	rec, err := tn.store.FindTransaction(t.Context(), "client-42")
+	if err != nil || rec.Transaction.ID != "client-42" {
+		t.Fatalf("stored record %+v, %v", rec, err)
+	}
+}
+
+func TestTransactionIDGenerated(t *testing.T) {
+	api, tn := newTestAPI(t)
+	a := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 20, "merchant": "m"}`))
+	b := decode[ScoreResult](t, do(api, "POST", "/risk", `{"amount": 20, "merchant": "m"}`))
+	if len(a.TransactionID) != 36 || a.TransactionID == b.TransactionID {
+		t.Fatalf("generated ids %q and %q", a.TransactionID, b.TransactionID)
+	}
+	if _, err := tn.store.FindTransaction(t.Context(), a.TransactionID); err != nil {
+		t.Fatalf("generated id not stored: %v", err)
+	}
+
+	batch := decode[[]BatchResult](t, do(api, "POST", "/risk/batch", `[{"amount": 1, "merchant": "m", "id": "b-1"}, {"amount": 2, "merchant": "m"}]`))
+	if len(batch) != 2 || batch[0].TransactionID != "b-1" || batch[1].TransactionID == "" {
+		t.Fatalf("batch results %+v", batch)
+	}
+}
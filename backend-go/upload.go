@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxUploadBytes caps the size of a CSV backfill accepted by /risk/upload.
+// Rows are parsed as they stream in, so this bounds the request rather than
+// memory use.
+var maxUploadBytes int64 = 64 << 20
+
+// uploadColumns is the expected CSV layout. A header row with these names is
+// optional.
+var uploadColumns = []string{"amount", "merchant", "account", "timestamp"}
+
+// UploadSummary is the response body of POST /risk/upload.
+type UploadSummary struct {
+	Processed int        `json:"processed"`
+	Errors    []RowError `json:"errors"`
+}
+
+// RowError reports a CSV row that could not be scored. Line is 1-based.
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// parseUploadRow turns one CSV record into a transaction.
+func parseUploadRow(rec []string) (Transaction, error) {
+	text, err := ungroupAmount(rec[0])
+	if err != nil {
+		return Transaction{}, err
+	}
+	amount, err := parseMoney(text)
+	if err != nil {
+		return Transaction{}, err
+	}
+	t := Transaction{Amount: amount, Merchant: strings.TrimSpace(rec[1]), AccountID: strings.TrimSpace(rec[2]), amountPlaces: decimalPlaces(text)}
+	if ts := strings.TrimSpace(rec[3]); ts != "" {
+		if t.Timestamp, err = time.Parse(time.RFC3339, ts); err != nil {
+			return Transaction{}, fmt.Errorf("invalid timestamp %q: want RFC 3339", ts)
+		}
+	}
+	return t, validateTransaction(t)
+}
+
+// uploadCSV serves POST /risk/upload: it scores and stores each row of a
+// text/csv body with columns amount,merchant,account,timestamp. Malformed rows
+// are reported by line number and skipped; the rest are still processed. A
+// body over maxUploadBytes is answered with 413, though rows before the cap
+// have already been recorded.
+func (s *Server) uploadCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "text/csv" {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be text/csv")
+		return
+	}
+
+	cr := csv.NewReader(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+	cr.FieldsPerRecord = len(uploadColumns)
+	cr.ReuseRecord = true
+	cr.TrimLeadingSpace = true
+
+	tn := s.tenant(r.Context())
+	summary := UploadSummary{Errors: []RowError{}}
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, "upload too large")
+				return
+			}
+			if r.Context().Err() != nil {
+				writeError(w, http.StatusServiceUnavailable, "request timed out")
+				return
+			}
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				writeError(w, http.StatusBadRequest, "could not read request body")
+				return
+			}
+			summary.Errors = append(summary.Errors, RowError{Line: perr.Line, Error: err.Error()})
+			if !errors.Is(perr.Err, csv.ErrFieldCount) {
+				// Anything but a short or long row leaves the reader in an
+				// unknown position, so stop here.
+				break
+			}
+			continue
+		}
+		// FieldPos is only meaningful for a record read without error.
+		line, _ := cr.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(rec[0]), uploadColumns[0]) {
+			continue
+		}
+		t, err := parseUploadRow(rec)
+		if err != nil {
+			summary.Errors = append(summary.Errors, RowError{Line: line, Error: err.Error()})
+			continue
+		}
+		if _, err := tn.process(r.Context(), t); err != nil {
+			writeProcessError(w, r, err)
+			return
+		}
+		summary.Processed++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func upload(api http.Handler, body string) *httptest.ResponseRecorder {
+	return do(api, "POST", "/risk/upload", body, "Content-Type", "text/csv")
+}
+
+func TestUploadWellFormed(t *testing.T) {
+	api, tn := newTestAPI(t)
+	setVar(t, &amountLocale, localeUS)
+	rec := upload(api, "amount,merchant,account,timestamp\n"+
+		"10,Corner Shop,acct-1,2026-03-09T10:00:00Z\n"+
+		"\"1,250.00\",Corner Shop,acct-1,\n"+
+		"20000,Corner Shop,acct-2,2026-03-09T11:00:00Z\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := decode[UploadSummary](t, rec); got.Processed != 3 || len(got.Errors) != 0 {
+		t.Fatalf("summary %+v", got)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 3 {
+		t.Fatalf("%d transactions stored, want 3", n)
+	}
+}
+
+func TestUploadBadRows(t *testing.T) {
+	api, tn := newTestAPI(t)
+	rec := upload(api, "10,Corner Shop,acct-1,\n"+
+		"ten,Corner Shop,acct-1,\n"+
+		"10,Corner Shop\n"+
+		"10,Corner Shop,acct-1,yesterday\n"+
+		"20,Corner Shop,acct-1,\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	got := decode[UploadSummary](t, rec)
+	if got.Processed != 2 {
+		t.Fatalf("processed %d, want 2: %+v", got.Processed, got)
+	}
+	var lines []int
+	for _, e := range got.Errors {
+		lines = append(lines, e.Line)
+	}
+	if len(lines) != 3 || lines[0] != 2 || lines[1] != 3 || lines[2] != 4 {
+		t.Fatalf("error lines %v, want [2 3 4]: %+v", lines, got.Errors)
+	}
+	if n := tn.store.(*MemoryStore).Len(); n != 2 {
+		t.Fatalf("%d transactions stored, want 2", n)
+	}
+}
+
+func TestUploadMalformedQuote(t *testing.T) {
+	api, _ := newTestAPI(t)
+	rec := upload(api, "10,Corner Shop,acct-1,\n1\"0,x,a,\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	got := decode[UploadSummary](t, rec)
+	if got.Processed != 1 || len(got.Errors) != 1 || got.Errors[0].Line != 2 {
+		t.Fatalf("summary %+v, want 1 processed and an error on line 2", got)
+	}
+	if !strings.Contains(got.Errors[0].Error, "quote") {
+		t.Fatalf("error %q does not describe the bad quote", got.Errors[0].Error)
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	api, _ := newTestAPI(t)
+	setVar(t, &maxUploadBytes, 64)
+	rec := upload(api, strings.Repeat("10,Corner Shop,acct-1,\n", 10))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUploadRequiresCSV(t *testing.T) {
+	api, _ := newTestAPI(t)
+	if rec := do(api, "POST", "/risk/upload", "10,m,a,"); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status %d, want 415", rec.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Velocity rule settings. Each recorded transaction adds one point to its
+// account's velocity score, and the score halves every velocityHalfLife. A
+// transaction that would lift the score above velocityThreshold is escalated
+// to HIGH. A threshold of zero disables the rule.
+var (
+	velocityHalfLife  = 60 * time.Second
+	velocityThreshold = 5.0
+)
+
+// velocityFloor is the score below which an account is forgotten.
+const velocityFloor = 0.01
+
+// velocityPruneEvery is how many recorded transactions pass between sweeps for
+// forgotten accounts.
+const velocityPruneEvery = 1024
+
+// decayedScore is a velocity score as of at.
+type decayedScore struct {
+	score float64
+	at    time.Time
+}
+
+// valueAt returns the score decayed from d.at to now.
+func (d decayedScore) valueAt(now time.Time) float64 {
+	age := now.Sub(d.at)
+	if age <= 0 || velocityHalfLife <= 0 {
+		return d.score
+	}
+	return d.score * math.Exp2(-float64(age)/float64(velocityHalfLife))
+}
+
+// velocityScores tracks per-account velocity scores. It is safe for
+// concurrent use.
+type velocityScores struct {
+	mu     sync.Mutex
+	scores map[string]decayedScore
+	adds   int
+}
+
+func newVelocityScores() *velocityScores {
+	return &velocityScores{scores: make(map[string]decayedScore)}
+}
+
+// get returns account's score at now.
+func (v *velocityScores) get(account string, now time.Time) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.scores[account].valueAt(now)
+}
+
+// add records one transaction for account at now and returns the new score.
+// Reading and raising the score under one lock means concurrent callers each
+// see the score including every transaction added before theirs.
+func (v *velocityScores) add(account string, now time.Time) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	d := decayedScore{score: v.scores[account].valueAt(now) + 1, at: now}
+	v.scores[account] = d
+	if v.adds++; v.adds%velocityPruneEvery == 0 {
+		for a, d := range v.scores {
+			if d.valueAt(now) < velocityFloor {
+				delete(v.scores, a)
+			}
+		}
+	}
+	return d.score
+}
+
+// remove takes back a transaction added for account, as of now.
+func (v *velocityScores) remove(account string, now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	d, ok := v.scores[account]
+	if !ok {
+		return
+	}
+	v.scores[account] = decayedScore{score: math.Max(d.valueAt(now)-1, 0), at: now}
+}
+
+// velocityScore returns account's decayed velocity score at now. Accounts
+// with no recorded transactions score zero.
+func (tn *Tenant) velocityScore(account string, now time.Time) float64 {
+	if account == "" {
+		return 0
+	}
+	return tn.velocity.get(account, now)
+}
+
+// velocityCheck reports whether a new transaction for account at now would
+// lift the account's velocity score above velocityThreshold. When ctx carries
+// a score from reserveVelocity, that score already counts the transaction.
+func (tn *Tenant) velocityCheck(ctx context.Context, account string, now time.Time) bool {
+	if velocityThreshold <= 0 || account == "" {
+		return false
+	}
+	if score, ok := ctx.Value(velocityKey).(float64); ok {
+		return score > velocityThreshold
+	}
+	// Counting the transaction being scored adds one point.
+	return tn.velocityScore(account, now)+1 > velocityThreshold
+}
+
+// reserveVelocity adds debit t to its account's velocity score before it is
+// scored, so that concurrent transactions for one account cannot all pass the
+// check on the same reading. The returned context carries the new score for
+// velocityCheck; undo takes the point back if t ends up not being recorded.
+func (tn *Tenant) reserveVelocity(ctx context.Context, t Transaction) (_ context.Context, undo func()) {
+	if t.AccountID == "" || isCredit(t) {
+		return ctx, func() {}
+	}
+	score := tn.velocity.add(t.AccountID, now())
+	return context.WithValue(ctx, velocityKey, score), func() { tn.velocity.remove(t.AccountID, now()) }
+}
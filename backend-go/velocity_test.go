@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+// processAt scores and records t with the clock at at.
+func processAt(t *testing.T, tn *Tenant, at time.Time, tx Transaction) ScoreResult {
+	t.Helper()
+	setClock(t, at)
+	res, err := tn.process(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestVelocityEscalatesRapidRepeats(t *testing.T) {
+	tn := newTestTenant(t, defaultTenant)
+	tx := Transaction{Amount: dollars(20), Merchant: "Corner Shop", AccountID: "acct-1"}
+	for i := 0; i < 6; i++ {
+		res := processAt(t, tn, noon.Add(time.Duration(i)*time.Second), tx)
+		if want := i == 5; (res.RiskLevel == "HIGH") != want {
+			t.Fatalf("transaction %d: got %s (%s)", i+1, res.RiskLevel, res.Reason)
+		}
+	}
+}
+
+func TestVelocitySpreadOutStaysLow(t *testing.T) {
+	tn := newTestTenant(t, defaultTenant)
+	tx := Transaction{Amount: dollars(20), Merchant: "Corner Shop", AccountID: "acct-1"}
+	for i := 0; i < 6; i++ {
+		if res := processAt(t, tn, noon.Add(time.Duration(i)*5*velocityHalfLife), tx); res.RiskLevel != "LOW" {
+			t.Fatalf("transaction %d: got %s (%s)", i+1, res.RiskLevel, res.Reason)
+		}
+	}
+}
+
+func TestVelocityScoreAccumulatesAndDecays(t *testing.T) {
+	tn := newTestTenant(t, defaultTenant)
+	tx := Transaction{Amount: dollars(20), Merchant: "Corner Shop", AccountID: "acct-1"}
+	if got := tn.velocityScore("acct-1", noon); got != 0 {
+		t.Fatalf("unseen account scores %v", got)
+	}
+	prev := 0.0
+	for i := 0; i < 3; i++ {
+		processAt(t, tn, noon, tx)
+		got := tn.velocityScore("acct-1", noon)
+		if math.Abs(got-prev-1) > 1e-9 {
+			t.Fatalf("transaction %d: score %v, want %v", i+1, got, prev+1)
+		}
+		prev = got
+	}
+	for _, tc := range []struct {
+		after time.Duration
+		want  float64
+	}{
+		{velocityHalfLife / 2, 3 / math.Sqrt2},
+		{velocityHalfLife, 1.5},
+		{2 * velocityHalfLife, 0.75},
+	} {
+		if got := tn.velocityScore("acct-1", noon.Add(tc.after)); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("after %s: score %v, want %v", tc.after, got, tc.want)
+		}
+	}
+	if got := tn.velocityScore("acct-2", noon); got != 0 {
+		t.Fatalf("other account scores %v", got)
+	}
+}
+
+func TestVelocityScoreHalfLifeConfigurable(t *testing.T) {
+	setVar(t, &velocityHalfLife, 10*time.Minute)
+	tn := newTestTenant(t, defaultTenant)
+	processAt(t, tn, noon, Transaction{Amount: dollars(20), Merchant: "m", AccountID: "acct-1"})
+	if got := tn.velocityScore("acct-1", noon.Add(10*time.Minute)); math.Abs(got-0.5) > 1e-9 {
+		t.Fatalf("score after one half-life %v, want 0.5", got)
+	}
+}
+
+func TestVelocityConcurrentTransactionsFlaggedExactly(t *testing.T) {
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	const n = 200
+	tx := Transaction{Amount: dollars(20), Merchant: "Corner Shop", AccountID: "acct-1"}
+	levels := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := tn.process(context.Background(), tx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			levels <- res.RiskLevel
+		}()
+	}
+	wg.Wait()
+	close(levels)
+	high := 0
+	for level := range levels {
+		if level == "HIGH" {
+			high++
+		}
+	}
+	// With the clock frozen nothing decays: the first velocityThreshold
+	// transactions pass and every later one is flagged.
+	if want := n - int(velocityThreshold); high != want {
+		t.Fatalf("%d of %d flagged HIGH, want %d", high, n, want)
+	}
+	if s := tn.velocityScore("acct-1", noon); s != n {
+		t.Fatalf("velocity score %v, want %d", s, n)
+	}
+}
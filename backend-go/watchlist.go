@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Watchlist is a set of sanctioned counterparty names, keyed by their
+// normalized form and mapping to the name as listed.
+type Watchlist map[string]string
+
+// watchlist is the active sanctions list, loaded from the -watchlist file.
+var watchlist Watchlist
+
+// loadWatchlist reads one name per line from path. Blank lines and lines
+// starting with '#' are ignored.
+func loadWatchlist(path string) (Watchlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read watchlist: %w", err)
+	}
+	defer f.Close()
+
+	wl := Watchlist{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key := normalizeName(line); key != "" {
+			wl[key] = line
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read watchlist: %w", err)
+	}
+	return wl, nil
+}
+
+// normalizeName lower-cases a party name, drops punctuation and collapses
+// whitespace, so "ACME, Inc." and "acme inc" compare equal.
+func normalizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Match returns the listed name matching counterparty, if any.
+func (wl Watchlist) Match(counterparty string) (string, bool) {
+	if counterparty == "" {
+		return "", false
+	}
+	name, ok := wl[normalizeName(counterparty)]
+	return name, ok
+}
+
+// watchlistReason explains a sanctions hit for t, or returns "" if the
+// counterparty is not listed.
+func watchlistReason(t Transaction) string {
+	name, ok := watchlist.Match(t.Counterparty)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("counterparty matches sanctions watchlist entry %q", name)
+}
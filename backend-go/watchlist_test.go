@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadTestWatchlist(t *testing.T) Watchlist {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "watchlist.txt")
+	if err := os.WriteFile(path, []byte("# sanctioned parties\nACME Trading, Ltd.\n\nIvan Petrov\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wl, err := loadWatchlist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wl
+}
+
+func TestWatchlistScreening(t *testing.T) {
+	setVar(t, &watchlist, loadTestWatchlist(t))
+	setClock(t, noon)
+	tn := newTestTenant(t, defaultTenant)
+	for _, tc := range []struct {
+		name, counterparty, level string
+	}{
+		{"exact match", "Ivan Petrov", "HIGH"},
+		{"case and punctuation variant", "acme trading ltd", "HIGH"},
+		{"clean name", "Jane Smith", "LOW"},
+	} {
+		d, final := tn.decide(t.Context(), Transaction{Amount: dollars(10), Merchant: "m", Counterparty: tc.counterparty})
+		if d.RiskLevel != tc.level {
+			t.Errorf("%s: got %s (%s)", tc.name, d.RiskLevel, d.Reason)
+		}
+		if tc.level == "HIGH" && !final {
+			t.Errorf("%s: a watchlist hit must be final", tc.name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhook receives HIGH-risk decisions when -webhook-url is set.
+var webhook *webhookNotifier
+
+// webhookEvent is the JSON body POSTed to the webhook.
+type webhookEvent struct {
+	Transaction Transaction `json:"transaction"`
+	RiskLevel   string      `json:"risk_level"`
+	Reason      string      `json:"reason"`
+	Timestamp   time.Time   `json:"timestamp"`
+}
+
+// Webhook circuit breaker settings: after webhookBreakerFailures failed
+// deliveries in a row, events are dropped for webhookBreakerCooldown before a
+// trial delivery is attempted.
+var (
+	webhookBreakerFailures = 5
+	webhookBreakerCooldown = 30 * time.Second
+)
+
+// webhookNotifier delivers events to a URL with bounded retries, behind a
+// circuit breaker so a failing receiver doesn't pile up goroutines.
+type webhookNotifier struct {
+	url      string
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+	breaker  *CircuitBreaker
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{
+		url:      url,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		attempts: 3,
+		backoff:  500 * time.Millisecond,
+		breaker:  NewCircuitBreaker(webhookBreakerFailures, webhookBreakerCooldown),
+	}
+}
+
+// notify delivers ev in the background. Failures are logged and never
+// reach the client that triggered the event. While the breaker is open the
+// event is dropped and counted instead.
+func (n *webhookNotifier) notify(ev webhookEvent) {
+	if !n.breaker.Allow() {
+		webhookDropped.inc("circuit_open")
+		logger.Warn("webhook circuit open; dropping event", "url", n.url, "risk_level", ev.RiskLevel)
+		return
+	}
+	go func() {
+		if err := n.send(ev); err != nil {
+			n.breaker.Failure()
+			logger.Error("webhook delivery failed", "url", n.url, "error", err, "breaker", n.breaker.State().String())
+			return
+		}
+		n.breaker.Success()
+	}()
+}
+
+// send POSTs ev, retrying with exponential backoff up to n.attempts times.
+func (n *webhookNotifier) send(ev webhookEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	delay := n.backoff
+	for attempt := 1; ; attempt++ {
+		err = n.post(body)
+		if err == nil || attempt == n.attempts {
+			return err
+		}
+		logger.Warn("webhook attempt failed", "attempt", attempt, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (n *webhookNotifier) post(body []byte) error {
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
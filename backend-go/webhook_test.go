@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhookTarget starts a receiver that answers the first fail requests with
+// 500 and delivers each decoded event to the returned channel.
+func webhookTarget(t *testing.T, fail int32) (*httptest.Server, <-chan webhookEvent, *atomic.Int32) {
+	t.Helper()
+	events := make(chan webhookEvent, 10)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var ev webhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		events <- ev
+	}))
+	t.Cleanup(srv.Close)
+	return srv, events, &calls
+}
+
+func TestWebhookHighDecision(t *testing.T) {
+	api, _ := newTestAPI(t)
+	srv, events, _ := webhookTarget(t, 0)
+	setVar(t, &webhook, newWebhookNotifier(srv.URL))
+
+	do(api, "POST", "/risk", `{"amount": 20000, "merchant": "m", "id": "tx-1"}`)
+	select {
+	case ev := <-events:
+		if ev.RiskLevel != "HIGH" || ev.Transaction.ID != "tx-1" || ev.Reason == "" || ev.Timestamp.IsZero() {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called for a HIGH decision")
+	}
+}
+
+func TestWebhookNotCalledForLow(t *testing.T) {
+	api, _ := newTestAPI(t)
+	srv, _, calls := webhookTarget(t, 0)
+	setVar(t, &webhook, newWebhookNotifier(srv.URL))
+
+	do(api, "POST", "/risk", `{"amount": 10, "merchant": "m"}`)
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("webhook called %d times for a LOW decision", n)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	srv, events, calls := webhookTarget(t, 2)
+	n := newWebhookNotifier(srv.URL)
+	n.backoff = time.Millisecond
+	n.notify(webhookEvent{RiskLevel: "HIGH"})
+	select {
+	case <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered after retries")
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("%d attempts, want 3", got)
+	}
+}
@@ -95,6 +95,7 @@ async def create_transaction(transaction: Transaction, x_user: Optional[str] = H
             risk_response = await client.post(
                 RISK_SERVICE_URL,
                 json={"amount": transaction.amount, "merchant": transaction.merchant},
+                headers={"X-API-Key": os.getenv("RISK_SERVICE_API_KEY", "")},
                 timeout=5.0,
             )
             # prefer JSON, but guard against malformed replies